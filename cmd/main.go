@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -71,6 +72,9 @@ func main() {
 	var enableHTTP2 bool
 	var featureGatesFlag string
 	var repoCacheSize int
+	var repoCacheMaxBytes int64
+	var repoCacheDir string
+	var repoCacheAccessTTL time.Duration
 	var documentCacheSize int
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
@@ -86,6 +90,13 @@ func main() {
 			fmt.Sprintf("Example: %s=true", features.ResourceFinder))
 	flag.IntVar(&repoCacheSize, "git-repo-cache-size", 0,
 		"Maximum number of git repositories to keep cached in memory (0 disables caching).")
+	flag.Int64Var(&repoCacheMaxBytes, "git-repo-cache-max-bytes", 0,
+		"Maximum total size in bytes of the cached git repositories (0 only bounds their number).")
+	flag.StringVar(&repoCacheDir, "git-repo-cache-dir", "",
+		"Directory where the git repositories are cloned instead of memory. "+
+			"It must not be shared with another instance.")
+	flag.DurationVar(&repoCacheAccessTTL, "git-repo-cache-access-ttl", time.Minute,
+		"How long a git credential that could read a cached repository is trusted before it is checked again.")
 	flag.IntVar(&documentCacheSize, "git-document-cache-size", 0,
 		"Maximum number of resource-to-file-path mappings to keep cached in memory (0 disables caching).")
 	opts := zap.Options{
@@ -103,10 +114,20 @@ func main() {
 
 	setupLog.Info("Feature gates", "loaded", features.LoadedFeatureGates.String())
 
-	// Configure the in-memory git repository cache (no-op when size <= 0).
-	pusher.InitRepoCache(repoCacheSize)
+	// Configure the git repository cache (no-op when size <= 0).
+	if err := pusher.ConfigureRepoCache(pusher.RepoCacheOptions{
+		MaxRepos:  repoCacheSize,
+		MaxBytes:  repoCacheMaxBytes,
+		Dir:       repoCacheDir,
+		AccessTTL: repoCacheAccessTTL,
+	}); err != nil {
+		setupLog.Error(err, "unable to configure the git repository cache")
+		os.Exit(1)
+	}
 	if repoCacheSize > 0 {
-		setupLog.Info("Git repository cache enabled", "maxRepos", repoCacheSize)
+		setupLog.Info("Git repository cache enabled",
+			"maxRepos", repoCacheSize, "maxBytes", repoCacheMaxBytes, "dir", repoCacheDir,
+			"accessTTL", repoCacheAccessTTL)
 	}
 
 	// Configure the in-memory document path cache (no-op when size <= 0).
@@ -250,6 +271,15 @@ func main() {
 		os.Exit(1)
 	}
 
+	if err = (&controller.PendingCommitReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorder("pendingcommit-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PendingCommit")
+		os.Exit(1)
+	}
+
 	// One controller per CRD: the association policy now runs inside
 	// RemoteUserReconciler, and the branch-target and user-specific policies run
 	// inside RemoteSyncerReconciler, so none are registered as separate
@@ -12,7 +12,8 @@ const useCountCap = 1 << 16
 
 // LFU is a count-bounded, in-memory least-frequently-used cache. Every access
 // increments the touched entry's useCount and the entry with the smallest
-// useCount is evicted first once the cache exceeds its capacity.
+// useCount is evicted first once the cache exceeds its capacity. A weighted LFU
+// is also bounded by the total weight of its entries.
 //
 // LFU is safe for concurrent use. A nil *LFU is a valid, permanently-disabled
 // cache: all methods are no-ops (Get always misses), so callers can treat
@@ -21,11 +22,23 @@ type LFU[K comparable, V any] struct {
 	mu      sync.Mutex
 	max     int
 	entries map[K]*entry[V]
+
+	maxWeight int64 // zero or less when only the count is bounded
+	weight    int64 // sum of the entries' weights
+	onEvict   func(K, V)
 }
 
 type entry[V any] struct {
 	value    V
 	useCount uint32 // LFU rank: ++ on every access, halved on aging
+	weight   int64
+}
+
+// evicted is an entry removed to make room, handed to onEvict once c.mu is
+// released.
+type evicted[K comparable, V any] struct {
+	key   K
+	value V
 }
 
 // NewLFU returns a cache holding at most max entries. A max of zero or less
@@ -40,6 +53,20 @@ func NewLFU[K comparable, V any](max int) *LFU[K, V] {
 	}
 }
 
+// NewWeightedLFU returns a cache holding at most max entries whose weights (see
+// SetWeight) add up to at most maxWeight. A maxWeight of zero or less bounds the
+// count only. onEvict, if not nil, is called with every entry evicted to make
+// room, outside of the cache lock; it is not called for Delete or Set
+// replacements. A max of zero or less returns nil, like NewLFU.
+func NewWeightedLFU[K comparable, V any](max int, maxWeight int64, onEvict func(K, V)) *LFU[K, V] {
+	c := NewLFU[K, V](max)
+	if c != nil {
+		c.maxWeight = maxWeight
+		c.onEvict = onEvict
+	}
+	return c
+}
+
 // Get returns the value stored for key and whether it was present. A hit bumps
 // the entry's LFU counter.
 func (c *LFU[K, V]) Get(key K) (V, bool) {
@@ -65,16 +92,18 @@ func (c *LFU[K, V]) Set(key K, value V) {
 		return
 	}
 	c.mu.Lock()
-	defer c.mu.Unlock()
 	if e, ok := c.entries[key]; ok {
 		e.value = value
 		c.touchLocked(e)
+		c.mu.Unlock()
 		return
 	}
 	e := &entry[V]{value: value}
 	c.entries[key] = e
 	c.touchLocked(e)
-	c.evictLocked(key)
+	victims := c.evictLocked(key)
+	c.mu.Unlock()
+	c.notify(victims)
 }
 
 // LoadOrStore returns the existing value for key (a load, which bumps its LFU
@@ -87,18 +116,56 @@ func (c *LFU[K, V]) LoadOrStore(key K, value V) (V, bool) {
 		return value, false
 	}
 	c.mu.Lock()
-	defer c.mu.Unlock()
 	if e, ok := c.entries[key]; ok {
 		c.touchLocked(e)
+		c.mu.Unlock()
 		return e.value, true
 	}
 	e := &entry[V]{value: value}
 	c.entries[key] = e
 	c.touchLocked(e)
-	c.evictLocked(key)
+	victims := c.evictLocked(key)
+	c.mu.Unlock()
+	c.notify(victims)
 	return value, false
 }
 
+// SetWeight records the weight of key, if present, and evicts the
+// least-frequently-used other entries until the total weight is back within
+// the bound. An entry that exceeds the bound on its own is evicted instead.
+func (c *LFU[K, V]) SetWeight(key K, weight int64) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if !ok {
+		c.mu.Unlock()
+		return
+	}
+	c.weight += weight - e.weight
+	e.weight = weight
+	var victims []evicted[K, V]
+	if c.maxWeight > 0 && e.weight > c.maxWeight {
+		c.removeLocked(key)
+		victims = append(victims, evicted[K, V]{key: key, value: e.value})
+	} else {
+		victims = c.evictLocked(key)
+	}
+	c.mu.Unlock()
+	c.notify(victims)
+}
+
+// Weight returns the total weight of the entries.
+func (c *LFU[K, V]) Weight() int64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.weight
+}
+
 // Delete removes key from the cache. It is a no-op when key is absent.
 func (c *LFU[K, V]) Delete(key K) {
 	if c == nil {
@@ -106,7 +173,7 @@ func (c *LFU[K, V]) Delete(key K) {
 	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	delete(c.entries, key)
+	c.removeLocked(key)
 }
 
 // touchLocked bumps the LFU counter for e and ages all counters if the cap is
@@ -120,11 +187,21 @@ func (c *LFU[K, V]) touchLocked(e *entry[V]) {
 	}
 }
 
+// removeLocked removes key and its weight. Must be called with c.mu held.
+func (c *LFU[K, V]) removeLocked(key K) {
+	if e, ok := c.entries[key]; ok {
+		c.weight -= e.weight
+		delete(c.entries, key)
+	}
+}
+
 // evictLocked removes least-frequently-used entries until the cache is back to
-// its capacity. exceptKey (the entry just inserted) is never chosen as a victim.
-// Must be called with c.mu held.
-func (c *LFU[K, V]) evictLocked(exceptKey K) {
-	for len(c.entries) > c.max {
+// its capacity and weight bound, and returns them. exceptKey (the entry just
+// inserted or weighed) is never chosen as a victim. Must be called with c.mu
+// held.
+func (c *LFU[K, V]) evictLocked(exceptKey K) []evicted[K, V] {
+	var victims []evicted[K, V]
+	for len(c.entries) > c.max || (c.maxWeight > 0 && c.weight > c.maxWeight) {
 		var (
 			victimKey K
 			victim    *entry[V]
@@ -138,8 +215,20 @@ func (c *LFU[K, V]) evictLocked(exceptKey K) {
 			}
 		}
 		if victim == nil {
-			return
+			return victims
 		}
-		delete(c.entries, victimKey)
+		c.removeLocked(victimKey)
+		victims = append(victims, evicted[K, V]{key: victimKey, value: victim.value})
+	}
+	return victims
+}
+
+// notify hands the evicted entries to onEvict. Must be called without c.mu.
+func (c *LFU[K, V]) notify(victims []evicted[K, V]) {
+	if c.onEvict == nil {
+		return
+	}
+	for _, v := range victims {
+		c.onEvict(v.key, v.value)
 	}
 }
@@ -127,3 +127,43 @@ func TestLFUNilIsDisabled(t *testing.T) {
 		t.Fatalf("Get on disabled cache = true, want false")
 	}
 }
+
+func TestLFUEvictsByWeight(t *testing.T) {
+	var evicted []string
+	c := NewWeightedLFU[string, int](10, 100, func(k string, _ int) { evicted = append(evicted, k) })
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a") // "b" is now the least-frequently-used
+	c.SetWeight("a", 60)
+	c.SetWeight("b", 30)
+	if len(evicted) != 0 {
+		t.Fatalf("evicted %v within the weight bound", evicted)
+	}
+
+	c.Set("c", 3)
+	c.SetWeight("c", 30) // 120 > 100: "b" must go
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Fatalf("evicted = %v, want [b]", evicted)
+	}
+	if got := c.Weight(); got != 90 {
+		t.Fatalf("Weight() = %d, want 90", got)
+	}
+
+	// An entry heavier than the bound on its own is not kept.
+	c.SetWeight("c", 200)
+	if _, ok := c.Get("c"); ok {
+		t.Fatalf("c exceeds the weight bound and should have been evicted")
+	}
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("a should have survived eviction")
+	}
+	if got := c.Weight(); got != 60 {
+		t.Fatalf("Weight() = %d, want 60", got)
+	}
+
+	// Delete does not notify.
+	c.Delete("a")
+	if len(evicted) != 2 || c.Weight() != 0 {
+		t.Fatalf("evicted = %v, weight = %d after Delete", evicted, c.Weight())
+	}
+}
@@ -116,6 +116,7 @@ func (r *ClusterWideRemoteSyncerReconciler) reconcileWebhook(ctx context.Context
 		// is exactly the documented meaning of leaving the field unset.
 		namespaceSelector: cwrs.Spec.NamespaceSelector,
 		objectSelector:    cwrs.Spec.ScopedResources.ObjectSelector,
+		timeoutSeconds:    webhookTimeoutSeconds(cwrs.Spec.RemoteSyncerSpec),
 	}
 
 	condition := &v1.Condition{
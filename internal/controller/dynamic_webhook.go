@@ -23,6 +23,7 @@ import (
 	"reflect"
 	"slices"
 
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
 	admissionv1 "k8s.io/api/admissionregistration/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -42,6 +43,17 @@ type dynamicWebhookEntry struct {
 	rules             []admissionv1.RuleWithOperations
 	namespaceSelector *v1.LabelSelector
 	objectSelector    *v1.LabelSelector
+	timeoutSeconds    int32
+}
+
+// webhookTimeoutSeconds is the timeoutSeconds of the entry of a syncer. It is
+// always set, since the API server would default it and the entry would then
+// never look up to date.
+func webhookTimeoutSeconds(spec syngit.RemoteSyncerSpec) int32 {
+	if spec.WebhookTimeoutSeconds > 0 {
+		return spec.WebhookTimeoutSeconds
+	}
+	return syngit.DefaultWebhookTimeoutSeconds
 }
 
 // dynamicWebhookManager owns the shared ValidatingWebhookConfiguration that every
@@ -118,15 +130,17 @@ func (d *dynamicWebhookManager) upsert(ctx context.Context, entry dynamicWebhook
 	}
 	clientConfig, annotations := d.clientConfig(caCert, entry.path)
 
-	sideEffectsNone := admissionv1.SideEffectClassNone
+	// The interception pushes to git, except for dry-run requests.
+	sideEffects := admissionv1.SideEffectClassNoneOnDryRun
 	webhook := admissionv1.ValidatingWebhook{
 		Name:                    entry.name,
 		AdmissionReviewVersions: []string{"v1"},
-		SideEffects:             &sideEffectsNone,
+		SideEffects:             &sideEffects,
 		Rules:                   entry.rules,
 		ClientConfig:            clientConfig,
 		NamespaceSelector:       entry.namespaceSelector,
 		ObjectSelector:          entry.objectSelector,
+		TimeoutSeconds:          &entry.timeoutSeconds,
 	}
 
 	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
@@ -181,5 +195,6 @@ func (d *dynamicWebhookManager) upsert(ctx context.Context, entry dynamicWebhook
 func webhookEntryEqual(a, b admissionv1.ValidatingWebhook) bool {
 	return slices.EqualFunc(a.Rules, b.Rules, rulesAreEqual) &&
 		reflect.DeepEqual(a.NamespaceSelector, b.NamespaceSelector) &&
-		reflect.DeepEqual(a.ObjectSelector, b.ObjectSelector)
+		reflect.DeepEqual(a.ObjectSelector, b.ObjectSelector) &&
+		reflect.DeepEqual(a.TimeoutSeconds, b.TimeoutSeconds)
 }
@@ -0,0 +1,368 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	interceptor "github.com/syngit-org/syngit/internal/interceptor"
+	"github.com/syngit-org/syngit/internal/pusher"
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	pkginterceptor "github.com/syngit-org/syngit/pkg/interceptor"
+	"github.com/syngit-org/syngit/pkg/kube"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	replayBaseDelay = 5 * time.Second
+	replayMaxDelay  = 5 * time.Minute
+	// A commit that still cannot be pushed after replayMaxAttempts replays
+	// fails, so that it stops holding back the commits queued behind it.
+	replayMaxAttempts = 10
+	// A replay that hangs on the remote gives up after replayTimeout, so it
+	// does not hold a reconcile worker; it is retried after the usual delay.
+	replayTimeout = 5 * time.Minute
+	// The Secret holding the manifest is created right after its
+	// PendingCommit: a commit still without one after manifestGracePeriod
+	// lost it and cannot be replayed.
+	manifestGracePeriod = 30 * time.Second
+)
+
+// PendingCommitReconciler replays the commits queued by the syncers that use
+// the BlockCacheCommit push error behavior.
+//
+// A reconcile request names a queue, not a PendingCommit: its name is the
+// value of the syngit.PcLabelKeyTarget label shared by the commits pending on
+// the same branch. The commits are replayed oldest first and a commit that
+// still cannot be pushed holds back the ones queued behind it, so the branch
+// receives the changes in the order they were made.
+type PendingCommitReconciler struct {
+	client.Client
+	Scheme    *runtime.Scheme
+	Recorder  events.EventRecorder
+	Namespace string
+}
+
+// +kubebuilder:rbac:groups=syngit.io,resources=pendingcommits,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=syngit.io,resources=pendingcommits/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=syngit.io,resources=remotesyncers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=syngit.io,resources=remotesyncers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=syngit.io,resources=clusterwideremotesyncers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=syngit.io,resources=clusterwideremotesyncers/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=syngit.io,resources=remotetargets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=syngit.io,resources=remoteusers,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create
+// +kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch
+
+func (r *PendingCommitReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	_ = log.FromContext(ctx)
+
+	queue, err := interceptor.ListPendingCommitQueue(ctx, r.Client, req.Namespace, req.Name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if len(queue) == 0 {
+		return ctrl.Result{}, nil
+	}
+
+	log.Log.Info("Reconcile request",
+		"resource", "pendingcommit",
+		"namespace", req.Namespace,
+		"queue", req.Name,
+		"length", len(queue),
+	)
+
+	// The credentials and the CA bundles are read by the interceptor helpers,
+	// which take the client from the context.
+	ctx = context.WithValue(ctx, kube.ClientCtxKey{}, r.Client)
+
+	for i := range queue {
+		pendingCommit := &queue[i]
+
+		// A new commit queued behind a failing one must not
+		// shortcut the delay before the next attempt.
+		if wait := nextReplayDelay(pendingCommit.Status, time.Now()); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+
+		syncer, sc, err := interceptor.GetPendingCommitSyncer(ctx, r.Client, *pendingCommit)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				// Nobody expects this commit anymore.
+				if err := r.drop(ctx, pendingCommit, "the syncer does not exist anymore"); err != nil {
+					return ctrl.Result{}, err
+				}
+				continue
+			}
+			return ctrl.Result{}, err
+		}
+
+		manifest, err := interceptor.GetPendingCommitManifest(ctx, r.Client, *pendingCommit)
+		if apierrors.IsNotFound(err) {
+			if age := time.Since(pendingCommit.CreationTimestamp.Time); age < manifestGracePeriod {
+				return ctrl.Result{RequeueAfter: manifestGracePeriod - age}, nil
+			}
+			if err := r.drop(ctx, pendingCommit, "the manifest of the commit does not exist anymore"); err != nil {
+				return ctrl.Result{}, err
+			}
+			interceptor.UpdatePendingCommitsCount(ctx, sc, r.Namespace)
+			continue
+		}
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+
+		commitHash, err := r.replay(ctx, sc, *pendingCommit, manifest)
+		if apierrors.IsNotFound(err) {
+			// The RemoteTarget or the RemoteUser has been deleted.
+			if err := r.drop(ctx, pendingCommit, err.Error()); err != nil {
+				return ctrl.Result{}, err
+			}
+			interceptor.UpdatePendingCommitsCount(ctx, sc, r.Namespace)
+			continue
+		}
+		if err != nil {
+			if !pusher.IsPermanentError(err) && pendingCommit.Status.Attempts+1 < replayMaxAttempts {
+				return r.retryLater(ctx, pendingCommit, err)
+			}
+			if err := r.fail(ctx, pendingCommit, err); err != nil {
+				return ctrl.Result{}, err
+			}
+			interceptor.UpdatePendingCommitsCount(ctx, sc, r.Namespace)
+			continue
+		}
+
+		if err := r.Delete(ctx, pendingCommit); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, err
+		}
+		r.Recorder.Eventf(syncer, nil, "Normal", "PendingCommitPushed", "Replay",
+			fmt.Sprintf("The pending commit %s of %s has been pushed to %s (%s)",
+				pendingCommit.Name, pendingCommit.Spec.Object.Name, pendingCommit.Spec.Repository, commitHash),
+		)
+		interceptor.UpdatePendingCommitsCount(ctx, sc, r.Namespace)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// Runs the git pipeline for the pending commit, with the current state of the
+// syncer, the RemoteTarget and the credentials of the RemoteUser. The object is
+// routed by the routing rule it matched when it was intercepted, and moved from
+// where its previous rule placed it.
+func (r *PendingCommitReconciler) replay(
+	ctx context.Context,
+	sc pkginterceptor.SyncerContext,
+	pendingCommit syngit.PendingCommit,
+	manifest string,
+) (string, error) {
+	sc = sc.WithRoutingRule(pkginterceptor.FindRoutingRule(sc.Spec.RoutingRules, pendingCommit.Spec.RoutingRule))
+	sc.PreviousPlacement = pendingCommit.Spec.PreviousPlacement
+
+	remoteTarget := &syngit.RemoteTarget{}
+	if err := r.Get(ctx, refNamespacedName(pendingCommit.Spec.RemoteTargetRef), remoteTarget); err != nil {
+		return "", err
+	}
+
+	remoteUser := &syngit.RemoteUser{}
+	if err := r.Get(ctx, refNamespacedName(pendingCommit.Spec.RemoteUserRef), remoteUser); err != nil {
+		return "", err
+	}
+	gitUserInfo, err := interceptor.GetGitUserInfoByRemoteUser(ctx, *remoteUser)
+	if err != nil {
+		return "", err
+	}
+
+	upstreamURL, err := pkginterceptor.ParseRepositoryURL(sc.Spec.RemoteRepository)
+	if err != nil {
+		return "", err
+	}
+	caBundle, err := interceptor.CABundleBuilder(ctx, sc, upstreamURL)
+	if err != nil {
+		return "", err
+	}
+	transport, err := interceptor.TransportBuilder(ctx, sc, upstreamURL)
+	if err != nil {
+		return "", err
+	}
+
+	pipelineCtx, cancel := context.WithTimeout(ctx, replayTimeout)
+	defer cancel()
+
+	object := pendingCommit.Spec.Object
+	res, err := pusher.RunGitPipeline(pipelineCtx, r.Client, pkginterceptor.GitPipelineParams{
+		Syncer:          sc,
+		RemoteTarget:    sc.RouteRemoteTarget(*remoteTarget),
+		InterceptedYAML: manifest,
+		InterceptedGVR: schema.GroupVersionResource{
+			Group:    object.Group,
+			Version:  object.Version,
+			Resource: object.Resource,
+		},
+		InterceptedName:            object.Name,
+		InterceptedPathMetadata:    pendingCommit.Spec.PathMetadata,
+		InterceptedOldPathMetadata: pendingCommit.Spec.OldPathMetadata,
+		GitUserInfo:                *gitUserInfo,
+		Operation:                  admissionv1.Operation(pendingCommit.Spec.Operation),
+		CABundle:                   caBundle,
+		Transport:                  transport,
+		Author:                     pendingCommit.Spec.Author,
+		AdmissionUID:               pendingCommit.Spec.AdmissionUID,
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "", fmt.Errorf("the push did not complete within %s: %w", replayTimeout, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	if res.CommitHash == "" {
+		return "", fmt.Errorf("the commit hash is empty")
+	}
+
+	return res.CommitHash, nil
+}
+
+// Records the failed attempt and schedules the next one.
+func (r *PendingCommitReconciler) retryLater(
+	ctx context.Context,
+	pendingCommit *syngit.PendingCommit,
+	replayErr error,
+) (ctrl.Result, error) {
+	now := v1.Now()
+	pendingCommit.Status.Attempts++
+	pendingCommit.Status.LastAttemptTime = &now
+	pendingCommit.Status.LastError = replayErr.Error()
+	if err := r.Status().Update(ctx, pendingCommit); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	r.Recorder.Eventf(pendingCommit, nil, "Warning", "ReplayFailed", "Replay", replayErr.Error())
+
+	return ctrl.Result{RequeueAfter: replayDelay(pendingCommit.Status.Attempts)}, nil
+}
+
+// Records the last failed attempt of a commit that is not replayed anymore.
+// It is kept, out of the queue, for the failure to be inspected.
+func (r *PendingCommitReconciler) fail(
+	ctx context.Context,
+	pendingCommit *syngit.PendingCommit,
+	replayErr error,
+) error {
+	reason := "TooManyAttempts"
+	if pusher.IsPermanentError(replayErr) {
+		reason = "PermanentError"
+	}
+
+	now := v1.Now()
+	pendingCommit.Status.Attempts++
+	pendingCommit.Status.LastAttemptTime = &now
+	pendingCommit.Status.LastError = replayErr.Error()
+	pendingCommit.Status.Conditions = kube.SetCondition(pendingCommit.Status.Conditions, v1.Condition{
+		LastTransitionTime: now,
+		Type:               syngit.PcConditionTypeFailed,
+		Status:             v1.ConditionTrue,
+		Reason:             reason,
+		Message:            replayErr.Error(),
+	})
+	if err := r.Status().Update(ctx, pendingCommit); err != nil {
+		return err
+	}
+
+	r.Recorder.Eventf(pendingCommit, nil, "Warning", "PendingCommitFailed", "Replay",
+		fmt.Sprintf("The commit is not replayed anymore after %d attempts: %s", pendingCommit.Status.Attempts, replayErr.Error()),
+	)
+	return nil
+}
+
+// Deletes a pending commit that can never be pushed.
+func (r *PendingCommitReconciler) drop(ctx context.Context, pendingCommit *syngit.PendingCommit, reason string) error {
+	log.Log.Info("dropping the pending commit",
+		"namespace", pendingCommit.Namespace,
+		"name", pendingCommit.Name,
+		"reason", reason,
+	)
+	r.Recorder.Eventf(pendingCommit, nil, "Warning", "PendingCommitDropped", "Replay", reason)
+
+	return client.IgnoreNotFound(r.Delete(ctx, pendingCommit))
+}
+
+// The delay between two replays of the same commit doubles
+// after each failed attempt, up to replayMaxDelay.
+func replayDelay(attempts int) time.Duration {
+	delay := replayBaseDelay
+	for i := 1; i < attempts && delay < replayMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, replayMaxDelay)
+}
+
+// Returns how long to wait before replaying a commit, given its last attempt.
+func nextReplayDelay(status syngit.PendingCommitStatus, now time.Time) time.Duration {
+	if status.Attempts == 0 || status.LastAttemptTime == nil {
+		return 0
+	}
+	return status.LastAttemptTime.Add(replayDelay(status.Attempts)).Sub(now)
+}
+
+func refNamespacedName(ref corev1.ObjectReference) types.NamespacedName {
+	return types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+}
+
+// Maps a PendingCommit to the queue it belongs to.
+func (r *PendingCommitReconciler) findQueueForPendingCommit(_ context.Context, obj client.Object) []reconcile.Request {
+	target := obj.GetLabels()[syngit.PcLabelKeyTarget]
+	if target == "" || obj.GetNamespace() != r.Namespace {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: target},
+	}}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PendingCommitReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.Recorder = mgr.GetEventRecorder("pendingcommit-controller")
+	r.Namespace = os.Getenv("MANAGER_NAMESPACE")
+
+	// Status updates do not change the generation: the failed
+	// attempts are rescheduled by the reconciler itself.
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("pendingcommit").
+		Watches(
+			&syngit.PendingCommit{},
+			handler.EnqueueRequestsFromMapFunc(r.findQueueForPendingCommit),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{}),
+		).
+		Complete(r)
+}
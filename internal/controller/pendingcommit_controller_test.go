@@ -0,0 +1,63 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"testing"
+	"time"
+
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReplayDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, replayBaseDelay},
+		{1, replayBaseDelay},
+		{2, 2 * replayBaseDelay},
+		{4, 8 * replayBaseDelay},
+		{100, replayMaxDelay},
+	}
+
+	for _, tc := range tests {
+		if got := replayDelay(tc.attempts); got != tc.want {
+			t.Errorf("replayDelay(%d)=%v, want %v", tc.attempts, got, tc.want)
+		}
+	}
+}
+
+func TestNextReplayDelay(t *testing.T) {
+	now := time.Now()
+	lastAttempt := v1.NewTime(now.Add(-2 * time.Second))
+
+	if got := nextReplayDelay(syngit.PendingCommitStatus{}, now); got != 0 {
+		t.Errorf("a commit never replayed must be replayed at once, got %v", got)
+	}
+
+	status := syngit.PendingCommitStatus{Attempts: 1, LastAttemptTime: &lastAttempt}
+	if got := nextReplayDelay(status, now); got <= 0 || got > replayBaseDelay {
+		t.Errorf("got %v, want the rest of the %v delay", got, replayBaseDelay)
+	}
+
+	status.LastAttemptTime = &v1.Time{Time: now.Add(-replayMaxDelay)}
+	if got := nextReplayDelay(status, now); got > 0 {
+		t.Errorf("a commit whose delay has elapsed must be replayed at once, got %v", got)
+	}
+}
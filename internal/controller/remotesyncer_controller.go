@@ -130,6 +130,7 @@ func (r *RemoteSyncerReconciler) reconcileWebhook(ctx context.Context, req ctrl.
 			MatchLabels: map[string]string{"kubernetes.io/metadata.name": req.Namespace},
 		},
 		objectSelector: remoteSyncer.Spec.ScopedResources.ObjectSelector,
+		timeoutSeconds: webhookTimeoutSeconds(remoteSyncer.Spec),
 	}
 
 	condition := &v1.Condition{
@@ -18,9 +18,11 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"os"
 
 	"github.com/syngit-org/syngit/internal/policy"
+	"github.com/syngit-org/syngit/internal/pusher"
 	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
 	"github.com/syngit-org/syngit/pkg/kube"
 	"github.com/syngit-org/syngit/pkg/refs"
@@ -64,6 +66,10 @@ type RemoteUserReconciler struct {
 func (r *RemoteUserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	_ = log.FromContext(ctx)
 
+	// The credentials may have changed (or the RemoteUser be gone): the git
+	// servers must be asked again whether they can read the cached repositories.
+	pusher.ForgetRemoteUser(req.NamespacedName)
+
 	// Get the RemoteUser Object
 	var remoteUser syngit.RemoteUser
 	if err := r.Get(ctx, req.NamespacedName, &remoteUser); err != nil {
@@ -137,8 +143,8 @@ func (r *RemoteUserReconciler) reconcileSecretStatus(ctx context.Context, remote
 	condition.Reason = "SecretFound"
 	condition.Message = string(syngit.SecretFound)
 
-	// Check if the referenced Secret is a basic-auth type
-	if secret.Type != corev1.SecretTypeBasicAuth {
+	// Check if the referenced Secret is a basic-auth or an ssh-auth type
+	if secret.Type != corev1.SecretTypeBasicAuth && secret.Type != corev1.SecretTypeSSHAuth {
 
 		remoteUser.Status.SecretBoundStatus = syngit.SecretWrongType
 
@@ -149,6 +155,20 @@ func (r *RemoteUserReconciler) reconcileSecretStatus(ctx context.Context, remote
 		return
 	}
 
+	// An SSH key is only usable along with the known_hosts the git server is
+	// verified against.
+	if secret.Type == corev1.SecretTypeSSHAuth {
+		if err := r.checkKnownHosts(ctx, remoteUser); err != nil {
+			remoteUser.Status.SecretBoundStatus = syngit.KnownHostsNotFound
+
+			condition.Reason = "KnownHostsNotFound"
+			condition.Message = err.Error()
+			_ = r.updateStatus(ctx, remoteUser, *condition)
+
+			return
+		}
+	}
+
 	remoteUser.Status.SecretBoundStatus = syngit.SecretBound
 	condition.Message = string(syngit.SecretBound)
 	condition.Type = "SecretBound"
@@ -159,6 +179,30 @@ func (r *RemoteUserReconciler) reconcileSecretStatus(ctx context.Context, remote
 	_ = r.updateStatus(ctx, remoteUser, *condition)
 }
 
+// checkKnownHosts verifies that the Secret referenced by knownHostsSecretRef
+// exists and holds known_hosts entries.
+func (r *RemoteUserReconciler) checkKnownHosts(ctx context.Context, remoteUser *syngit.RemoteUser) error {
+	ref := remoteUser.Spec.KnownHostsSecretRef
+	if ref == nil || ref.Name == "" {
+		return fmt.Errorf("spec.knownHostsSecretRef must be set when the secret is of type %s", corev1.SecretTypeSSHAuth)
+	}
+
+	namespace, err := refs.ResolveNamespace(ref.Namespace, remoteUser.Namespace, field.NewPath("spec", "knownHostsSecretRef"))
+	if err != nil {
+		return err
+	}
+
+	var secret corev1.Secret
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, &secret); err != nil {
+		return fmt.Errorf("the known hosts secret %s/%s can not be found: %w", namespace, ref.Name, err)
+	}
+	if len(secret.Data[syngit.KnownHostsKey]) == 0 {
+		return fmt.Errorf("the known hosts secret %s/%s has no %s entry", namespace, ref.Name, syngit.KnownHostsKey)
+	}
+
+	return nil
+}
+
 func (r *RemoteUserReconciler) updateStatus(ctx context.Context, remoteUser *syngit.RemoteUser, condition v1.Condition) error {
 	conditions := kube.SetCondition(remoteUser.Status.DeepCopy().Conditions, condition)
 
@@ -230,22 +274,20 @@ func (r *RemoteUserReconciler) findRemoteUsersForRemoteTarget(ctx context.Contex
 }
 
 func remoteUserValueExtractor(rawObj client.Object) []string {
-	// Extract the referenced Secret from the RemoteUser Spec, if one is
-	// provided. The key carries the resolved namespace because the Secret
-	// may live outside of the RemoteUser's own namespace.
+	// Extract the referenced Secrets from the RemoteUser Spec, if any are
+	// provided. The keys carry the resolved namespace because the Secrets
+	// may live outside of the RemoteUser's own namespace. The known_hosts
+	// Secret is indexed as well, so that rotating it re-evaluates the binding.
 	remoteUser := rawObj.(*syngit.RemoteUser)
-	if remoteUser.Spec.SecretRef.Name == "" {
-		return nil
-	}
-	namespace, err := refs.ResolveNamespace(
-		remoteUser.Spec.SecretRef.Namespace,
-		remoteUser.Namespace,
-		field.NewPath("spec", "secretRef"),
-	)
+	objectRefs, err := refs.RemoteUserRefs(remoteUser.Spec, remoteUser.Namespace)
 	if err != nil {
 		return nil
 	}
-	return []string{namespace + "/" + remoteUser.Spec.SecretRef.Name}
+	keys := make([]string, 0, len(objectRefs))
+	for _, ref := range objectRefs {
+		keys = append(keys, ref.Namespace+"/"+ref.Name)
+	}
+	return keys
 }
 
 // SetupWithManager sets up the controller with the Manager.
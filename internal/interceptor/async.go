@@ -0,0 +1,283 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/syngit-org/syngit/internal/pusher"
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"github.com/syngit-org/syngit/pkg/kube"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// asyncCommitTimeout bounds the push of one change in the background. Nothing
+// waits for it, but a git server that never answers must not hold the worker
+// of its repository forever.
+const asyncCommitTimeout = 5 * time.Minute
+
+// asyncDrainTimeout bounds the wait for the queued changes once the manager
+// stops, well within its graceful shutdown period. The pushes still running
+// after it are cancelled and the changes still queued are lost.
+var asyncDrainTimeout = 10 * time.Second
+
+// AsyncCommitterCtxKey is the context key of the AsyncCommitter that the
+// interception pipeline hands the changes of the ApplyThenCommit syncers to.
+type AsyncCommitterCtxKey struct{}
+
+// AsyncCommitterFromContext returns the AsyncCommitter of the context, if any.
+func AsyncCommitterFromContext(ctx context.Context) *AsyncCommitter {
+	committer, _ := ctx.Value(AsyncCommitterCtxKey{}).(*AsyncCommitter)
+	return committer
+}
+
+// AsyncCommitter pushes the changes intercepted by the syncers with the
+// ApplyThenCommit strategy once their admission request has been answered.
+//
+// Each repository has its own worker, started with the first change and
+// stopped when there is nothing left to push, so the changes of a repository
+// are pushed one at a time and in the order they were admitted. A change that
+// still waits when a newer change of the same object comes in is replaced by
+// the newer one: only the latest state of the object ends up in git.
+//
+// It is a Runnable of the manager: once the manager stops, it takes no more
+// changes and waits for the workers to push the ones already queued, for up
+// to asyncDrainTimeout. The queues are only held in memory: the delivery of
+// the changes is at most once.
+type AsyncCommitter struct {
+	// ctx carries the client, since the requests the changes come from are
+	// over by the time they are pushed. It is cancelled when the drain of
+	// the queues times out.
+	ctx      context.Context
+	cancel   context.CancelFunc
+	recorder events.EventRecorder
+
+	mu sync.Mutex
+	// The changes waiting to be pushed, per repository. A repository is in
+	// the map for as long as its worker runs.
+	queues map[string][]*asyncCommit
+	// stopped is set once the manager stops, and workers counts the workers
+	// still running.
+	stopped bool
+	workers sync.WaitGroup
+}
+
+// ErrAsyncCommitterStopped is returned for the changes enqueued once the
+// AsyncCommitter has stopped.
+var ErrAsyncCommitterStopped = errors.New("the background committer is stopped")
+
+// asyncCommit is a change of one object for one RemoteTarget.
+type asyncCommit struct {
+	key            asyncCommitKey
+	params         GitPushParameters
+	pipelineParams interceptor.GitPipelineParams
+}
+
+// asyncCommitKey identifies the changes that replace each other.
+type asyncCommitKey struct {
+	syncer      types.NamespacedName
+	clusterWide bool
+	repository  string
+	branch      string
+	gvr         schema.GroupVersionResource
+	namespace   string
+	name        string
+}
+
+// NewAsyncCommitter returns an AsyncCommitter that reads the cluster with
+// k8sClient and reports the outcome of the pushes with recorder, which may be nil.
+func NewAsyncCommitter(k8sClient client.Client, recorder events.EventRecorder) *AsyncCommitter {
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), kube.ClientCtxKey{}, k8sClient))
+	return &AsyncCommitter{
+		ctx:      ctx,
+		cancel:   cancel,
+		recorder: recorder,
+		queues:   make(map[string][]*asyncCommit),
+	}
+}
+
+// Start waits for ctx to end, then for the changes already queued to be
+// pushed, for up to asyncDrainTimeout. It implements manager.Runnable.
+func (c *AsyncCommitter) Start(ctx context.Context) error {
+	<-ctx.Done()
+
+	c.mu.Lock()
+	c.stopped = true
+	c.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		c.workers.Wait()
+		close(drained)
+	}()
+
+	timer := time.NewTimer(asyncDrainTimeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+	case <-timer.C:
+		log.Log.Info("the background pushes did not complete in time, cancelling them",
+			"timeout", asyncDrainTimeout.String())
+		c.cancel()
+		<-drained
+	}
+	c.cancel()
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable: every replica
+// pushes the changes it admitted.
+func (c *AsyncCommitter) NeedLeaderElection() bool {
+	return false
+}
+
+// Enqueue schedules the push of a change and returns immediately. It fails once
+// the committer has stopped.
+func (c *AsyncCommitter) Enqueue(params GitPushParameters, pipelineParams interceptor.GitPipelineParams) error {
+	target := pipelineParams.RemoteTarget.Spec
+	commit := &asyncCommit{
+		key: asyncCommitKey{
+			syncer:      params.Syncer.Ref,
+			clusterWide: params.Syncer.ClusterWide,
+			repository:  target.TargetRepository,
+			branch:      target.TargetBranch,
+			gvr:         pipelineParams.InterceptedGVR,
+			namespace:   params.Syncer.InterceptedNamespace,
+			name:        pipelineParams.InterceptedName,
+		},
+		params:         params,
+		pipelineParams: pipelineParams,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopped {
+		return ErrAsyncCommitterStopped
+	}
+	queue, running := c.queues[target.TargetRepository]
+	for i, pending := range queue {
+		if pending.key == commit.key {
+			queue[i] = commit
+			return nil
+		}
+	}
+	c.queues[target.TargetRepository] = append(queue, commit)
+	if !running {
+		c.workers.Add(1)
+		go c.work(target.TargetRepository)
+	}
+	return nil
+}
+
+// pending returns the number of changes that wait to be pushed to repository.
+func (c *AsyncCommitter) pending(repository string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.queues[repository])
+}
+
+func (c *AsyncCommitter) work(repository string) {
+	defer c.workers.Done()
+	for {
+		c.mu.Lock()
+		queue := c.queues[repository]
+		if len(queue) == 0 {
+			delete(c.queues, repository)
+			c.mu.Unlock()
+			return
+		}
+		commit := queue[0]
+		c.queues[repository] = queue[1:]
+		c.mu.Unlock()
+
+		if c.ctx.Err() != nil {
+			log.Log.Info("the background committer has stopped, the change is not pushed",
+				"syncer", commit.params.Syncer.String(), "repository", repository, "object", commit.key.name)
+			continue
+		}
+		c.push(commit)
+	}
+}
+
+func (c *AsyncCommitter) push(commit *asyncCommit) {
+	pushCtx, cancel := context.WithTimeout(c.ctx, asyncCommitTimeout)
+	defer cancel()
+
+	sc := commit.params.Syncer
+	object := fmt.Sprintf("%s %s", commit.key.gvr.Resource, commit.key.name)
+	if commit.key.namespace != "" {
+		object = fmt.Sprintf("%s %s/%s", commit.key.gvr.Resource, commit.key.namespace, commit.key.name)
+	}
+
+	var (
+		res interceptor.GitPushResponse
+		err error
+	)
+	if sc.Spec.DefaultPushErrorBehavior == syngit.BlockCacheCommit {
+		res, err = runOrQueueGitPipeline(pushCtx, commit.params, commit.pipelineParams)
+	} else {
+		res, err = pusher.RunGitPipeline(pushCtx, commit.params.Cluster, commit.pipelineParams)
+		if err == nil && res.CommitHash == "" {
+			err = errors.New("the commit hash is empty")
+		}
+	}
+
+	// The outcome is reported even when the push has timed out or has been
+	// cancelled.
+	ctx := context.WithoutCancel(c.ctx)
+	conditionUpdater := NewRemoteSyncerConditionUpdater(sc)
+	if err != nil {
+		log.Log.Error(err, "the background push failed", "syncer", sc.String(), "object", object)
+		conditionUpdater.UpdateRemoteSyncerConditions(ctx, BuildErrorCondition(err.Error()))
+		c.event(ctx, sc, "Warning", "PushFailed",
+			fmt.Sprintf("The %s could not be pushed to %s: %v", object, commit.key.repository, err))
+		return
+	}
+
+	if res.PendingCommit != "" {
+		UpdatePendingCommitsCount(ctx, sc, commit.params.ManagerNamespace)
+		c.event(ctx, sc, "Warning", "PushQueued",
+			fmt.Sprintf("The %s could not be pushed to %s yet: it is queued as pendingcommit/%s", object, res.URL, res.PendingCommit))
+		return
+	}
+
+	statusUpdater := RemoteSyncerStatusUpdater{
+		syncer:       sc,
+		group:        commit.key.gvr.Group,
+		version:      commit.key.gvr.Version,
+		resource:     commit.key.gvr.Resource,
+		resourceName: commit.key.name,
+		userInfo:     commit.params.Author,
+	}
+	statusUpdater.UpdateRemoteSyncerState(
+		ctx, []interceptor.GitPushResponse{res}, syngit.LastPushedObjectStateKey, "",
+	)
+	conditionUpdater.UpdateRemoteSyncerConditions(ctx, BuildSuccessCondition(""))
+	c.event(ctx, sc, "Normal", "Pushed",
+		fmt.Sprintf("The %s has been pushed to %s (%s)", object, res.URL, res.CommitHash))
+}
+
+// event records an event on the syncer of sc.
+func (c *AsyncCommitter) event(ctx context.Context, sc interceptor.SyncerContext, eventType, reason, note string) {
+	if c.recorder == nil {
+		return
+	}
+
+	var syncer client.Object = &syngit.RemoteSyncer{}
+	if sc.ClusterWide {
+		syncer = &syngit.ClusterWideRemoteSyncer{}
+	}
+	if err := kube.ClientFromContext(ctx).Get(ctx, sc.Ref, syncer); err != nil {
+		log.Log.Error(err, "can't get the syncer to record an event on "+sc.String())
+		return
+	}
+	c.recorder.Eventf(syncer, nil, eventType, reason, "Push", "%s", note)
+}
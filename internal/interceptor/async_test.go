@@ -0,0 +1,259 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/tools/events"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAsyncCommitter_coalesces(t *testing.T) {
+	const repository = "https://git.example.com/org/repo.git"
+	// The repository is marked as having a running worker, so that nothing
+	// is pushed and the queue can be inspected.
+	committer := NewAsyncCommitter(nil, nil)
+	committer.queues[repository] = nil
+
+	change := func(name, yaml string) interceptor.GitPipelineParams {
+		target := syngit.RemoteTarget{}
+		target.Spec.TargetRepository = repository
+		target.Spec.TargetBranch = "main"
+		return interceptor.GitPipelineParams{
+			RemoteTarget:    target,
+			InterceptedGVR:  schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+			InterceptedName: name,
+			InterceptedYAML: yaml,
+		}
+	}
+	params := GitPushParameters{Syncer: interceptor.SyncerContext{InterceptedNamespace: "default"}}
+
+	_ = committer.Enqueue(params, change("a", "a1"))
+	_ = committer.Enqueue(params, change("b", "b1"))
+	_ = committer.Enqueue(params, change("a", "a2"))
+
+	if got := committer.pending(repository); got != 2 {
+		t.Fatalf("pending=%d, want 2", got)
+	}
+	queue := committer.queues[repository]
+	if queue[0].pipelineParams.InterceptedYAML != "a2" || queue[1].pipelineParams.InterceptedYAML != "b1" {
+		t.Errorf("queue=[%s %s], want [a2 b1]",
+			queue[0].pipelineParams.InterceptedYAML, queue[1].pipelineParams.InterceptedYAML)
+	}
+
+	// Another branch of the same repository is not the same change.
+	other := change("a", "a3")
+	other.RemoteTarget.Spec.TargetBranch = "dev"
+	_ = committer.Enqueue(params, other)
+	if got := committer.pending(repository); got != 3 {
+		t.Errorf("pending=%d, want 3", got)
+	}
+}
+
+// seededRemote returns the path of a bare repository whose master branch holds
+// a README.md commit.
+func seededRemote(t *testing.T) string {
+	t.Helper()
+
+	remote := t.TempDir()
+	if _, err := git.PlainInit(remote, true); err != nil {
+		t.Fatalf("failed to init the remote: %v", err)
+	}
+	seed, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatalf("failed to init the seed: %v", err)
+	}
+	if _, err := seed.CreateRemote(&config.RemoteConfig{Name: "origin", URLs: []string{remote}}); err != nil {
+		t.Fatalf("failed to create the remote: %v", err)
+	}
+	worktree, _ := seed.Worktree()
+	if err := util.WriteFile(worktree.Filesystem, "README.md", []byte("seed"), 0644); err != nil {
+		t.Fatalf("failed to write the seed: %v", err)
+	}
+	if _, err := worktree.Add("README.md"); err != nil {
+		t.Fatalf("failed to add the seed: %v", err)
+	}
+	if _, err := worktree.Commit("add README.md", &git.CommitOptions{
+		Author: &object.Signature{Name: "seed", Email: "seed@syngit.io", When: time.Now()},
+	}); err != nil {
+		t.Fatalf("failed to commit the seed: %v", err)
+	}
+	if err := seed.Push(&git.PushOptions{}); err != nil {
+		t.Fatalf("failed to push the seed: %v", err)
+	}
+	return remote
+}
+
+// newRunningAsyncCommitter returns an AsyncCommitter over a cluster that holds
+// the RemoteSyncer of sc, the events it records, and a function that stops it
+// once the queued changes are pushed.
+func newRunningAsyncCommitter(t *testing.T, sc interceptor.SyncerContext) (*AsyncCommitter, *events.FakeRecorder, func()) {
+	t.Helper()
+
+	scheme := runtime.NewScheme()
+	if err := syngit.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build the scheme: %v", err)
+	}
+	rs := &syngit.RemoteSyncer{}
+	rs.Namespace, rs.Name = sc.Ref.Namespace, sc.Ref.Name
+	rs.Spec = sc.Spec
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(rs).WithStatusSubresource(rs).
+		Build()
+
+	recorder := events.NewFakeRecorder(10)
+	committer := NewAsyncCommitter(k8sClient, recorder)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error)
+	go func() { stopped <- committer.Start(ctx) }()
+
+	stop := func() {
+		cancel()
+		if err := <-stopped; err != nil {
+			t.Errorf("Start: %v", err)
+		}
+	}
+	return committer, recorder, stop
+}
+
+func asyncChange(sc interceptor.SyncerContext, repository string) (GitPushParameters, interceptor.GitPipelineParams) {
+	target := syngit.RemoteTarget{}
+	target.Spec = syngit.RemoteTargetSpec{
+		UpstreamRepository: repository,
+		UpstreamBranch:     "master",
+		TargetRepository:   repository,
+		TargetBranch:       "master",
+	}
+	return GitPushParameters{Syncer: sc}, interceptor.GitPipelineParams{
+		Syncer:          sc,
+		RemoteTarget:    target,
+		InterceptedGVR:  schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+		InterceptedName: "config",
+		InterceptedYAML: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n  namespace: default\n",
+		GitUserInfo:     interceptor.GitUserInfo{User: "test", Email: "test@syngit.io"},
+		Operation:       admissionv1.Create,
+	}
+}
+
+func asyncSyncerContext() interceptor.SyncerContext {
+	rs := syngit.RemoteSyncer{}
+	rs.Namespace, rs.Name = "default", "syncer"
+	rs.Spec.Strategy = syngit.ApplyThenCommit
+	return interceptor.NewRemoteSyncerContext(rs, "default")
+}
+
+func TestAsyncCommitter_pushes(t *testing.T) {
+	remote := seededRemote(t)
+	sc := asyncSyncerContext()
+	committer, recorder, stop := newRunningAsyncCommitter(t, sc)
+
+	if err := committer.Enqueue(asyncChange(sc, remote)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	// Stopping drains the queue: the change is pushed by then.
+	stop()
+
+	repository, err := git.PlainOpen(remote)
+	if err != nil {
+		t.Fatalf("failed to open the remote: %v", err)
+	}
+	head, _ := repository.Head()
+	commit, err := repository.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatalf("failed to read the commit: %v", err)
+	}
+	if _, err := commit.File("default/v1/configmaps/config.yaml"); err != nil {
+		t.Errorf("the change is not pushed: %v", err)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Normal Pushed") || !strings.Contains(event, head.Hash().String()) {
+			t.Errorf("event=%q, want the push of %s", event, head.Hash())
+		}
+	default:
+		t.Errorf("the push is not reported")
+	}
+
+	if err := committer.Enqueue(asyncChange(sc, remote)); !errors.Is(err, ErrAsyncCommitterStopped) {
+		t.Errorf("Enqueue() once stopped error = %v, want %v", err, ErrAsyncCommitterStopped)
+	}
+}
+
+func TestAsyncCommitter_reportsFailure(t *testing.T) {
+	sc := asyncSyncerContext()
+	committer, recorder, stop := newRunningAsyncCommitter(t, sc)
+
+	missing := filepath.Join(t.TempDir(), "missing.git")
+	if err := committer.Enqueue(asyncChange(sc, missing)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	stop()
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Warning PushFailed") || !strings.Contains(event, missing) {
+			t.Errorf("event=%q, want the failure of the push to %s", event, missing)
+		}
+	default:
+		t.Errorf("the failure is not reported")
+	}
+}
+
+func TestAsyncCommitter_boundsTheDrain(t *testing.T) {
+	saved := asyncDrainTimeout
+	asyncDrainTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { asyncDrainTimeout = saved })
+
+	// The git server never answers.
+	requested := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case requested <- struct{}{}:
+		default:
+		}
+		<-r.Context().Done()
+	}))
+	t.Cleanup(server.Close)
+
+	sc := asyncSyncerContext()
+	committer, recorder, stop := newRunningAsyncCommitter(t, sc)
+
+	repository := server.URL + "/repo.git"
+	if err := committer.Enqueue(asyncChange(sc, repository)); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	<-requested
+
+	start := time.Now()
+	stop()
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the drain took %v, want it bounded by %v", elapsed, asyncDrainTimeout)
+	}
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, "Warning PushFailed") {
+			t.Errorf("event=%q, want the failure of the cancelled push", event)
+		}
+	default:
+		t.Errorf("the cancelled push is not reported")
+	}
+}
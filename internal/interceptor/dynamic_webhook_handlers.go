@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
 	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
@@ -40,6 +41,9 @@ func ClusterWideRemoteSyncerWebhookPath(name string) string {
 type WebhookInterceptsAll struct {
 	K8sClient client.Client
 
+	// Pushes the changes of the syncers with the ApplyThenCommit strategy.
+	AsyncCommitter *AsyncCommitter
+
 	// Caching system
 	pathHandlers (map[string]*DynamicWebhookHandler)
 	sync.RWMutex
@@ -63,17 +67,41 @@ func (dwc *DynamicWebhookHandler) syncerContext(interceptedNamespace string) int
 	return interceptor.NewRemoteSyncerContext(*dwc.remoteSyncer, interceptedNamespace)
 }
 
+// timeout returns how long the API server waits for the answer to r: the
+// timeout it sends along with the request, or else the timeoutSeconds of the
+// webhook entry of the syncer.
+func (dwc *DynamicWebhookHandler) timeout(r *http.Request) time.Duration {
+	if timeout, err := time.ParseDuration(r.URL.Query().Get("timeout")); err == nil && timeout > 0 {
+		return timeout
+	}
+	var seconds int32
+	if dwc.clusterWideRemoteSyncer != nil {
+		seconds = dwc.clusterWideRemoteSyncer.Spec.WebhookTimeoutSeconds
+	} else {
+		seconds = dwc.remoteSyncer.Spec.WebhookTimeoutSeconds
+	}
+	if seconds <= 0 {
+		seconds = syngit.DefaultWebhookTimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
 // NewWebhookInterceptsAll creates and starts the interception server. There is
 // exactly one per manager: it owns a single mux registered at "/syngit/" and a
 // single path->handler map, which every syncer controller registers into.
 func NewWebhookInterceptsAll(mgr ctrl.Manager) *WebhookInterceptsAll {
 	s := &WebhookInterceptsAll{
-		K8sClient: mgr.GetClient(),
-		Manager:   mgr,
+		K8sClient:      mgr.GetClient(),
+		AsyncCommitter: NewAsyncCommitter(mgr.GetClient(), mgr.GetEventRecorder("syngit-interceptor")),
+		Manager:        mgr,
 		// Built here rather than in Start so that a syncer registered before the
 		// server is started is recorded instead of panicking on a nil map.
 		pathHandlers: make(map[string]*DynamicWebhookHandler),
 	}
+	// The manager drains the queues of the committer when it stops.
+	if err := mgr.Add(s.AsyncCommitter); err != nil {
+		log.Log.Error(err, "can't run the background committer with the manager")
+	}
 	return s
 }
 
@@ -84,6 +112,7 @@ func (s *WebhookInterceptsAll) Start() *WebhookInterceptsAll {
 	_ = log.FromContext(ctx)
 
 	ctx = context.WithValue(ctx, kube.ClientCtxKey{}, s.K8sClient)
+	ctx = context.WithValue(ctx, AsyncCommitterCtxKey{}, s.AsyncCommitter)
 
 	s.Lock()
 	if s.pathHandlers == nil {
@@ -183,6 +212,13 @@ func (dwc *DynamicWebhookHandler) Handle(ctx context.Context, w http.ResponseWri
 	// The namespace of the intercepted object is empty when it is cluster-scoped.
 	sc := dwc.syncerContext(admissionReviewReq.Request.Namespace)
 
+	// Past the timeout, the API server has given up on the request: the
+	// pipeline stops, and releases the repositories it holds, by then.
+	ctx, cancel := context.WithTimeout(ctx, dwc.timeout(r))
+	defer cancel()
+	stop := context.AfterFunc(r.Context(), cancel)
+	defer stop()
+
 	admResponse := RunInterceptionPipeline(ctx, admissionReviewReq.Request, sc, os.Getenv("MANAGER_NAMESPACE"))
 
 	resp, err := json.Marshal(admResponse)
@@ -1,7 +1,10 @@
 package interceptor
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
 	"k8s.io/apimachinery/pkg/types"
@@ -161,3 +164,32 @@ func TestWebhookInterceptsAll_Unregister(t *testing.T) {
 		}
 	})
 }
+
+func TestDynamicWebhookHandler_timeout(t *testing.T) {
+	rs := syngit.RemoteSyncer{}
+	handler := &DynamicWebhookHandler{remoteSyncer: &rs}
+
+	request := httptest.NewRequest(http.MethodPost, "/syngit/namespace-scoped-validate/ns/foo", nil)
+	if got := handler.timeout(request); got != 10*time.Second {
+		t.Errorf("timeout without webhookTimeoutSeconds = %v, want the default of 10s", got)
+	}
+
+	rs.Spec.WebhookTimeoutSeconds = 25
+	if got := handler.timeout(request); got != 25*time.Second {
+		t.Errorf("timeout = %v, want the webhookTimeoutSeconds of 25s", got)
+	}
+
+	// The timeout the API server sends along with the request prevails.
+	request = httptest.NewRequest(http.MethodPost, "/syngit/namespace-scoped-validate/ns/foo?timeout=7s", nil)
+	if got := handler.timeout(request); got != 7*time.Second {
+		t.Errorf("timeout = %v, want the 7s of the request", got)
+	}
+
+	cwrs := syngit.ClusterWideRemoteSyncer{}
+	cwrs.Spec.WebhookTimeoutSeconds = 3
+	handler = &DynamicWebhookHandler{clusterWideRemoteSyncer: &cwrs}
+	request = httptest.NewRequest(http.MethodPost, "/syngit/cluster-scoped-validate/foo", nil)
+	if got := handler.timeout(request); got != 3*time.Second {
+		t.Errorf("timeout of a cluster-wide syncer = %v, want 3s", got)
+	}
+}
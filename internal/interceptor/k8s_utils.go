@@ -59,6 +59,12 @@ func (updater RemoteSyncerStatusUpdater) UpdateRemoteSyncerState(
 	for _, info := range targetRepos {
 		commitHashes = append(commitHashes, info.CommitHash)
 	}
+	var changeRequests []string
+	for _, info := range targetRepos {
+		if info.ChangeRequestURL != "" {
+			changeRequests = append(changeRequests, info.ChangeRequestURL)
+		}
+	}
 
 	repoPaths := []string{""}
 	if len(targetRepos) > 0 {
@@ -97,6 +103,7 @@ func (updater RemoteSyncerStatusUpdater) UpdateRemoteSyncerState(
 			LastPushedObjectGitCommitHashes: commitHashes,
 			LastPushedGitUser:               updater.userInfo.Username,
 			LastPushedObjectStatus:          lastPushDetails,
+			LastPushedObjectChangeRequests:  changeRequests,
 		}
 		mutate = func(status *syngit.RemoteSyncerStatus) {
 			status.LastPushedObjectState = lastPushedObjectState
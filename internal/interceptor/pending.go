@@ -0,0 +1,264 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"github.com/syngit-org/syngit/pkg/kube"
+	"github.com/syngit-org/syngit/pkg/webhooks"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	remoteSyncerKind            = "RemoteSyncer"
+	clusterWideRemoteSyncerKind = "ClusterWideRemoteSyncer"
+)
+
+// PendingCommitTarget returns the value of the syngit.PcLabelKeyTarget label of
+// the commits pending on a repository branch.
+func PendingCommitTarget(repository, branch string) string {
+	sum := sha256.Sum256([]byte(repository + "\n" + branch))
+	return hex.EncodeToString(sum[:16])
+}
+
+// NewPendingCommit builds the queue entry of a commit that could not be pushed.
+// Only references to the RemoteTarget and the RemoteUser are stored: the
+// credentials are read again when the commit is replayed. The manifest is
+// stored apart, by NewPendingCommitManifest.
+func NewPendingCommit(
+	namespace string,
+	params interceptor.GitPipelineParams,
+	author authenticationv1.UserInfo,
+	object webhooks.ObjectMetadata,
+) *syngit.PendingCommit {
+	syncerRef := corev1.ObjectReference{
+		APIVersion: syngit.GroupVersion.String(),
+		Kind:       remoteSyncerKind,
+		Namespace:  params.Syncer.Ref.Namespace,
+		Name:       params.Syncer.Ref.Name,
+	}
+	if params.Syncer.ClusterWide {
+		syncerRef.Kind = clusterWideRemoteSyncerKind
+	}
+
+	target := params.RemoteTarget
+
+	routingRule := ""
+	if params.Syncer.RoutingRule != nil {
+		routingRule = params.Syncer.RoutingRule.Name
+	}
+
+	return &syngit.PendingCommit{
+		ObjectMeta: v1.ObjectMeta{
+			GenerateName: syngit.PcNamePrefix + "-",
+			Namespace:    namespace,
+			Labels: map[string]string{
+				syngit.PcLabelKeyTarget: PendingCommitTarget(target.Spec.TargetRepository, target.Spec.TargetBranch),
+			},
+		},
+		Spec: syngit.PendingCommitSpec{
+			SyncerRef:            syncerRef,
+			InterceptedNamespace: params.Syncer.InterceptedNamespace,
+			RemoteTargetRef: corev1.ObjectReference{
+				APIVersion: syngit.GroupVersion.String(),
+				Kind:       "RemoteTarget",
+				Namespace:  target.Namespace,
+				Name:       target.Name,
+			},
+			RemoteUserRef: corev1.ObjectReference{
+				APIVersion: syngit.GroupVersion.String(),
+				Kind:       "RemoteUser",
+				Namespace:  params.GitUserInfo.RemoteUserRef.Namespace,
+				Name:       params.GitUserInfo.RemoteUserRef.Name,
+			},
+			Author:       author,
+			AdmissionUID: params.AdmissionUID,
+			Operation:    string(params.Operation),
+			Object: syngit.JsonGVRN{
+				Group:    object.GVR.Group,
+				Version:  object.GVR.Version,
+				Resource: object.GVR.Resource,
+				Name:     object.Name,
+			},
+			PathMetadata:      params.InterceptedPathMetadata,
+			OldPathMetadata:   params.InterceptedOldPathMetadata,
+			RoutingRule:       routingRule,
+			PreviousPlacement: params.Syncer.PreviousPlacement,
+			Repository:        target.Spec.TargetRepository,
+			Branch:            target.Spec.TargetBranch,
+			EnqueueTime:       v1.NewMicroTime(time.Now()),
+		},
+	}
+}
+
+// NewPendingCommitManifest builds the Secret that holds the manifest of a
+// queued commit. The manifest may hold the data of an intercepted Secret, so
+// it is kept out of the PendingCommit, which the Secret RBAC does not cover.
+// The Secret is owned by the PendingCommit, which must have been created.
+func NewPendingCommitManifest(pendingCommit *syngit.PendingCommit, manifest string) *corev1.Secret {
+	isController := true
+	return &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      pendingCommit.Name,
+			Namespace: pendingCommit.Namespace,
+			Labels:    pendingCommit.Labels,
+			OwnerReferences: []v1.OwnerReference{{
+				APIVersion: syngit.GroupVersion.String(),
+				Kind:       "PendingCommit",
+				Name:       pendingCommit.Name,
+				UID:        pendingCommit.UID,
+				Controller: &isController,
+			}},
+		},
+		Type: corev1.SecretTypeOpaque,
+		Data: map[string][]byte{syngit.PcSecretKeyManifest: []byte(manifest)},
+	}
+}
+
+// GetPendingCommitManifest returns the manifest of a queued commit.
+func GetPendingCommitManifest(
+	ctx context.Context,
+	k8sClient client.Reader,
+	pendingCommit syngit.PendingCommit,
+) (string, error) {
+	secret := &corev1.Secret{}
+	if err := k8sClient.Get(ctx, types.NamespacedName{
+		Namespace: pendingCommit.Namespace,
+		Name:      pendingCommit.Name,
+	}, secret); err != nil {
+		return "", err
+	}
+	return string(secret.Data[syngit.PcSecretKeyManifest]), nil
+}
+
+// SortPendingCommits orders the commits in the order they must be replayed.
+// The name breaks the ties of commits queued in the same microsecond.
+func SortPendingCommits(pendingCommits []syngit.PendingCommit) {
+	slices.SortStableFunc(pendingCommits, func(a, b syngit.PendingCommit) int {
+		if c := a.Spec.EnqueueTime.Compare(b.Spec.EnqueueTime.Time); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+}
+
+// IsPendingCommitFailed reports whether the commit is not replayed anymore.
+func IsPendingCommitFailed(pendingCommit syngit.PendingCommit) bool {
+	return meta.IsStatusConditionTrue(pendingCommit.Status.Conditions, syngit.PcConditionTypeFailed)
+}
+
+// ListPendingCommitQueue returns the commits labelled with the target value
+// that wait to be replayed, oldest first. The failed ones are left out.
+func ListPendingCommitQueue(
+	ctx context.Context,
+	k8sClient client.Reader,
+	namespace string,
+	target string,
+) ([]syngit.PendingCommit, error) {
+	pendingCommitList := &syngit.PendingCommitList{}
+	if err := k8sClient.List(ctx, pendingCommitList,
+		client.InNamespace(namespace),
+		client.MatchingLabels{syngit.PcLabelKeyTarget: target},
+	); err != nil {
+		return nil, err
+	}
+	queue := slices.DeleteFunc(pendingCommitList.Items, IsPendingCommitFailed)
+	SortPendingCommits(queue)
+
+	return queue, nil
+}
+
+// ListPendingCommits returns the commits pending on a repository branch
+// that wait to be replayed, oldest first.
+func ListPendingCommits(
+	ctx context.Context,
+	k8sClient client.Reader,
+	namespace string,
+	repository string,
+	branch string,
+) ([]syngit.PendingCommit, error) {
+	queue, err := ListPendingCommitQueue(ctx, k8sClient, namespace, PendingCommitTarget(repository, branch))
+	if err != nil {
+		return nil, err
+	}
+
+	pendingCommits := make([]syngit.PendingCommit, 0, len(queue))
+	for _, pendingCommit := range queue {
+		if pendingCommit.Spec.Repository == repository && pendingCommit.Spec.Branch == branch {
+			pendingCommits = append(pendingCommits, pendingCommit)
+		}
+	}
+
+	return pendingCommits, nil
+}
+
+// IsPendingCommitOf reports whether the commit was queued by the syncer.
+func IsPendingCommitOf(pendingCommit syngit.PendingCommit, sc interceptor.SyncerContext) bool {
+	ref := pendingCommit.Spec.SyncerRef
+	if sc.ClusterWide != (ref.Kind == clusterWideRemoteSyncerKind) {
+		return false
+	}
+	return ref.Namespace == sc.Ref.Namespace && ref.Name == sc.Ref.Name
+}
+
+// GetPendingCommitSyncer fetches the syncer that queued the commit and
+// resolves it, as it is now, for the namespace of the intercepted object.
+func GetPendingCommitSyncer(
+	ctx context.Context,
+	k8sClient client.Reader,
+	pendingCommit syngit.PendingCommit,
+) (client.Object, interceptor.SyncerContext, error) {
+	ref := pendingCommit.Spec.SyncerRef
+	namespacedName := types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}
+
+	switch ref.Kind {
+	case clusterWideRemoteSyncerKind:
+		cwrs := &syngit.ClusterWideRemoteSyncer{}
+		if err := k8sClient.Get(ctx, namespacedName, cwrs); err != nil {
+			return nil, interceptor.SyncerContext{}, err
+		}
+		return cwrs, interceptor.NewClusterWideSyncerContext(*cwrs, pendingCommit.Spec.InterceptedNamespace), nil
+	case remoteSyncerKind:
+		rsy := &syngit.RemoteSyncer{}
+		if err := k8sClient.Get(ctx, namespacedName, rsy); err != nil {
+			return nil, interceptor.SyncerContext{}, err
+		}
+		return rsy, interceptor.NewRemoteSyncerContext(*rsy, pendingCommit.Spec.InterceptedNamespace), nil
+	default:
+		return nil, interceptor.SyncerContext{}, fmt.Errorf("unknown syncer kind %q", ref.Kind)
+	}
+}
+
+// UpdatePendingCommitsCount reports in the status of the syncer the number
+// of its commits that wait to be replayed.
+func UpdatePendingCommitsCount(ctx context.Context, sc interceptor.SyncerContext, namespace string) {
+	pendingCommitList := &syngit.PendingCommitList{}
+	if err := kube.ClientFromContext(ctx).List(ctx, pendingCommitList, client.InNamespace(namespace)); err != nil {
+		log.Log.Error(err, "can't list the pending commits of "+sc.String())
+		return
+	}
+
+	count := 0
+	for _, pendingCommit := range pendingCommitList.Items {
+		if IsPendingCommitOf(pendingCommit, sc) && !IsPendingCommitFailed(pendingCommit) {
+			count++
+		}
+	}
+
+	updateRemoteSyncerStatus(ctx, sc, func(status *syngit.RemoteSyncerStatus) {
+		status.PendingCommits = count
+	})
+}
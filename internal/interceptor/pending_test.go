@@ -0,0 +1,215 @@
+package interceptor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"github.com/syngit-org/syngit/pkg/webhooks"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPendingCommitTarget(t *testing.T) {
+	main := PendingCommitTarget("https://git.example.com/org/repo.git", "main")
+
+	if len(main) > 63 {
+		t.Errorf("label value %q is longer than 63 characters", main)
+	}
+	if main != PendingCommitTarget("https://git.example.com/org/repo.git", "main") {
+		t.Error("the same branch must map to the same queue")
+	}
+	if main == PendingCommitTarget("https://git.example.com/org/repo.git", "dev") {
+		t.Error("two branches must map to different queues")
+	}
+	if main == PendingCommitTarget("https://git.example.com/org/other.git", "main") {
+		t.Error("two repositories must map to different queues")
+	}
+}
+
+func TestNewPendingCommit(t *testing.T) {
+	cwrs := syngit.ClusterWideRemoteSyncer{}
+	cwrs.Name = "cluster-syncer"
+
+	target := syngit.RemoteTarget{}
+	target.Namespace = "team-a"
+	target.Name = "main-target"
+	target.Spec.TargetRepository = "https://git.example.com/org/repo.git"
+	target.Spec.TargetBranch = "main"
+
+	params := interceptor.GitPipelineParams{
+		Syncer:          interceptor.NewClusterWideSyncerContext(cwrs, "team-a"),
+		RemoteTarget:    target,
+		InterceptedYAML: "kind: ConfigMap\n",
+		GitUserInfo: interceptor.GitUserInfo{
+			User:          "alice",
+			Token:         "s3cr3t",
+			RemoteUserRef: types.NamespacedName{Namespace: "team-a", Name: "alice-github"},
+		},
+		Operation: admissionv1.Update,
+	}
+	object := webhooks.ObjectMetadata{
+		GVR:  schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+		Name: "my-config",
+	}
+
+	pc := NewPendingCommit("syngit", params, authenticationv1.UserInfo{Username: "alice"}, object)
+
+	if pc.Namespace != "syngit" {
+		t.Errorf("Namespace=%q, want syngit", pc.Namespace)
+	}
+	if got := pc.Labels[syngit.PcLabelKeyTarget]; got != PendingCommitTarget(target.Spec.TargetRepository, "main") {
+		t.Errorf("target label=%q does not select the queue of the branch", got)
+	}
+	if pc.Spec.SyncerRef.Kind != "ClusterWideRemoteSyncer" || pc.Spec.SyncerRef.Name != "cluster-syncer" {
+		t.Errorf("SyncerRef=%+v, want the cluster-wide syncer", pc.Spec.SyncerRef)
+	}
+	if pc.Spec.InterceptedNamespace != "team-a" {
+		t.Errorf("InterceptedNamespace=%q, want team-a", pc.Spec.InterceptedNamespace)
+	}
+	if pc.Spec.RemoteTargetRef.Namespace != "team-a" || pc.Spec.RemoteTargetRef.Name != "main-target" {
+		t.Errorf("RemoteTargetRef=%+v, want team-a/main-target", pc.Spec.RemoteTargetRef)
+	}
+	if pc.Spec.RemoteUserRef.Namespace != "team-a" || pc.Spec.RemoteUserRef.Name != "alice-github" {
+		t.Errorf("RemoteUserRef=%+v, want team-a/alice-github", pc.Spec.RemoteUserRef)
+	}
+	if pc.Spec.Operation != "UPDATE" {
+		t.Errorf("Operation=%q, want UPDATE", pc.Spec.Operation)
+	}
+	if pc.Spec.Object.Resource != "configmaps" || pc.Spec.Object.Name != "my-config" {
+		t.Errorf("Object=%+v", pc.Spec.Object)
+	}
+	if pc.Spec.Repository != target.Spec.TargetRepository || pc.Spec.Branch != "main" {
+		t.Errorf("Repository=%q Branch=%q", pc.Spec.Repository, pc.Spec.Branch)
+	}
+	if pc.Spec.RoutingRule != "" {
+		t.Errorf("RoutingRule=%q, want none", pc.Spec.RoutingRule)
+	}
+
+	params.Syncer = params.Syncer.WithRoutingRule(&syngit.RoutingRule{Name: "config"})
+	if routed := NewPendingCommit("syngit", params, authenticationv1.UserInfo{}, object); routed.Spec.RoutingRule != "config" {
+		t.Errorf("RoutingRule=%q, want the rule the object matched", routed.Spec.RoutingRule)
+	}
+}
+
+func TestPendingCommitManifest(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := syngit.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build the scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build the scheme: %v", err)
+	}
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	ctx := context.Background()
+
+	pc := pendingCommitAt("pc-x1", time.Now(), "https://git.example.com/org/repo.git", "main")
+	pc.UID = "pc-x1-uid"
+	manifest := "apiVersion: v1\nkind: Secret\ndata:\n  password: czNjcjN0\n"
+	secret := NewPendingCommitManifest(&pc, manifest)
+	if err := k8sClient.Create(ctx, secret); err != nil {
+		t.Fatalf("failed to store the manifest: %v", err)
+	}
+
+	if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].UID != pc.UID || secret.OwnerReferences[0].Kind != "PendingCommit" {
+		t.Errorf("OwnerReferences=%+v, want the PendingCommit", secret.OwnerReferences)
+	}
+	got, err := GetPendingCommitManifest(ctx, k8sClient, pc)
+	if err != nil {
+		t.Fatalf("GetPendingCommitManifest: %v", err)
+	}
+	if got != manifest {
+		t.Errorf("GetPendingCommitManifest()=%q, want %q", got, manifest)
+	}
+
+	other := pendingCommitAt("pc-x2", time.Now(), "https://git.example.com/org/repo.git", "main")
+	if _, err := GetPendingCommitManifest(ctx, k8sClient, other); !apierrors.IsNotFound(err) {
+		t.Errorf("GetPendingCommitManifest() of a commit without manifest error = %v, want not found", err)
+	}
+}
+
+func pendingCommitAt(name string, enqueueTime time.Time, repository, branch string) syngit.PendingCommit {
+	pc := syngit.PendingCommit{}
+	pc.Namespace = "syngit"
+	pc.Name = name
+	pc.Labels = map[string]string{syngit.PcLabelKeyTarget: PendingCommitTarget(repository, branch)}
+	pc.Spec.Repository = repository
+	pc.Spec.Branch = branch
+	pc.Spec.EnqueueTime = metav1.NewMicroTime(enqueueTime)
+	return pc
+}
+
+func TestListPendingCommits(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := syngit.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build the scheme: %v", err)
+	}
+
+	repository := "https://git.example.com/org/repo.git"
+	now := time.Now()
+	first := pendingCommitAt("pc-b", now, repository, "main")
+	tie := pendingCommitAt("pc-c", now, repository, "main")
+	second := pendingCommitAt("pc-a", now.Add(time.Millisecond), repository, "main")
+	otherBranch := pendingCommitAt("pc-d", now.Add(-time.Second), repository, "dev")
+	failed := pendingCommitAt("pc-e", now.Add(-time.Second), repository, "main")
+	failed.Status.Conditions = []metav1.Condition{{Type: syngit.PcConditionTypeFailed, Status: metav1.ConditionTrue}}
+
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(&second, &otherBranch, &tie, &first, &failed).
+		Build()
+
+	pendingCommits, err := ListPendingCommits(context.Background(), k8sClient, "syngit", repository, "main")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"pc-b", "pc-c", "pc-a"}
+	if len(pendingCommits) != len(want) {
+		t.Fatalf("got %d pending commits, want %d", len(pendingCommits), len(want))
+	}
+	for i, name := range want {
+		if pendingCommits[i].Name != name {
+			t.Errorf("pendingCommits[%d]=%q, want %q", i, pendingCommits[i].Name, name)
+		}
+	}
+}
+
+func TestIsPendingCommitOf(t *testing.T) {
+	rs := syngit.RemoteSyncer{}
+	rs.Namespace = "team-a"
+	rs.Name = "my-syncer" // nolint:goconst
+	cwrs := syngit.ClusterWideRemoteSyncer{}
+	cwrs.Name = "my-syncer" // nolint:goconst
+
+	params := interceptor.GitPipelineParams{Syncer: interceptor.NewRemoteSyncerContext(rs, "team-a")}
+	pc := NewPendingCommit("syngit", params, authenticationv1.UserInfo{}, webhooks.ObjectMetadata{})
+
+	if !IsPendingCommitOf(*pc, interceptor.NewRemoteSyncerContext(rs, "team-a")) {
+		t.Error("the commit must belong to the syncer that queued it")
+	}
+	if IsPendingCommitOf(*pc, interceptor.NewClusterWideSyncerContext(cwrs, "team-a")) {
+		t.Error("the commit must not belong to a cluster-wide syncer of the same name")
+	}
+}
+
+func TestSplitPendingResponses(t *testing.T) {
+	pushed, queued := SplitPendingResponses([]interceptor.GitPushResponse{
+		{URL: "https://one", CommitHash: "h1"},
+		{URL: "https://two", PendingCommit: "pc-x1"},
+	})
+	if len(pushed) != 1 || pushed[0].URL != "https://one" {
+		t.Errorf("pushed=%+v", pushed)
+	}
+	if len(queued) != 1 || queued[0].PendingCommit != "pc-x1" {
+		t.Errorf("queued=%+v", queued)
+	}
+}
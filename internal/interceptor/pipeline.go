@@ -2,8 +2,11 @@ package interceptor
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net/url"
+	"slices"
+	"strings"
+	"unicode"
 
 	"github.com/syngit-org/syngit/internal/pusher"
 	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
@@ -15,7 +18,10 @@ import (
 	"github.com/syngit-org/syngit/pkg/render"
 	"github.com/syngit-org/syngit/pkg/webhooks"
 	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 func RunInterceptionPipeline(
@@ -26,7 +32,7 @@ func RunInterceptionPipeline(
 ) admissionv1.AdmissionReview {
 	userInfo := admReq.UserInfo
 
-	upstreamRemoteSyncerRepoURL, err := url.Parse(sc.Spec.RemoteRepository)
+	upstreamRemoteSyncerRepoURL, err := interceptor.ParseRepositoryURL(sc.Spec.RemoteRepository)
 	if err != nil {
 		return AdmissionReviewBuilder(
 			ctx, se.BuildInterceptorPipelineErr("cannot parse the RemoteSyncer's upstream URL"),
@@ -76,6 +82,35 @@ func RunInterceptionPipeline(
 	// Get the intercepted object metadata
 	objectMetadata := webhooks.ExtractObjectMetadata(admReq)
 
+	// Route the object with the first routing rule of the syncer that it matches.
+	// An update also routes the object as it was before, to move it when the
+	// rule it matches changes.
+	raw := admReq.Object.Raw
+	if admReq.Operation == admissionv1.Delete {
+		raw = admReq.OldObject.Raw
+	}
+	routedObject, err := newRoutedObject(admReq, objectMetadata.PathMetadata, raw)
+	if err != nil {
+		return AdmissionReviewBuilder(ctx, se.BuildInterceptorPipelineErr(err.Error()), admReq, false, true, sc)
+	}
+	routingRule, err := interceptor.MatchRoutingRule(ctx, sc.Spec.RoutingRules, routedObject)
+	if err != nil {
+		return AdmissionReviewBuilder(ctx, se.BuildInterceptorPipelineErr(err.Error()), admReq, false, true, sc)
+	}
+	previousRoutingRule := routingRule
+	if objectMetadata.OldPathMetadata != nil {
+		oldRoutedObject, err := newRoutedObject(admReq, *objectMetadata.OldPathMetadata, admReq.OldObject.Raw)
+		if err != nil {
+			return AdmissionReviewBuilder(ctx, se.BuildInterceptorPipelineErr(err.Error()), admReq, false, true, sc)
+		}
+		previousRoutingRule, err = interceptor.MatchRoutingRule(ctx, sc.Spec.RoutingRules, oldRoutedObject)
+		if err != nil {
+			return AdmissionReviewBuilder(ctx, se.BuildInterceptorPipelineErr(err.Error()), admReq, false, true, sc)
+		}
+	}
+	previousSc := sc.WithRoutingRule(previousRoutingRule)
+	sc = sc.WithRoutingRules(previousRoutingRule, routingRule)
+
 	// Set the targets using the user credentials
 	userRemoteTargets, err := GetUserInfoRemoteTargetsAssociation(
 		ctx,
@@ -87,6 +122,23 @@ func RunInterceptionPipeline(
 		return AdmissionReviewBuilder(ctx, err.Error(), admReq, false, true, sc)
 	}
 
+	// The object is deleted from the RemoteTargets of its previous routing rule
+	// that the new one does not push to. On the ones they share, it is moved
+	// within the commit.
+	var previousUserRemoteTargets map[interceptor.GitUserInfo][]syngit.RemoteTarget
+	if sc.PreviousPlacement != nil {
+		previousUserRemoteTargets, err = GetUserInfoRemoteTargetsAssociation(
+			ctx,
+			userInfo,
+			upstreamRemoteSyncerRepoURL,
+			previousSc,
+		)
+		if err != nil && !errors.Is(err, se.ErrRemoteTargetNotFound) {
+			return AdmissionReviewBuilder(ctx, err.Error(), admReq, false, true, sc)
+		}
+		previousUserRemoteTargets = excludeRemoteTargets(previousUserRemoteTargets, userRemoteTargets)
+	}
+
 	operation := admReq.Operation
 	manifest := ""
 
@@ -95,6 +147,7 @@ func RunInterceptionPipeline(
 		manifest, err = render.ObjectToYAML(
 			ctx,
 			admReq.Object.Raw,
+			webhooks.FieldManager(admReq),
 			managerNamespace,
 			sc.Spec,
 			sc.RefOwnerNamespace,
@@ -123,16 +176,27 @@ func RunInterceptionPipeline(
 	if err != nil {
 		return AdmissionReviewBuilder(ctx, se.BuildInterceptorPipelineErr(err.Error()), admReq, false, true, sc)
 	}
+	transport, err := TransportBuilder(ctx, sc, upstreamRemoteSyncerRepoURL)
+	if err != nil {
+		return AdmissionReviewBuilder(ctx, se.BuildInterceptorPipelineErr(err.Error()), admReq, false, true, sc)
+	}
 
 	// Git push
 	responses, err := RunGitPushPipeline(ctx, GitPushParameters{
-		UserInfoRemoteTargets: userRemoteTargets,
-		Syncer:                sc,
-		YAMLManifest:          manifest,
-		ObjectMetadata:        objectMetadata,
-		Operation:             operation,
-		CABundle:              caBundle,
-		Cluster:               kube.ClientFromContext(ctx),
+		UserInfoRemoteTargets:         userRemoteTargets,
+		PreviousUserInfoRemoteTargets: previousUserRemoteTargets,
+		PreviousSyncer:                previousSc,
+		Syncer:                        sc,
+		YAMLManifest:                  manifest,
+		ObjectMetadata:                objectMetadata,
+		Operation:                     operation,
+		CABundle:                      caBundle,
+		Transport:                     transport,
+		Cluster:                       kube.ClientFromContext(ctx),
+		Author:                        userInfo,
+		AdmissionUID:                  admReq.UID,
+		ManagerNamespace:              managerNamespace,
+		DryRun:                        IsDryRun(admReq),
 	})
 	if err != nil {
 		if sc.Spec.Strategy == syngit.CommitApply &&
@@ -142,20 +206,84 @@ func RunInterceptionPipeline(
 		return AdmissionReviewBuilder(ctx, se.BuildInterceptorPipelineErr(err.Error()), admReq, false, true, sc)
 	}
 
-	statusUpdater := NewRemoteSyncerStatusUpdater(admReq, sc)
-	statusUpdater.UpdateRemoteSyncerState(
-		ctx, responses, syngit.LastPushedObjectStateKey, "",
-	)
+	if IsDryRun(admReq) {
+		review := AdmissionReviewBuilder(ctx, BuildWebhookDryRunMessage(responses), admReq, IsWebhookAllowed(sc, false), false, sc)
+		review.Response.Warnings = BuildWebhookWarnings(sc, responses)
+		return review
+	}
+
+	// The pushes run in the background: they report their own outcome.
+	if sc.Spec.Strategy == syngit.ApplyThenCommit {
+		return AdmissionReviewBuilder(ctx, BuildWebhookSuccessMessage(responses), admReq, true, false, sc)
+	}
+
+	pushed, queued := SplitPendingResponses(responses)
+	if len(pushed) > 0 || len(queued) == 0 {
+		statusUpdater := NewRemoteSyncerStatusUpdater(admReq, sc)
+		statusUpdater.UpdateRemoteSyncerState(
+			ctx, pushed, syngit.LastPushedObjectStateKey, "",
+		)
+	}
+	if len(queued) > 0 {
+		UpdatePendingCommitsCount(ctx, sc, managerNamespace)
+	}
 
 	// Check if the webhook is allowed
+	var review admissionv1.AdmissionReview
 	if !IsWebhookAllowed(sc, false) {
-		return AdmissionReviewBuilder(
+		review = AdmissionReviewBuilder(
 			ctx, se.BuildInterceptorPipelineErr("the remote syncer is in CommitOnly mode"),
 			admReq, false, false, sc,
 		)
+	} else {
+		review = AdmissionReviewBuilder(ctx, BuildWebhookSuccessMessage(responses), admReq, true, false, sc)
 	}
+	review.Response.Warnings = BuildWebhookWarnings(sc, responses)
 
-	return AdmissionReviewBuilder(ctx, BuildWebhookSuccessMessage(responses), admReq, true, false, sc)
+	return review
+}
+
+// newRoutedObject returns what the routing rules match an object of the
+// admission request on: the object, the deleted one or the one before an
+// update, with its metadata.
+func newRoutedObject(admReq *admissionv1.AdmissionRequest, pathMetadata syngit.PathMetadata, raw []byte) (interceptor.RoutedObject, error) {
+	routedObject := interceptor.RoutedObject{
+		Group:     admReq.Kind.Group,
+		Version:   admReq.Kind.Version,
+		Kind:      admReq.Kind.Kind,
+		Namespace: admReq.Namespace,
+		Labels:    pathMetadata.Labels,
+	}
+
+	if len(raw) > 0 {
+		object, err := render.JSONToMap(raw)
+		if err != nil {
+			return routedObject, err
+		}
+		routedObject.Object = object
+	}
+	return routedObject, nil
+}
+
+// excludeRemoteTargets returns the RemoteTargets of each user that do not push
+// to a repository and a branch that the excluded ones of the user push to.
+func excludeRemoteTargets(
+	userRemoteTargets map[interceptor.GitUserInfo][]syngit.RemoteTarget,
+	excluded map[interceptor.GitUserInfo][]syngit.RemoteTarget,
+) map[interceptor.GitUserInfo][]syngit.RemoteTarget {
+	remaining := map[interceptor.GitUserInfo][]syngit.RemoteTarget{}
+	for userInfo, remoteTargets := range userRemoteTargets {
+		for _, remoteTarget := range remoteTargets {
+			if slices.ContainsFunc(excluded[userInfo], func(other syngit.RemoteTarget) bool {
+				return other.Spec.TargetRepository == remoteTarget.Spec.TargetRepository &&
+					other.Spec.TargetBranch == remoteTarget.Spec.TargetBranch
+			}) {
+				continue
+			}
+			remaining[userInfo] = append(remaining[userInfo], remoteTarget)
+		}
+	}
+	return remaining
 }
 
 type GitPushParameters struct {
@@ -168,6 +296,12 @@ type GitPushParameters struct {
 	// The syncer that has intercepted the object, resolved for this request.
 	Syncer interceptor.SyncerContext
 
+	// The RemoteTargets of the routing rule that the object matched before
+	// an update, that it is deleted from, and the syncer resolved for that
+	// rule. Only set when the update changed the rule.
+	PreviousUserInfoRemoteTargets map[interceptor.GitUserInfo][]syngit.RemoteTarget
+	PreviousSyncer                interceptor.SyncerContext
+
 	// The yaml manifest of the intercepted object.
 	YAMLManifest string
 
@@ -181,65 +315,307 @@ type GitPushParameters struct {
 	// Bundle containing the CAs of the targeted git platform(s).
 	CABundle []byte
 
+	// The proxy and the client certificate of the targeted git platform(s).
+	Transport interceptor.GitTransport
+
 	// Cluster reader handed to the mutation providers for live lookups. May be nil.
 	Cluster client.Reader
+
+	// The kubernetes user that has applied or delete the intercepted object.
+	Author authenticationv1.UserInfo
+
+	// The UID of the intercepted admission request.
+	AdmissionUID types.UID
+
+	// The namespace where the commits that cannot be pushed are queued
+	// when the syncer uses the BlockCacheCommit push error behavior.
+	ManagerNamespace string
+
+	// Whether the intercepted request is a dry run. The object is rendered in
+	// the repositories but nothing is committed, pushed or queued.
+	DryRun bool
 }
 
+// RunGitPushPipeline pushes the intercepted object to every RemoteTarget of
+// every user, and returns the responses in the same order. The RemoteTargets
+// are pushed concurrently, up to the maxConcurrentPushes of the syncer. Every
+// RemoteTarget is tried even once one has failed, and the error names each of
+// the ones that failed.
 func RunGitPushPipeline(ctx context.Context, params GitPushParameters) ([]interceptor.GitPushResponse, error) {
-	responses := make([]interceptor.GitPushResponse, 0, len(params.UserInfoRemoteTargets))
-
 	cluster := params.Cluster
 
-	for userInfo, remoteTargets := range params.UserInfoRemoteTargets {
-		for _, remoteTarget := range remoteTargets {
-			params := &interceptor.GitPipelineParams{
-				Syncer:          params.Syncer,
-				RemoteTarget:    *remoteTarget.DeepCopy(),
-				InterceptedYAML: params.YAMLManifest,
-				InterceptedGVR:  params.ObjectMetadata.GVR,
-				InterceptedName: params.ObjectMetadata.Name,
-				GitUserInfo:     userInfo,
-				Operation:       params.Operation,
-				CABundle:        params.CABundle,
-			}
-			res, err := pusher.RunGitPipeline(ctx, cluster, *params)
-			if err != nil {
+	cacheCommits := params.Syncer.Spec.DefaultPushErrorBehavior == syngit.BlockCacheCommit
+
+	// With ApplyThenCommit, the changes are pushed once the request is admitted.
+	async := params.Syncer.Spec.Strategy == syngit.ApplyThenCommit && !params.DryRun
+	committer := AsyncCommitterFromContext(ctx)
+	if async && committer == nil {
+		return nil, fmt.Errorf("no background committer is running to push the changes of the %s strategy", syngit.ApplyThenCommit)
+	}
+
+	targets := gitPipelineTargets(params)
+
+	switch {
+	case params.DryRun:
+		return runEachTarget(params.Syncer, targets, func(pipelineParams interceptor.GitPipelineParams) (interceptor.GitPushResponse, error) {
+			return pusher.RunGitDryRunPipeline(ctx, cluster, pipelineParams)
+		})
+
+	case async:
+		responses := make([]interceptor.GitPushResponse, len(targets))
+		for i, pipelineParams := range targets {
+			if err := committer.Enqueue(params, pipelineParams); err != nil {
 				return nil, err
 			}
-
-			if res.CommitHash == "" {
-				return nil, fmt.Errorf("the commit hash is empty")
+			responses[i] = interceptor.GitPushResponse{
+				URL:   pipelineParams.RemoteTarget.Spec.TargetRepository,
+				Async: true,
 			}
+		}
+		return responses, nil
 
-			responses = append(responses, res)
+	case cacheCommits:
+		// A commit that cannot be pushed is queued: nothing is undone.
+		return runEachTarget(params.Syncer, targets, func(pipelineParams interceptor.GitPipelineParams) (interceptor.GitPushResponse, error) {
+			return runOrQueueGitPipeline(ctx, params, pipelineParams)
+		})
+	}
+
+	responses, err := pusher.RunGitPipelines(ctx, cluster, targets)
+	if err != nil {
+		return nil, err
+	}
+	for _, res := range responses {
+		if res.CommitHash == "" {
+			return nil, fmt.Errorf("the commit hash is empty")
+		}
+	}
+
+	return responses, nil
+}
+
+// gitPipelineTargets returns the git pipeline of every RemoteTarget of every
+// user, then the deletion of the object from the RemoteTargets of its previous
+// routing rule.
+func gitPipelineTargets(params GitPushParameters) []interceptor.GitPipelineParams {
+	targets := make([]interceptor.GitPipelineParams, 0, len(params.UserInfoRemoteTargets))
+	for userInfo, remoteTargets := range params.UserInfoRemoteTargets {
+		for _, remoteTarget := range remoteTargets {
+			targets = append(targets, interceptor.GitPipelineParams{
+				Syncer:                     params.Syncer,
+				RemoteTarget:               *remoteTarget.DeepCopy(),
+				InterceptedYAML:            params.YAMLManifest,
+				InterceptedGVR:             params.ObjectMetadata.GVR,
+				InterceptedName:            params.ObjectMetadata.Name,
+				InterceptedPathMetadata:    params.ObjectMetadata.PathMetadata,
+				InterceptedOldPathMetadata: params.ObjectMetadata.OldPathMetadata,
+				GitUserInfo:                userInfo,
+				Operation:                  params.Operation,
+				CABundle:                   params.CABundle,
+				Transport:                  params.Transport,
+				Author:                     params.Author,
+				AdmissionUID:               params.AdmissionUID,
+			})
 		}
 	}
+	for userInfo, remoteTargets := range params.PreviousUserInfoRemoteTargets {
+		for _, remoteTarget := range remoteTargets {
+			targets = append(targets, interceptor.GitPipelineParams{
+				Syncer:                  params.PreviousSyncer,
+				RemoteTarget:            *remoteTarget.DeepCopy(),
+				InterceptedGVR:          params.ObjectMetadata.GVR,
+				InterceptedName:         params.ObjectMetadata.Name,
+				InterceptedPathMetadata: *params.ObjectMetadata.OldPathMetadata,
+				GitUserInfo:             userInfo,
+				Operation:               admissionv1.Delete,
+				CABundle:                params.CABundle,
+				Transport:               params.Transport,
+				Author:                  params.Author,
+				AdmissionUID:            params.AdmissionUID,
+			})
+		}
+	}
+
+	return targets
+}
 
+// runEachTarget runs run for each target, concurrently up to the
+// maxConcurrentPushes of the syncer, and returns the responses in the order of
+// the targets.
+func runEachTarget(
+	syncer interceptor.SyncerContext,
+	targets []interceptor.GitPipelineParams,
+	run func(interceptor.GitPipelineParams) (interceptor.GitPushResponse, error),
+) ([]interceptor.GitPushResponse, error) {
+	responses := make([]interceptor.GitPushResponse, len(targets))
+	errs := make([]error, len(targets))
+	pusher.RunConcurrently(syncer, len(targets), func(i int) {
+		responses[i], errs[i] = run(targets[i])
+		if errs[i] != nil {
+			errs[i] = fmt.Errorf("%s (branch %s): %w",
+				targets[i].RemoteTarget.Spec.TargetRepository, targets[i].RemoteTarget.Spec.TargetBranch, errs[i])
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
 	return responses, nil
 }
 
+// Pushes the commit unless older commits are still pending on the same branch,
+// in which case it is queued behind them: the branch must receive the changes
+// in the order they were made. A commit that fails to be pushed is queued too.
+func runOrQueueGitPipeline(
+	ctx context.Context,
+	params GitPushParameters,
+	pipelineParams interceptor.GitPipelineParams,
+) (interceptor.GitPushResponse, error) {
+	k8sClient := kube.ClientFromContext(ctx)
+	target := pipelineParams.RemoteTarget.Spec
+
+	pendingCommits, err := ListPendingCommits(
+		ctx, k8sClient, params.ManagerNamespace, target.TargetRepository, target.TargetBranch,
+	)
+	if err != nil {
+		return interceptor.GitPushResponse{}, fmt.Errorf("failed to list the pending commits: %w", err)
+	}
+
+	if len(pendingCommits) == 0 {
+		res, err := pusher.RunGitPipeline(ctx, params.Cluster, pipelineParams)
+		if err == nil {
+			if res.CommitHash == "" {
+				return interceptor.GitPushResponse{}, fmt.Errorf("the commit hash is empty")
+			}
+			return res, nil
+		}
+		log.Log.Info("the commit could not be pushed, queueing it",
+			"syncer", params.Syncer.String(),
+			"repository", target.TargetRepository,
+			"branch", target.TargetBranch,
+			"error", err.Error(),
+		)
+	}
+
+	pendingCommit := NewPendingCommit(params.ManagerNamespace, pipelineParams, params.Author, params.ObjectMetadata)
+	if err := k8sClient.Create(ctx, pendingCommit); err != nil {
+		return interceptor.GitPushResponse{}, fmt.Errorf("failed to queue the commit: %w", err)
+	}
+	manifest := NewPendingCommitManifest(pendingCommit, pipelineParams.InterceptedYAML)
+	if err := k8sClient.Create(ctx, manifest); err != nil {
+		// A commit without its manifest cannot be replayed.
+		_ = client.IgnoreNotFound(k8sClient.Delete(ctx, pendingCommit))
+		return interceptor.GitPushResponse{}, fmt.Errorf("failed to store the manifest of the queued commit: %w", err)
+	}
+
+	return interceptor.GitPushResponse{
+		URL:           target.TargetRepository,
+		PendingCommit: pendingCommit.Name,
+	}, nil
+}
+
+// SplitPendingResponses separates the commits that have been pushed
+// from the ones that have been queued.
+func SplitPendingResponses(responses []interceptor.GitPushResponse) (pushed, queued []interceptor.GitPushResponse) {
+	for _, res := range responses {
+		if res.PendingCommit != "" {
+			queued = append(queued, res)
+		} else {
+			pushed = append(pushed, res)
+		}
+	}
+	return pushed, queued
+}
+
 // Check if there is no error at all during the pipeline processing
-// and if the RemoteSyncer is configured to CommitApply mode.
+// and if the RemoteSyncer is configured to CommitApply or ApplyThenCommit mode.
 func IsWebhookAllowed(
 	sc interceptor.SyncerContext,
 	pipelineErrored bool,
 ) bool {
-	if !pipelineErrored && sc.Spec.Strategy == syngit.CommitApply {
+	if !pipelineErrored &&
+		(sc.Spec.Strategy == syngit.CommitApply || sc.Spec.Strategy == syngit.ApplyThenCommit) {
 		return true
 	}
 	return false
 }
 
+// Build the webhook message of a dry run based on the locations
+// where the resource would be pushed.
+func BuildWebhookDryRunMessage(responses []interceptor.GitPushResponse) string {
+	message := "\nThe resource would be pushed to:\n"
+	for _, res := range responses {
+		message += fmt.Sprintf("- repo: %s\n", res.URL)
+		if len(res.Paths) == 0 {
+			message += "  no file would change\n"
+			continue
+		}
+		message += "  paths:\n"
+		for _, path := range res.Paths {
+			message += fmt.Sprintf("    %s\n", path)
+		}
+	}
+	return message
+}
+
 // Build the webhook success message based on the locations
 // where the resource has been pushed.
 func BuildWebhookSuccessMessage(responses []interceptor.GitPushResponse) string {
 	message := "The resource has been push to:\n"
 	for _, res := range responses {
+		if res.PendingCommit != "" {
+			message += fmt.Sprintf("- repo: %s\n  queued as pendingcommit/%s until the push succeeds\n",
+				res.URL, res.PendingCommit)
+			continue
+		}
+		if res.Async {
+			message += fmt.Sprintf("- repo: %s\n  the push runs in the background\n", res.URL)
+			continue
+		}
 		message += fmt.Sprintf("- repo: %s\n  paths:", res.URL)
 		for _, path := range res.Paths {
 			message += fmt.Sprintf("    %s\n", path)
 		}
 		message += fmt.Sprintf("  commit hash: %s", res.CommitHash)
+		if res.ChangeRequestURL != "" {
+			message += fmt.Sprintf("\n  change request: %s", res.ChangeRequestURL)
+		}
 	}
 	return message
 }
+
+// Build the warnings of the admission response that preview the diff of each
+// repository, when the syncer enables it. The kube-apiserver rejects warnings
+// with control characters, so the diff is returned one line per warning.
+func BuildWebhookWarnings(sc interceptor.SyncerContext, responses []interceptor.GitPushResponse) []string {
+	if !sc.Spec.DiffPreview.Enabled {
+		return nil
+	}
+
+	warnings := []string{}
+	for _, res := range responses {
+		if res.PendingCommit != "" {
+			continue
+		}
+		if res.CommitHash == "" {
+			warnings = append(warnings, fmt.Sprintf("dry run against %s:", res.URL))
+		} else {
+			warnings = append(warnings, fmt.Sprintf("commit %s pushed to %s", res.CommitHash, res.URL))
+			if commitURL, err := interceptor.CommitURL(res.URL, res.CommitHash); err == nil {
+				warnings = append(warnings, commitURL)
+			}
+		}
+		if res.Diff == "" {
+			warnings = append(warnings, "no change")
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimRight(res.Diff, "\n"), "\n") {
+			warnings = append(warnings, strings.Map(func(r rune) rune {
+				if unicode.IsControl(r) {
+					return ' '
+				}
+				return r
+			}, line))
+		}
+	}
+	return warnings
+}
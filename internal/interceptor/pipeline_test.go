@@ -6,6 +6,8 @@ import (
 
 	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
 	"github.com/syngit-org/syngit/pkg/interceptor"
+	"github.com/syngit-org/syngit/pkg/webhooks"
+	admissionv1 "k8s.io/api/admission/v1"
 )
 
 func TestIsWebhookAllowed(t *testing.T) {
@@ -25,6 +27,8 @@ func TestIsWebhookAllowed(t *testing.T) {
 		{"CommitApply, errored -> denied", syngit.CommitApply, true, false},
 		{"CommitOnly, no error -> denied", syngit.CommitOnly, false, false},
 		{"CommitOnly, errored -> denied", syngit.CommitOnly, true, false},
+		{"ApplyThenCommit, no error -> allowed", syngit.ApplyThenCommit, false, true},
+		{"ApplyThenCommit, errored -> denied", syngit.ApplyThenCommit, true, false},
 	}
 
 	for _, tc := range tests {
@@ -55,6 +59,29 @@ func TestBuildWebhookSuccessMessage(t *testing.T) {
 		}
 	})
 
+	t.Run("queued response names the pending commit", func(t *testing.T) {
+		got := BuildWebhookSuccessMessage([]interceptor.GitPushResponse{
+			{URL: "https://one", Paths: []string{"p1"}, CommitHash: "h1"},
+			{URL: "https://two", PendingCommit: "pc-x1"},
+		})
+		for _, s := range []string{"https://one", "h1", "https://two", "pendingcommit/pc-x1"} {
+			if !strings.Contains(got, s) {
+				t.Errorf("message %q missing %q", got, s)
+			}
+		}
+	})
+
+	t.Run("async response says the push runs in the background", func(t *testing.T) {
+		got := BuildWebhookSuccessMessage([]interceptor.GitPushResponse{
+			{URL: "https://one", Async: true},
+		})
+		for _, s := range []string{"https://one", "in the background"} {
+			if !strings.Contains(got, s) {
+				t.Errorf("message %q missing %q", got, s)
+			}
+		}
+	})
+
 	t.Run("multiple responses all appear", func(t *testing.T) {
 		got := BuildWebhookSuccessMessage([]interceptor.GitPushResponse{
 			{URL: "https://one", Paths: []string{"p1"}, CommitHash: "h1"},
@@ -67,3 +94,102 @@ func TestBuildWebhookSuccessMessage(t *testing.T) {
 		}
 	})
 }
+
+func TestBuildWebhookDryRunMessage(t *testing.T) {
+	got := BuildWebhookDryRunMessage([]interceptor.GitPushResponse{
+		{URL: "https://one", Paths: []string{"p1"}},
+		{URL: "https://two", Paths: []string{}},
+	})
+	for _, s := range []string{"https://one", "p1", "https://two", "no file would change"} {
+		if !strings.Contains(got, s) {
+			t.Errorf("message %q missing %q", got, s)
+		}
+	}
+	if strings.Contains(got, "commit:") {
+		t.Errorf("dry run message %q should not name a commit", got)
+	}
+}
+
+func TestBuildWebhookWarnings(t *testing.T) {
+	responses := []interceptor.GitPushResponse{
+		{URL: "https://github.com/org/repo.git", CommitHash: "h1", Diff: "--- a/p1\n+++ b/p1\n+\tkey\n"},
+		{URL: "https://two", PendingCommit: "pc-x1"},
+	}
+
+	if got := BuildWebhookWarnings(interceptor.SyncerContext{}, responses); got != nil {
+		t.Errorf("warnings %v returned without diff preview", got)
+	}
+
+	sc := interceptor.SyncerContext{}
+	sc.Spec.DiffPreview.Enabled = true
+	got := BuildWebhookWarnings(sc, responses)
+	want := []string{
+		"commit h1 pushed to https://github.com/org/repo.git",
+		"https://github.com/org/repo/commit/h1",
+		"--- a/p1",
+		"+++ b/p1",
+		"+ key",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("BuildWebhookWarnings()=%q, want %q", got, want)
+	}
+}
+
+func TestGitPipelineTargetsOfAReroutedObject(t *testing.T) {
+	remoteTarget := func(repository, branch string) syngit.RemoteTarget {
+		return syngit.RemoteTarget{Spec: syngit.RemoteTargetSpec{TargetRepository: repository, TargetBranch: branch}}
+	}
+	alice := interceptor.GitUserInfo{User: "alice"}
+	bob := interceptor.GitUserInfo{User: "bob"}
+
+	current := map[interceptor.GitUserInfo][]syngit.RemoteTarget{
+		alice: {remoteTarget("https://git/team-b.git", "main"), remoteTarget("https://git/shared.git", "main")},
+	}
+	previous := excludeRemoteTargets(map[interceptor.GitUserInfo][]syngit.RemoteTarget{
+		alice: {remoteTarget("https://git/team-a.git", "main"), remoteTarget("https://git/shared.git", "main")},
+		bob:   {remoteTarget("https://git/shared.git", "main")},
+	}, current)
+
+	// The object is moved within the commit on the RemoteTarget both rules push to.
+	if got := len(previous[alice]); got != 1 || previous[alice][0].Spec.TargetRepository != "https://git/team-a.git" {
+		t.Fatalf("previous RemoteTargets of alice=%v, want team-a only", previous[alice])
+	}
+	if got := len(previous[bob]); got != 1 {
+		t.Fatalf("previous RemoteTargets of bob=%v, want shared", previous[bob])
+	}
+
+	previousSyncer := interceptor.SyncerContext{RoutingRule: &syngit.RoutingRule{Name: "team-a"}}
+	targets := gitPipelineTargets(GitPushParameters{
+		UserInfoRemoteTargets:         current,
+		PreviousUserInfoRemoteTargets: previous,
+		PreviousSyncer:                previousSyncer,
+		Syncer:                        interceptor.SyncerContext{RoutingRule: &syngit.RoutingRule{Name: "team-b"}},
+		YAMLManifest:                  "kind: ConfigMap\n",
+		ObjectMetadata: webhooks.ObjectMetadata{
+			Name:            "config",
+			PathMetadata:    syngit.PathMetadata{Labels: map[string]string{"team": "b"}},
+			OldPathMetadata: &syngit.PathMetadata{Labels: map[string]string{"team": "a"}},
+		},
+		Operation: admissionv1.Update,
+	})
+
+	if len(targets) != 4 {
+		t.Fatalf("got %d targets, want 4", len(targets))
+	}
+	for _, target := range targets[:2] {
+		if target.Operation != admissionv1.Update || target.Syncer.RoutingRule.Name != "team-b" || target.InterceptedYAML == "" {
+			t.Errorf("target %s: operation=%s rule=%s, want the update with team-b",
+				target.RemoteTarget.Spec.TargetRepository, target.Operation, target.Syncer.RoutingRule.Name)
+		}
+	}
+	for _, target := range targets[2:] {
+		if target.Operation != admissionv1.Delete || target.Syncer.RoutingRule.Name != "team-a" || target.InterceptedYAML != "" {
+			t.Errorf("target %s: operation=%s rule=%s, want the deletion with team-a",
+				target.RemoteTarget.Spec.TargetRepository, target.Operation, target.Syncer.RoutingRule.Name)
+		}
+		if target.InterceptedPathMetadata.Labels["team"] != "a" {
+			t.Errorf("target %s: deleted with the labels %v, want the previous ones",
+				target.RemoteTarget.Spec.TargetRepository, target.InterceptedPathMetadata.Labels)
+		}
+	}
+}
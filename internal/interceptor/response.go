@@ -13,8 +13,15 @@ import (
 const (
 	defaultFailureMessage = "the changes have not been pushed to the remote git repository: "
 	defaultSuccessMessage = "the changes were correctly been pushed on the remote git repository"
+	defaultDryRunMessage  = "dry run: nothing has been pushed to the remote git repository"
 )
 
+// IsDryRun reports whether the admission request must not have side effects,
+// as for kubectl apply --dry-run=server or kubectl diff.
+func IsDryRun(admissionRequest *admissionv1.AdmissionRequest) bool {
+	return admissionRequest.DryRun != nil && *admissionRequest.DryRun
+}
+
 func AdmissionReviewBuilder(
 	ctx context.Context,
 	addionalMessage string,
@@ -24,11 +31,17 @@ func AdmissionReviewBuilder(
 ) admissionv1.AdmissionReview {
 	statusUpdater := NewRemoteSyncerStatusUpdater(admissionRequest, sc)
 	conditionUpdater := NewRemoteSyncerConditionUpdater(sc)
+	// A dry-run request leaves the syncer untouched: the webhook is
+	// registered with the NoneOnDryRun side effects class.
+	dryRun := IsDryRun(admissionRequest)
 
 	successMessage := defaultSuccessMessage
 	if sc.Spec.DefaultBlockAppliedMessage != "" {
 		successMessage = sc.Spec.DefaultBlockAppliedMessage
 	}
+	if dryRun {
+		successMessage = defaultDryRunMessage
+	}
 
 	// Set the status and the message depending of the status of the webhook
 	status := "Failure"
@@ -37,8 +50,10 @@ func AdmissionReviewBuilder(
 	if !processErrored {
 		status = "Success"
 		message = successMessage
-		conditionUpdater.UpdateRemoteSyncerConditions(ctx, BuildSuccessCondition(""))
-	} else {
+		if !dryRun {
+			conditionUpdater.UpdateRemoteSyncerConditions(ctx, BuildSuccessCondition(""))
+		}
+	} else if !dryRun {
 		conditionUpdater.UpdateRemoteSyncerConditions(ctx, BuildErrorCondition(addionalMessage))
 	}
 
@@ -47,9 +62,11 @@ func AdmissionReviewBuilder(
 		message += addionalMessage
 	}
 
-	statusUpdater.UpdateRemoteSyncerState(
-		ctx, []interceptor.GitPushResponse{}, syngit.LastObservedObjectStateKey, message,
-	)
+	if !dryRun {
+		statusUpdater.UpdateRemoteSyncerState(
+			ctx, []interceptor.GitPushResponse{}, syngit.LastObservedObjectStateKey, message,
+		)
+	}
 
 	// Construct the admisson review request
 	admissionReviewResp := admissionv1.AdmissionReview{
@@ -24,7 +24,9 @@ import (
 // If no RemoteTargets found, then fallback to the
 // RemoteSyncer's default RemoteTarget.
 // Returns a map of the credentials to access to
-// the target defined by the RemoteTarget
+// the target defined by the RemoteTarget.
+// The routing rule of the syncer context, if any,
+// sets the branch the RemoteTargets push to.
 func GetUserInfoRemoteTargetsAssociation( // nolint: gocyclo
 	ctx context.Context,
 	user authenticationv1.UserInfo,
@@ -38,7 +40,7 @@ func GetUserInfoRemoteTargetsAssociation( // nolint: gocyclo
 		ctx,
 		sc,
 		user.Username,
-		remoteSyncerRemoteRepoUrl.Host,
+		interceptor.RepositoryHost(remoteSyncerRemoteRepoUrl),
 	)
 	if err != nil {
 		return userTargetsMap, err
@@ -88,18 +90,18 @@ func GetUserInfoRemoteTargetsAssociation( // nolint: gocyclo
 			if labelSelector != nil && !labelSelector.Matches(labels.Set(remoteTarget.Labels)) {
 				continue
 			}
-			rtUrl, err := url.Parse(remoteTarget.Spec.TargetRepository)
+			rtUrl, err := interceptor.ParseRepositoryURL(remoteTarget.Spec.TargetRepository)
 			if err != nil {
 				return userTargetsMap, err
 			}
 			if remoteTarget.Spec.UpstreamRepository == sc.Spec.RemoteRepository && remoteTarget.Spec.UpstreamBranch == sc.Spec.DefaultBranch {
 				for _, remoteUser := range remoteUsers {
-					if rtUrl.Host == remoteUser.Spec.GitBaseDomainFQDN {
+					if interceptor.RepositoryHost(rtUrl) == remoteUser.Spec.GitBaseDomainFQDN {
 						gitUserInfo, err := GetGitUserInfoByRemoteUser(ctx, *remoteUser)
 						if err != nil {
 							return userTargetsMap, err
 						}
-						userTargetsMap[*gitUserInfo] = append(userTargetsMap[*gitUserInfo], *remoteTarget)
+						userTargetsMap[*gitUserInfo] = append(userTargetsMap[*gitUserInfo], sc.RouteRemoteTarget(*remoteTarget))
 					}
 				}
 			}
@@ -144,7 +146,7 @@ func GetUserInfoRemoteTargetsAssociation( // nolint: gocyclo
 			return userTargetsMap, syngiterrors.NewRemoteUserNotFound("the default RemoteUser is not found")
 		}
 
-		if remoteUser.Spec.GitBaseDomainFQDN != remoteSyncerRemoteRepoUrl.Host {
+		if remoteUser.Spec.GitBaseDomainFQDN != interceptor.RepositoryHost(remoteSyncerRemoteRepoUrl) {
 			return userTargetsMap, syngiterrors.NewWrongRemoteTargetConfig(sc.String(), *remoteUser)
 		}
 		gitUserInfo, err := GetGitUserInfoByRemoteUser(ctx, *remoteUser)
@@ -184,7 +186,7 @@ func GetUserInfoRemoteTargetsAssociation( // nolint: gocyclo
 			))
 		}
 
-		userTargetsMap[*gitUserInfo] = append(userTargetsMap[*gitUserInfo], *remoteTarget)
+		userTargetsMap[*gitUserInfo] = append(userTargetsMap[*gitUserInfo], sc.RouteRemoteTarget(*remoteTarget))
 	}
 
 	return userTargetsMap, nil
@@ -334,20 +336,162 @@ func GetGitUserInfoByRemoteUser(
 		return nil, syngiterrors.NewCredentialsNotFound("connection error", secretNamespacedName.Name)
 	}
 
-	token := string(secret.Data["password"])
+	var gitUser *interceptor.GitUserInfo
+	if secret.Type == corev1.SecretTypeSSHAuth {
+		gitUser, err = getSSHGitUserInfo(ctx, remoteUser, secret)
+	} else {
+		gitUser, err = getTokenGitUserInfo(remoteUser, secret)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	gitUser.SigningKey, gitUser.SigningKeyPassphrase, err = getSigningKey(ctx, remoteUser)
+	if err != nil {
+		return nil, err
+	}
+
+	return gitUser, nil
+}
+
+// Reads the credentials of a RemoteUser that authenticates with a token.
+func getTokenGitUserInfo(
+	remoteUser syngit.RemoteUser,
+	secret *corev1.Secret,
+) (*interceptor.GitUserInfo, error) {
+	token := string(secret.Data[corev1.BasicAuthPasswordKey])
 
 	gitUser := &interceptor.GitUserInfo{
-		User:  string(secret.Data["username"]),
+		User:  string(secret.Data[corev1.BasicAuthUsernameKey]),
 		Email: remoteUser.Spec.Email,
 		Token: token,
+		RemoteUserRef: types.NamespacedName{
+			Namespace: remoteUser.Namespace,
+			Name:      remoteUser.Name,
+		},
 	}
 
 	if token == "" {
 		return nil, syngiterrors.NewCredentialsNotFound(
 			"token not found; the token must be specified in the password field and the secret type must be kubernetes.io/basic-auth",
-			secretNamespacedName.Name,
+			secret.Name,
 		)
 	}
 
 	return gitUser, nil
 }
+
+// Reads the credentials of a RemoteUser that authenticates with an SSH key.
+// The key alone does not name anybody, so the commits are authored by the
+// optional "username" entry of the Secret, or by the RemoteUser itself.
+func getSSHGitUserInfo(
+	ctx context.Context,
+	remoteUser syngit.RemoteUser,
+	secret *corev1.Secret,
+) (*interceptor.GitUserInfo, error) {
+	privateKey := string(secret.Data[corev1.SSHAuthPrivateKey])
+	if privateKey == "" {
+		return nil, syngiterrors.NewCredentialsNotFound(
+			"private key not found; the key must be specified in the "+corev1.SSHAuthPrivateKey+" field",
+			secret.Name,
+		)
+	}
+
+	knownHosts, err := getKnownHosts(ctx, remoteUser)
+	if err != nil {
+		return nil, err
+	}
+
+	user := string(secret.Data[corev1.BasicAuthUsernameKey])
+	if user == "" {
+		user = remoteUser.Name
+	}
+
+	return &interceptor.GitUserInfo{
+		User:          user,
+		Email:         remoteUser.Spec.Email,
+		SSHPrivateKey: privateKey,
+		KnownHosts:    knownHosts,
+		RemoteUserRef: types.NamespacedName{
+			Namespace: remoteUser.Namespace,
+			Name:      remoteUser.Name,
+		},
+	}, nil
+}
+
+// Reads the known_hosts entries referenced by a RemoteUser.
+func getKnownHosts(ctx context.Context, remoteUser syngit.RemoteUser) (string, error) {
+	ref := remoteUser.Spec.KnownHostsSecretRef
+	if ref == nil || ref.Name == "" {
+		return "", syngiterrors.NewCredentialsNotFound(
+			"known hosts not found; spec.knownHostsSecretRef must be set to use an SSH key for remote user: "+remoteUser.Name,
+			remoteUser.Spec.SecretRef.Name,
+		)
+	}
+
+	namespace, err := refs.ResolveNamespace(
+		ref.Namespace,
+		remoteUser.Namespace,
+		field.NewPath("spec", "knownHostsSecretRef"),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	secret := &corev1.Secret{}
+	if err := kube.ClientFromContext(ctx).Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", syngiterrors.NewCredentialsNotFound("known hosts secret not found for remote user: "+remoteUser.Name, ref.Name)
+		}
+		return "", syngiterrors.NewCredentialsNotFound("connection error", ref.Name)
+	}
+
+	knownHosts := string(secret.Data[syngit.KnownHostsKey])
+	if knownHosts == "" {
+		return "", syngiterrors.NewCredentialsNotFound(
+			"known hosts not found; the entries must be specified in the "+syngit.KnownHostsKey+" field",
+			ref.Name,
+		)
+	}
+
+	return knownHosts, nil
+}
+
+// Reads the key the commits of a RemoteUser are signed with. It returns an
+// empty key when the RemoteUser does not sign its commits.
+func getSigningKey(ctx context.Context, remoteUser syngit.RemoteUser) (string, string, error) {
+	ref := remoteUser.Spec.SigningKeySecretRef
+	if ref == nil || ref.Name == "" {
+		return "", "", nil
+	}
+
+	namespace, err := refs.ResolveNamespace(
+		ref.Namespace,
+		remoteUser.Namespace,
+		field.NewPath("spec", "signingKeySecretRef"),
+	)
+	if err != nil {
+		return "", "", err
+	}
+
+	secret := &corev1.Secret{}
+	if err := kube.ClientFromContext(ctx).Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", "", syngiterrors.NewCredentialsNotFound("signing key secret not found for remote user: "+remoteUser.Name, ref.Name)
+		}
+		return "", "", syngiterrors.NewCredentialsNotFound("connection error", ref.Name)
+	}
+
+	signingKey := string(secret.Data[syngit.SigningKeyKey])
+	if signingKey == "" && secret.Type == corev1.SecretTypeSSHAuth {
+		signingKey = string(secret.Data[corev1.SSHAuthPrivateKey])
+	}
+	if signingKey == "" {
+		return "", "", syngiterrors.NewCredentialsNotFound(
+			"signing key not found; the key must be specified in the "+syngit.SigningKeyKey+" field",
+			ref.Name,
+		)
+	}
+
+	return signingKey, string(secret.Data[syngit.SigningKeyPassphraseKey]), nil
+}
@@ -5,7 +5,6 @@ import (
 	"errors"
 	"net/url"
 	"os"
-	"strings"
 
 	"github.com/syngit-org/syngit/pkg/interceptor"
 	"github.com/syngit-org/syngit/pkg/kube"
@@ -21,7 +20,7 @@ func CABundleBuilder(
 	remoteSyncerRemoteRepoUrl *url.URL,
 ) ([]byte, error) {
 	// Step 1: Search for the global CA Bundle of the server located in the syngit namespace
-	caBundle, err := FindGlobalCABundle(ctx, strings.Split(remoteSyncerRemoteRepoUrl.Host, ":")[0])
+	caBundle, err := FindGlobalCABundle(ctx, remoteSyncerRemoteRepoUrl.Hostname())
 	if err != nil && errors.Is(err, ErrCaSecretWrongType) {
 		return nil, err
 	}
@@ -0,0 +1,121 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"github.com/syngit-org/syngit/pkg/kube"
+	"github.com/syngit-org/syngit/pkg/refs"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// TransportBuilder returns the proxy and the client certificate the git
+// server of the syncer is reached with. The ones of the syncer override the
+// ones of the host of its repository, read from the "<host>-proxy" and the
+// "<host>-client-cert" Secrets of the namespace of syngit.
+func TransportBuilder(
+	ctx context.Context,
+	sc interceptor.SyncerContext,
+	remoteSyncerRemoteRepoUrl *url.URL,
+) (interceptor.GitTransport, error) {
+	transport := interceptor.GitTransport{}
+	managerNamespace := os.Getenv("MANAGER_NAMESPACE")
+	host := remoteSyncerRemoteRepoUrl.Hostname()
+
+	// Step 1: Search for the global proxy and client certificate of the server located in the syngit namespace
+	proxySecret, err := findSecret(ctx, managerNamespace, host+"-proxy")
+	if err != nil && !apierrors.IsNotFound(err) {
+		return transport, err
+	}
+	if proxySecret != nil {
+		transport.ProxyURL = string(proxySecret.Data["url"])
+		if transport.ProxyURL == "" {
+			return transport, fmt.Errorf("the proxy secret %s/%s has no \"url\" key", managerNamespace, proxySecret.Name)
+		}
+		transport.ProxyUsername = string(proxySecret.Data["username"])
+		transport.ProxyPassword = string(proxySecret.Data["password"])
+	}
+	transport.ClientCert, transport.ClientKey, err = FindClientCert(ctx, managerNamespace, host+"-client-cert")
+	if err != nil && !apierrors.IsNotFound(err) {
+		return transport, err
+	}
+
+	// Step 2: Search for the specific proxy and client certificate, by default in the current namespace
+	proxy := sc.Spec.Proxy
+	if proxy.URL != "" {
+		transport.ProxyURL = proxy.URL
+		transport.ProxyUsername = ""
+		transport.ProxyPassword = ""
+	}
+	if proxy.SecretRef.Name != "" {
+		ns, err := refs.ResolveNamespace(
+			proxy.SecretRef.Namespace,
+			sc.RefOwnerNamespace,
+			field.NewPath("spec", "proxy", "secretRef"),
+		)
+		if err != nil {
+			return transport, err
+		}
+		proxySecret, err := findSecret(ctx, ns, proxy.SecretRef.Name)
+		if err != nil {
+			return transport, err
+		}
+		transport.ProxyUsername = string(proxySecret.Data["username"])
+		transport.ProxyPassword = string(proxySecret.Data["password"])
+	}
+
+	clientCertSecretRef := sc.Spec.ClientCertSecretRef
+	if clientCertSecretRef.Name != "" {
+		ns, err := refs.ResolveNamespace(
+			clientCertSecretRef.Namespace,
+			sc.RefOwnerNamespace,
+			field.NewPath("spec", "clientCertSecretRef"),
+		)
+		if err != nil {
+			return transport, err
+		}
+		transport.ClientCert, transport.ClientKey, err = FindClientCert(ctx, ns, clientCertSecretRef.Name)
+		if err != nil {
+			return transport, err
+		}
+	}
+
+	return transport, nil
+}
+
+var ErrClientCertSecretWrongType = errors.New("the client certificate secret must be of type \"kubernetes.io/tls\"") // nolint:lll
+
+// FindClientCert returns the PEM encoded certificate and key stored in a
+// Secret of type kubernetes.io/tls, after checking that they form a pair.
+func FindClientCert(ctx context.Context, namespace string, name string) ([]byte, []byte, error) {
+	clientCertSecret, err := findSecret(ctx, namespace, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	if clientCertSecret.Type != corev1.SecretTypeTLS {
+		return nil, nil, ErrClientCertSecretWrongType
+	}
+	cert := clientCertSecret.Data[corev1.TLSCertKey]
+	key := clientCertSecret.Data[corev1.TLSPrivateKeyKey]
+	if _, err := tls.X509KeyPair(cert, key); err != nil {
+		return nil, nil, fmt.Errorf("the client certificate secret %s/%s is invalid: %w", namespace, name, err)
+	}
+	return cert, key, nil
+}
+
+func findSecret(ctx context.Context, namespace string, name string) (*corev1.Secret, error) {
+	secret := &corev1.Secret{}
+	k8sClient := kube.ClientFromContext(ctx)
+	if err := k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
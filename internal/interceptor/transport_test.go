@@ -0,0 +1,135 @@
+package interceptor
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/url"
+	"testing"
+	"time"
+
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"github.com/syngit-org/syngit/pkg/kube"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// clientCertSecret returns a kubernetes.io/tls Secret holding a self-signed
+// client certificate.
+func clientCertSecret(t *testing.T, namespace, name string) *corev1.Secret {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate the key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create the certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to marshal the key: %v", err)
+	}
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Type:       corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			corev1.TLSPrivateKeyKey: pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+		},
+	}
+}
+
+func TestTransportBuilder(t *testing.T) {
+	t.Setenv("MANAGER_NAMESPACE", "syngit")
+	repoURL, _ := url.Parse("https://git.example.com/org/repo.git")
+
+	hostProxy := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "syngit", Name: "git.example.com-proxy"},
+		Data: map[string][]byte{
+			"url":      []byte("http://proxy.example.com:3128"),
+			"username": []byte("host-user"),
+			"password": []byte("host-password"),
+		},
+	}
+	hostCert := clientCertSecret(t, "syngit", "git.example.com-client-cert")
+	teamProxy := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "proxy-auth"},
+		Type:       corev1.SecretTypeBasicAuth,
+		Data: map[string][]byte{
+			corev1.BasicAuthUsernameKey: []byte("team-user"),
+			corev1.BasicAuthPasswordKey: []byte("team-password"),
+		},
+	}
+	teamCert := clientCertSecret(t, "team-a", "client-cert")
+	opaqueCert := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "opaque"},
+		Data:       teamCert.Data,
+	}
+	k8sClient := fake.NewClientBuilder().
+		WithObjects(hostProxy, hostCert, teamProxy, teamCert, opaqueCert).
+		Build()
+	ctx := context.WithValue(context.Background(), kube.ClientCtxKey{}, client.Client(k8sClient))
+
+	sc := interceptor.SyncerContext{RefOwnerNamespace: "team-a"}
+	transport, err := TransportBuilder(ctx, sc, repoURL)
+	if err != nil {
+		t.Fatalf("TransportBuilder: %v", err)
+	}
+	if transport.ProxyURL != "http://proxy.example.com:3128" || transport.ProxyUsername != "host-user" ||
+		transport.ProxyPassword != "host-password" {
+		t.Errorf("expected the proxy of the host, got %+v", transport)
+	}
+	if string(transport.ClientCert) != string(hostCert.Data[corev1.TLSCertKey]) {
+		t.Error("expected the client certificate of the host")
+	}
+
+	sc.Spec.Proxy = syngit.ProxyConfig{
+		URL:       "http://team-proxy.example.com:8080",
+		SecretRef: corev1.SecretReference{Name: "proxy-auth"},
+	}
+	sc.Spec.ClientCertSecretRef = corev1.SecretReference{Name: "client-cert"}
+	transport, err = TransportBuilder(ctx, sc, repoURL)
+	if err != nil {
+		t.Fatalf("TransportBuilder: %v", err)
+	}
+	if transport.ProxyURL != "http://team-proxy.example.com:8080" || transport.ProxyUsername != "team-user" ||
+		transport.ProxyPassword != "team-password" {
+		t.Errorf("expected the proxy of the syncer, got %+v", transport)
+	}
+	if string(transport.ClientCert) != string(teamCert.Data[corev1.TLSCertKey]) ||
+		string(transport.ClientKey) != string(teamCert.Data[corev1.TLSPrivateKeyKey]) {
+		t.Error("expected the client certificate of the syncer")
+	}
+
+	sc.Spec.ClientCertSecretRef = corev1.SecretReference{Name: "opaque"}
+	if _, err := TransportBuilder(ctx, sc, repoURL); !errors.Is(err, ErrClientCertSecretWrongType) {
+		t.Errorf("expected ErrClientCertSecretWrongType, got %v", err)
+	}
+
+	otherURL, _ := url.Parse("https://other.example.com/org/repo.git")
+	transport, err = TransportBuilder(ctx, interceptor.SyncerContext{RefOwnerNamespace: "team-a"}, otherURL)
+	if err != nil {
+		t.Fatalf("TransportBuilder: %v", err)
+	}
+	if transport.ProxyURL != "" || transport.ClientCert != nil {
+		t.Errorf("a host without Secrets must be reached directly, got %+v", transport)
+	}
+}
@@ -9,27 +9,43 @@ import (
 
 	"github.com/go-git/go-git/v5"
 	"github.com/syngit-org/syngit/internal/walker"
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
 	"github.com/syngit-org/syngit/pkg/interceptor"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
 )
 
 type DefaultWorktreeCustomizer struct{}
 
-// Writes the artifact to the pre-determined path:
+// Writes the artifact to the path rendered by the pathTemplate of the syncer,
+// or else to the pre-determined path:
 // ([RootPath/]<namespace>/<group>/<version>/<resource>/<name>.yaml)
 // and returns the claimed paths.
 func (dt DefaultWorktreeCustomizer) place(params interceptor.GitPipelineParams, artifacts ArtifactSet, worktree *git.Worktree, transform walker.DocTransform) (interceptor.ClaimedPaths, error) {
 	claimed := interceptor.NewClaimedPaths()
 
 	for _, a := range artifacts.Items {
-		path, err := dt.pathConstructor(params, a.GVR, worktree)
-		if err != nil {
-			return interceptor.NewClaimedPaths(), err
-		}
+		var fullFilePath string
+		if params.Syncer.Spec.PathTemplate != "" {
+			var err error
+			fullFilePath, err = dt.templatePath(params, a, worktree)
+			if err != nil {
+				return interceptor.NewClaimedPaths(), err
+			}
+			if err := dt.writeContent(a.Content, fullFilePath, worktree, a.transformOrNil(transform)); err != nil {
+				return interceptor.NewClaimedPaths(), err
+			}
+		} else {
+			path, err := dt.pathConstructor(params, a.GVR, worktree)
+			if err != nil {
+				return interceptor.NewClaimedPaths(), err
+			}
 
-		fullFilePath, err := dt.writeFile(params, a.Content, path, worktree, a.transformOrNil(transform))
-		if err != nil {
-			return interceptor.NewClaimedPaths(), err
+			fullFilePath, err = dt.writeFile(params, a.Content, path, worktree, a.transformOrNil(transform))
+			if err != nil {
+				return interceptor.NewClaimedPaths(), err
+			}
 		}
 
 		if a.IsDeletion() {
@@ -37,6 +53,19 @@ func (dt DefaultWorktreeCustomizer) place(params interceptor.GitPipelineParams,
 		} else {
 			claimed.AppendAddedPath(fullFilePath)
 		}
+
+		// An update of the labels or annotations that the pathTemplate uses,
+		// or of the routing rule that the object matches, moves the object:
+		// its previous file is removed in the same commit.
+		previousFilePath, err := dt.previousPath(params, a)
+		if err != nil {
+			return interceptor.NewClaimedPaths(), err
+		}
+		if previousFilePath != "" && previousFilePath != fullFilePath {
+			if _, err := worktree.Filesystem.Stat(previousFilePath); err == nil {
+				claimed.AppendDeletedPath(previousFilePath)
+			}
+		}
 	}
 
 	return claimed, nil
@@ -78,6 +107,101 @@ func (dt DefaultWorktreeCustomizer) pathConstructor(params interceptor.GitPipeli
 	return path, nil
 }
 
+// templatePath renders the pathTemplate of the syncer for the artifact and
+// creates the directory of the file. A path that does not name a YAML file
+// is the directory of a "<name>.yaml" file.
+func (dt DefaultWorktreeCustomizer) templatePath(params interceptor.GitPipelineParams, a Artifact, worktree *git.Worktree) (string, error) {
+	fullFilePath, err := dt.renderTemplatePath(params, pathTemplateData(params, a))
+	if err != nil {
+		return fullFilePath, err
+	}
+	if !a.IsDeletion() {
+		if err := worktree.Filesystem.MkdirAll(filepath.Dir(fullFilePath), 0755); err != nil {
+			return fullFilePath, err
+		}
+	}
+	return fullFilePath, nil
+}
+
+// previousPath is the path of the file the intercepted object was placed in
+// before an update: with the metadata it had, and where the routing rule it
+// matched placed it. It is empty for the other operations and the other
+// artifacts.
+func (dt DefaultWorktreeCustomizer) previousPath(params interceptor.GitPipelineParams, a Artifact) (string, error) {
+	if params.InterceptedOldPathMetadata == nil && params.Syncer.PreviousPlacement == nil {
+		return "", nil
+	}
+	if a.IsDeletion() || a.GVR != params.InterceptedGVR || (a.Name != "" && a.Name != params.InterceptedName) {
+		return "", nil
+	}
+
+	previous := params
+	if params.InterceptedOldPathMetadata != nil {
+		previous.InterceptedPathMetadata = *params.InterceptedOldPathMetadata
+	}
+	if placement := params.Syncer.PreviousPlacement; placement != nil {
+		previous.Syncer.Spec.RootPath = placement.RootPath
+		previous.Syncer.Spec.PathTemplate = placement.PathTemplate
+	}
+
+	if previous.Syncer.Spec.PathTemplate != "" {
+		return dt.renderTemplatePath(previous, pathTemplateData(previous, a))
+	}
+	namespacePath := previous.Syncer.InterceptedNamespace
+	if namespacePath == "" {
+		namespacePath = interceptor.ClusterScopedPathSegment
+	}
+	return dt.validatePath(filepath.Join(previous.Syncer.Spec.RootPath, namespacePath,
+		a.GVR.Group, a.GVR.Version, a.GVR.Resource, previous.InterceptedName+".yaml"))
+}
+
+func (dt DefaultWorktreeCustomizer) renderTemplatePath(params interceptor.GitPipelineParams, data interceptor.PathTemplateData) (string, error) {
+	rendered, err := interceptor.RenderPath(params.Syncer.Spec.PathTemplate, data)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasSuffix(rendered, ".yaml") && !strings.HasSuffix(rendered, ".yml") {
+		rendered = filepath.Join(rendered, data.Name+".yaml")
+	}
+	if params.Syncer.Spec.RootPath != "" {
+		rendered = filepath.Join(params.Syncer.Spec.RootPath, rendered)
+	}
+	return dt.validatePath(rendered)
+}
+
+// pathTemplateData is what the pathTemplate is executed with for the
+// artifact. The metadata of the intercepted object is the one it had in the
+// admission request, so that its path does not depend on the excludedFields.
+// The one of the other artifacts is read from their content.
+func pathTemplateData(params interceptor.GitPipelineParams, a Artifact) interceptor.PathTemplateData {
+	name, namespace := params.InterceptedName, params.Syncer.InterceptedNamespace
+	if a.Name != "" {
+		name = a.Name
+	}
+	if a.Namespace != "" {
+		namespace = a.Namespace
+	}
+
+	metadata := params.InterceptedPathMetadata
+	if a.GVR != params.InterceptedGVR && !a.IsDeletion() {
+		object := metav1.PartialObjectMetadata{}
+		if err := yaml.Unmarshal(a.Content, &object); err == nil {
+			metadata = syngit.PathMetadata{Kind: object.Kind, Labels: object.Labels, Annotations: object.Annotations}
+		}
+	}
+
+	return interceptor.PathTemplateData{
+		Group:       a.GVR.Group,
+		Version:     a.GVR.Version,
+		Resource:    a.GVR.Resource,
+		Kind:        metadata.Kind,
+		Namespace:   namespace,
+		Name:        name,
+		Labels:      metadata.Labels,
+		Annotations: metadata.Annotations,
+	}
+}
+
 func (dt DefaultWorktreeCustomizer) validatePath(path string) (string, error) {
 	// Validate and clean the path
 	cleanPath := filepath.Clean(path)
@@ -130,8 +254,12 @@ func (dt DefaultWorktreeCustomizer) writeFile(params interceptor.GitPipelinePara
 	dir, fileName = dt.getFileDirName(params.InterceptedName, fullFilePath, fileName)
 	fullFilePath = filepath.Join(dir, fileName)
 
+	return fullFilePath, dt.writeContent(content, fullFilePath, w, transform)
+}
+
+func (dt DefaultWorktreeCustomizer) writeContent(content []byte, fullFilePath string, w *git.Worktree, transform walker.DocTransform) error {
 	if content == nil { // The file has been deleted
-		return fullFilePath, nil
+		return nil
 	}
 
 	// This layout gives the artifact a file of its own, so whatever is already
@@ -143,12 +271,12 @@ func (dt DefaultWorktreeCustomizer) writeFile(params interceptor.GitPipelinePara
 	}
 	content, err = transform.Apply(fullFilePath, existing, content)
 	if err != nil {
-		return fullFilePath, err
+		return err
 	}
 
 	if err := walker.WriteWorktreeFile(w, fullFilePath, content); err != nil {
-		return fullFilePath, fmt.Errorf("failed to write to file %s: %v", fullFilePath, err)
+		return fmt.Errorf("failed to write to file %s: %v", fullFilePath, err)
 	}
 
-	return fullFilePath, nil
+	return nil
 }
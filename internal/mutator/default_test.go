@@ -1,7 +1,14 @@
 package mutator
 
 import (
+	"slices"
 	"testing"
+
+	"github.com/syngit-org/syngit/internal/walker"
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 func TestDefaultWorktreeCustomizer_validatePath(t *testing.T) {
@@ -127,3 +134,165 @@ func TestDefaultWorktreeCustomizer_getFileDirName(t *testing.T) {
 		})
 	}
 }
+
+func TestPathTemplateData(t *testing.T) {
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	helmReleases := schema.GroupVersionResource{Group: "helm.toolkit.fluxcd.io", Version: "v2", Resource: "helmreleases"}
+	params := interceptor.GitPipelineParams{
+		Syncer:          interceptor.SyncerContext{InterceptedNamespace: "default"},
+		InterceptedGVR:  deployments,
+		InterceptedName: "web",
+		InterceptedPathMetadata: syngit.PathMetadata{
+			Kind:   "Deployment",
+			Labels: map[string]string{"app": "web"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		artifact Artifact
+		want     string
+	}{
+		{
+			name: "intercepted object keeps the metadata of the admission request",
+			artifact: Artifact{
+				GVR:     deployments,
+				Content: []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n"),
+			},
+			want: "Deployment default/web app=web",
+		},
+		{
+			name:     "deleted intercepted object",
+			artifact: Artifact{GVR: deployments},
+			want:     "Deployment default/web app=web",
+		},
+		{
+			name: "other artifact reads its own metadata",
+			artifact: Artifact{
+				GVR:       helmReleases,
+				Name:      "release",
+				Namespace: "flux-system",
+				Content: []byte("apiVersion: helm.toolkit.fluxcd.io/v2\nkind: HelmRelease\n" +
+					"metadata:\n  name: release\n  labels:\n    app: release\n"),
+			},
+			want: "HelmRelease flux-system/release app=release",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data := pathTemplateData(params, tc.artifact)
+			got := data.Kind + " " + data.Namespace + "/" + data.Name + " app=" + data.Labels["app"]
+			if got != tc.want {
+				t.Errorf("pathTemplateData()=%q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDefaultWorktreeCustomizerPlace_LabelChangeMovesFile(t *testing.T) {
+	deployments := schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}
+	newParams := func(operation admissionv1.Operation, oldTeam string) interceptor.GitPipelineParams {
+		params := interceptor.GitPipelineParams{
+			Syncer: interceptor.SyncerContext{
+				Spec:                 syngit.RemoteSyncerSpec{PathTemplate: "teams/{{ .Labels.team }}/{{ .Name }}.yaml"},
+				InterceptedNamespace: "default",
+			},
+			InterceptedGVR:  deployments,
+			InterceptedName: "web",
+			InterceptedPathMetadata: syngit.PathMetadata{
+				Kind:   "Deployment",
+				Labels: map[string]string{"team": "b"},
+			},
+			Operation: operation,
+		}
+		if oldTeam != "" {
+			params.InterceptedOldPathMetadata = &syngit.PathMetadata{
+				Kind:   "Deployment",
+				Labels: map[string]string{"team": oldTeam},
+			}
+		}
+		return params
+	}
+	content := []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n  labels:\n    team: b\n")
+
+	tests := []struct {
+		name       string
+		params     interceptor.GitPipelineParams
+		wantDelete []string
+	}{
+		{
+			name:       "label change moves the file",
+			params:     newParams(admissionv1.Update, "a"),
+			wantDelete: []string{"teams/a/web.yaml"},
+		},
+		{
+			name:       "unchanged label keeps the file",
+			params:     newParams(admissionv1.Update, "b"),
+			wantDelete: []string{},
+		},
+		{
+			name:       "create has no previous file",
+			params:     newParams(admissionv1.Create, ""),
+			wantDelete: []string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			wt := newMemWorktree(t)
+			if err := walker.WriteWorktreeFile(wt, "teams/a/web.yaml", []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n")); err != nil {
+				t.Fatalf("seed: %v", err)
+			}
+
+			artifacts := ArtifactSet{}
+			artifacts.Add(Artifact{GVR: deployments, Content: content})
+			claimed, err := (DefaultWorktreeCustomizer{}).place(tc.params, artifacts, wt, nil)
+			if err != nil {
+				t.Fatalf("place: %v", err)
+			}
+
+			if !slices.Equal(claimed.Add, []string{"teams/b/web.yaml"}) {
+				t.Errorf("Add=%v, want [teams/b/web.yaml]", claimed.Add)
+			}
+			if !slices.Equal(claimed.Delete, tc.wantDelete) {
+				t.Errorf("Delete=%v, want %v", claimed.Delete, tc.wantDelete)
+			}
+			if got := readWorktree(t, wt, "teams/b/web.yaml"); got != string(content) {
+				t.Errorf("teams/b/web.yaml=%q, want %q", got, content)
+			}
+		})
+	}
+}
+
+func TestDefaultWorktreeCustomizerPlace_RoutingRuleChangeMovesFile(t *testing.T) {
+	configMaps := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	params := interceptor.GitPipelineParams{
+		Syncer: interceptor.SyncerContext{
+			Spec:                 syngit.RemoteSyncerSpec{RootPath: "teams/b"},
+			InterceptedNamespace: "default",
+			PreviousPlacement:    &syngit.PathPlacement{RootPath: "teams/a"},
+		},
+		InterceptedGVR:  configMaps,
+		InterceptedName: "config",
+		Operation:       admissionv1.Update,
+	}
+	wt := newMemWorktree(t)
+	if err := walker.WriteWorktreeFile(wt, "teams/a/default/v1/configmaps/config.yaml", []byte("kind: ConfigMap\n")); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	artifacts := ArtifactSet{}
+	artifacts.Add(Artifact{GVR: configMaps, Content: []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n")})
+	claimed, err := (DefaultWorktreeCustomizer{}).place(params, artifacts, wt, nil)
+	if err != nil {
+		t.Fatalf("place: %v", err)
+	}
+
+	if !slices.Equal(claimed.Add, []string{"teams/b/default/v1/configmaps/config.yaml"}) {
+		t.Errorf("Add=%v, want the path of the new routing rule", claimed.Add)
+	}
+	if !slices.Equal(claimed.Delete, []string{"teams/a/default/v1/configmaps/config.yaml"}) {
+		t.Errorf("Delete=%v, want the path of the previous routing rule", claimed.Delete)
+	}
+}
@@ -119,7 +119,7 @@ func (p FluxHelmReleaseProvider) Render(rc RenderContext, out *ArtifactSet) erro
 	if err != nil {
 		return fmt.Errorf("failed to marshal the generated HelmRelease: %w", err)
 	}
-	cleaned, err := render.ObjectToYAML(rc.Ctx, raw, os.Getenv("MANAGER_NAMESPACE"),
+	cleaned, err := render.ObjectToYAML(rc.Ctx, raw, "", os.Getenv("MANAGER_NAMESPACE"),
 		rc.Params.Syncer.Spec, rc.Params.Syncer.RefOwnerNamespace)
 	if err != nil {
 		return fmt.Errorf("failed to apply the excluded fields to the HelmRelease: %w", err)
@@ -7,6 +7,7 @@ import (
 	sopsprovider "github.com/syngit-org/syngit-provider-sops/pkg"
 	"github.com/syngit-org/syngit/internal/walker"
 	features "github.com/syngit-org/syngit/pkg/feature"
+	"github.com/syngit-org/syngit/pkg/interceptor"
 	"github.com/syngit-org/syngit/pkg/refs"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -18,6 +19,12 @@ import (
 // much of each document is covered.
 const sopsConfigFile = ".sops.yaml"
 
+// IsSOPSEnabled reports whether the manifests pushed for params are encrypted
+// with SOPS.
+func IsSOPSEnabled(params interceptor.GitPipelineParams) bool {
+	return features.LoadedFeatureGates.Enabled(features.SopsEncryption) && params.Syncer.Spec.SOPS.Enabled
+}
+
 // sopsTransform builds the content transform that encrypts every document
 // pushed by this syncer, or returns nil when the SOPS provider is off.
 //
@@ -25,8 +32,7 @@ const sopsConfigFile = ".sops.yaml"
 // here, once: the .sops.yaml of the repository and the age identities. The
 // returned closure is then called per (document, path) by the placement phase.
 func sopsTransform(rc RenderContext) (walker.DocTransform, error) {
-	sops := rc.Params.Syncer.Spec.SOPS
-	if !features.LoadedFeatureGates.Enabled(features.SopsEncryption) || !sops.Enabled {
+	if !IsSOPSEnabled(rc.Params) {
 		return nil, nil
 	}
 
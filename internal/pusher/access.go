@@ -0,0 +1,126 @@
+package pusher
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// accessKey identifies a credential reading a repository. The credential is
+// a fingerprint of the secret material, so a rotated Secret is never mistaken
+// for the one it replaces.
+type accessKey struct {
+	repository string
+	credential string
+}
+
+// accessGrant records that a credential could read a repository.
+type accessGrant struct {
+	remoteUser types.NamespacedName
+	expires    time.Time
+}
+
+// accessGrants are the recent successful access checks. A cached repository
+// holds what any credential fetched, so a credential must prove it can read
+// the repository before it is handed one; the proof is kept for
+// RepoCacheOptions.AccessTTL to spare a round trip to the git server on every
+// request. Failed checks are not kept: a credential that is granted access is
+// let in right away.
+var accessGrants = struct {
+	mu     sync.Mutex
+	grants map[accessKey]accessGrant
+}{grants: map[accessKey]accessGrant{}}
+
+// credentialFingerprint returns a digest of the secret material of userInfo.
+func credentialFingerprint(userInfo interceptor.GitUserInfo) string {
+	digest := sha256.New()
+	for _, part := range []string{userInfo.User, userInfo.Token, userInfo.SSHPrivateKey} {
+		digest.Write([]byte(part))
+		digest.Write([]byte{0})
+	}
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// checkAccess verifies that the credential of params can read its repository,
+// with an authenticated ls-remote unless the credential has passed the check
+// within the TTL.
+func checkAccess(ctx context.Context, params GetRepositoryParams) error {
+	key := accessKey{repository: params.Repository, credential: credentialFingerprint(params.GitUserInfo)}
+	now := time.Now()
+
+	accessGrants.mu.Lock()
+	grant, found := accessGrants.grants[key]
+	accessGrants.mu.Unlock()
+	if found && now.Before(grant.expires) {
+		return nil
+	}
+
+	auth, err := params.auth()
+	if err != nil {
+		return err
+	}
+	remote := git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
+		Name: originRemote,
+		URLs: []string{params.Repository},
+	})
+	err = withRetries(ctx, networkRetries, func() error {
+		_, err := remote.ListContext(ctx, &git.ListOptions{
+			Auth:            auth,
+			InsecureSkipTLS: params.Syncer.Spec.InsecureSkipTlsVerify,
+			ProxyOptions:    proxyOptions(params.Transport),
+			ClientCert:      params.Transport.ClientCert,
+			ClientKey:       params.Transport.ClientKey,
+			CABundle:        params.CABundle,
+		})
+		// An empty repository has nothing to list, but it has let the credential in.
+		if errors.Is(err, transport.ErrEmptyRemoteRepository) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("the git user %s cannot read the repository %s: %w",
+			params.GitUserInfo.User, params.Repository, err)
+	}
+
+	ttl := repoCacheOptions.AccessTTL
+	if ttl <= 0 {
+		return nil
+	}
+	accessGrants.mu.Lock()
+	defer accessGrants.mu.Unlock()
+	for k, g := range accessGrants.grants {
+		if !now.Before(g.expires) {
+			delete(accessGrants.grants, k)
+		}
+	}
+	accessGrants.grants[key] = accessGrant{
+		remoteUser: params.GitUserInfo.RemoteUserRef,
+		expires:    now.Add(ttl),
+	}
+	return nil
+}
+
+// ForgetRemoteUser drops the access checks passed with the credentials of a
+// RemoteUser, so that they are checked again on the next request. It is
+// called whenever the RemoteUser or its Secret changes.
+func ForgetRemoteUser(remoteUser types.NamespacedName) {
+	accessGrants.mu.Lock()
+	defer accessGrants.mu.Unlock()
+	for key, grant := range accessGrants.grants {
+		if grant.remoteUser == remoteUser {
+			delete(accessGrants.grants, key)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package pusher
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syngit-org/syngit/pkg/envtest"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// privateRepository serves a repository that alice can read and eve cannot.
+func privateRepository(t *testing.T) (string, interceptor.GitUserInfo, interceptor.GitUserInfo) {
+	t.Helper()
+
+	gs, err := envtest.NewGitServer()
+	if err != nil {
+		t.Fatalf("NewGitServer: %v", err)
+	}
+	t.Cleanup(gs.Stop)
+
+	repo := envtest.RepoRef{Owner: "syngituser", Name: "private"}
+	alice := envtest.GitUser{Username: "alice", Password: "alice-token"}
+	eve := envtest.GitUser{Username: "eve", Password: "eve-token"}
+	gs.AddUser(alice)
+	gs.AddUser(eve)
+	gs.SetPermission(alice.Username, repo, envtest.ReadWrite)
+	if err := gs.CreateRepo(repo, "main"); err != nil {
+		t.Fatalf("CreateRepo: %v", err)
+	}
+
+	return gs.RepoURL(repo),
+		interceptor.GitUserInfo{
+			User:          alice.Username,
+			Token:         alice.Password,
+			RemoteUserRef: types.NamespacedName{Namespace: "team-a", Name: "alice"},
+		},
+		interceptor.GitUserInfo{
+			User:          eve.Username,
+			Token:         eve.Password,
+			RemoteUserRef: types.NamespacedName{Namespace: "team-b", Name: "eve"},
+		}
+}
+
+func TestGetRepositoryChecksAccess(t *testing.T) {
+	url, alice, eve := privateRepository(t)
+	if err := ConfigureRepoCache(RepoCacheOptions{MaxRepos: 2, AccessTTL: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	defer InitRepoCache(0)
+
+	params := GetRepositoryParams{GitUserInfo: alice, Repository: url, Branch: "main"}
+	_, release, err := getRepository(context.Background(), params)
+	if err != nil {
+		t.Fatalf("alice can read the repository: %v", err)
+	}
+	release()
+
+	// The repository cloned by alice is cached, but eve must not get it.
+	params.GitUserInfo = eve
+	if _, _, err := getRepository(context.Background(), params); err == nil || !strings.Contains(err.Error(), "cannot read the repository") {
+		t.Fatalf("eve cannot read the repository and must be denied before the cached one is used, got %v", err)
+	}
+	if _, found := repoCache.Get(params.cacheKey()); !found {
+		t.Error("the denied request must leave the cached repository in place")
+	}
+
+	// A rotated token is another credential: it is checked on its own.
+	rotated := alice
+	rotated.Token = "rotated"
+	params.GitUserInfo = rotated
+	if _, _, err := getRepository(context.Background(), params); err == nil {
+		t.Fatal("a token the git server does not know must not be handed the cached repository")
+	}
+}
+
+func TestForgetRemoteUser(t *testing.T) {
+	url, alice, _ := privateRepository(t)
+	if err := ConfigureRepoCache(RepoCacheOptions{MaxRepos: 2, AccessTTL: time.Minute}); err != nil {
+		t.Fatal(err)
+	}
+	defer InitRepoCache(0)
+
+	params := GetRepositoryParams{GitUserInfo: alice, Repository: url, Branch: "main"}
+	if err := checkAccess(context.Background(), params); err != nil {
+		t.Fatalf("checkAccess: %v", err)
+	}
+	key := accessKey{repository: url, credential: credentialFingerprint(alice)}
+	granted := func() bool {
+		accessGrants.mu.Lock()
+		defer accessGrants.mu.Unlock()
+		_, found := accessGrants.grants[key]
+		return found
+	}
+	if !granted() {
+		t.Fatal("a successful check must be kept for the TTL")
+	}
+
+	ForgetRemoteUser(types.NamespacedName{Namespace: "team-b", Name: "eve"})
+	if !granted() {
+		t.Error("forgetting another RemoteUser must keep the check")
+	}
+	ForgetRemoteUser(alice.RemoteUserRef)
+	if granted() {
+		t.Error("forgetting the RemoteUser must drop its checks")
+	}
+}
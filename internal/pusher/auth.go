@@ -0,0 +1,110 @@
+package pusher
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/skeema/knownhosts"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+)
+
+// defaultSSHUser is the login used when an SSH repository URL does not name
+// one. Every major forge expects "git".
+const defaultSSHUser = "git"
+
+// authMethod returns the credentials go-git must present to reach repository.
+// The transport is picked by go-git from the URL, so the credentials of the
+// RemoteUser have to match it: a Personal Access Token over HTTP(S), a private
+// key over SSH.
+func authMethod(userInfo interceptor.GitUserInfo, repository string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(repository)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the repository URL %s: %w", repository, err)
+	}
+
+	if endpoint.Protocol != "ssh" {
+		if userInfo.SSHPrivateKey != "" && userInfo.Token == "" {
+			return nil, fmt.Errorf(
+				"the repository %s is not reached over SSH but the RemoteUser only holds an SSH private key",
+				repository,
+			)
+		}
+		return &http.BasicAuth{
+			Username: userInfo.User,
+			Password: userInfo.Token,
+		}, nil
+	}
+
+	if userInfo.SSHPrivateKey == "" {
+		return nil, fmt.Errorf(
+			"the repository %s is reached over SSH but the RemoteUser holds no SSH private key; "+
+				"the secret must be of type kubernetes.io/ssh-auth",
+			repository,
+		)
+	}
+
+	sshUser := endpoint.User
+	if sshUser == "" {
+		sshUser = defaultSSHUser
+	}
+	auth, err := gitssh.NewPublicKeys(sshUser, []byte(userInfo.SSHPrivateKey), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the SSH private key: %w", err)
+	}
+
+	hostKeyDB, err := knownHostsDB(userInfo.KnownHosts)
+	if err != nil {
+		return nil, err
+	}
+	auth.HostKeyCallback = hostKeyDB.HostKeyCallback()
+	// go-git only negotiates the host key algorithms itself when it reads the
+	// known_hosts of the local user. Without them, the server may present a key
+	// of another type than the one pinned here and fail the verification.
+	auth.HostKeyAlgorithms = hostKeyDB.HostKeyAlgorithms(
+		net.JoinHostPort(endpoint.Host, strconv.Itoa(endpointPort(endpoint))),
+	)
+
+	return auth, nil
+}
+
+// endpointPort returns the port go-git dials for an SSH endpoint.
+func endpointPort(endpoint *transport.Endpoint) int {
+	if endpoint.Port == 0 {
+		return 22
+	}
+	return endpoint.Port
+}
+
+// knownHostsDB loads the known_hosts entries of a RemoteUser. The knownhosts
+// package only reads files, so the entries go through a temporary one that is
+// removed as soon as they are loaded in memory.
+func knownHostsDB(knownHosts string) (*knownhosts.HostKeyDB, error) {
+	if knownHosts == "" {
+		return nil, fmt.Errorf("no known_hosts entries to verify the SSH host key of the git server against")
+	}
+
+	file, err := os.CreateTemp("", "syngit-known-hosts-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the known_hosts file: %w", err)
+	}
+	defer os.Remove(file.Name()) // nolint:errcheck
+
+	_, err = file.WriteString(knownHosts)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write the known_hosts file: %w", err)
+	}
+
+	db, err := knownhosts.NewDB(file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the known_hosts entries: %w", err)
+	}
+	return db, nil
+}
@@ -0,0 +1,136 @@
+package pusher
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"golang.org/x/crypto/ssh"
+)
+
+// newTestSSHKey returns a fresh OpenSSH private key and the known_hosts line
+// pinning its public half for host.
+func newTestSSHKey(t *testing.T, host string) (string, string) {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(privateKey, "")
+	if err != nil {
+		t.Fatalf("failed to marshal private key: %v", err)
+	}
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("failed to build public key: %v", err)
+	}
+
+	knownHosts := host + " " + strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublicKey)))
+	return string(pem.EncodeToMemory(block)), knownHosts
+}
+
+func TestAuthMethod(t *testing.T) {
+	privateKey, knownHosts := newTestSSHKey(t, "git.example.com")
+
+	basicUser := interceptor.GitUserInfo{User: "alice", Token: "s3cr3t"}
+	sshUser := interceptor.GitUserInfo{User: "alice", SSHPrivateKey: privateKey, KnownHosts: knownHosts}
+
+	tests := []struct {
+		name       string
+		userInfo   interceptor.GitUserInfo
+		repository string
+		wantSSH    string // expected SSH login, empty for basic auth
+		wantErr    string
+	}{
+		{"token over https", basicUser, "https://git.example.com/org/repo.git", "", ""},
+		{"key over ssh URL", sshUser, "ssh://deploy@git.example.com/org/repo.git", "deploy", ""},
+		{"key over scp-like URL", sshUser, "git@git.example.com:org/repo.git", "git", ""},
+		{"key over ssh URL without user", sshUser, "ssh://git.example.com:2222/org/repo.git", defaultSSHUser, ""},
+		{"token over ssh", basicUser, "git@git.example.com:org/repo.git", "", "holds no SSH private key"},
+		{"key over https", sshUser, "https://git.example.com/org/repo.git", "", "only holds an SSH private key"},
+		{
+			"key without known hosts",
+			interceptor.GitUserInfo{SSHPrivateKey: privateKey},
+			"git@git.example.com:org/repo.git", "", "no known_hosts entries",
+		},
+		{
+			"malformed key",
+			interceptor.GitUserInfo{SSHPrivateKey: "not a key", KnownHosts: knownHosts},
+			"git@git.example.com:org/repo.git", "", "failed to parse the SSH private key",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			auth, err := authMethod(tc.userInfo, tc.repository)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if tc.wantSSH == "" {
+				basic, ok := auth.(*http.BasicAuth)
+				if !ok {
+					t.Fatalf("got %T, want *http.BasicAuth", auth)
+				}
+				if basic.Username != tc.userInfo.User || basic.Password != tc.userInfo.Token {
+					t.Errorf("got %s:%s, want %s:%s", basic.Username, basic.Password, tc.userInfo.User, tc.userInfo.Token)
+				}
+				return
+			}
+
+			keys, ok := auth.(*gitssh.PublicKeys)
+			if !ok {
+				t.Fatalf("got %T, want *ssh.PublicKeys", auth)
+			}
+			if keys.User != tc.wantSSH {
+				t.Errorf("got SSH user %q, want %q", keys.User, tc.wantSSH)
+			}
+			if keys.HostKeyCallback == nil {
+				t.Error("the host key callback must be pinned to the known_hosts entries")
+			}
+		})
+	}
+}
+
+func TestKnownHostsDB(t *testing.T) {
+	_, knownHosts := newTestSSHKey(t, "git.example.com")
+	_, otherKnownHosts := newTestSSHKey(t, "git.example.com")
+
+	db, err := knownHostsDB(knownHosts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if algos := db.HostKeyAlgorithms("git.example.com:22"); len(algos) == 0 {
+		t.Error("expected the host key algorithm of the pinned key")
+	}
+
+	otherKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.TrimPrefix(otherKnownHosts, "git.example.com ")))
+	if err != nil {
+		t.Fatalf("failed to parse key: %v", err)
+	}
+	callback := db.HostKeyCallback()
+	if err := callback("git.example.com:22", fakeAddr("git.example.com:22"), otherKey); err == nil {
+		t.Error("a host key that is not pinned must be rejected")
+	}
+
+	if _, err := knownHostsDB(""); err == nil {
+		t.Error("expected an error for empty known_hosts")
+	}
+}
+
+type fakeAddr string
+
+func (a fakeAddr) Network() string { return "tcp" }
+func (a fakeAddr) String() string  { return string(a) }
@@ -0,0 +1,139 @@
+package pusher
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// batchKey groups the changes that are committed together: the changes made
+// by one user through one syncer and pushed with one RemoteUser to one branch.
+type batchKey struct {
+	syncer      types.NamespacedName
+	clusterWide bool
+	username    string
+	gitUser     interceptor.GitUserInfo
+	repository  string
+	branch      string
+}
+
+func newBatchKey(params interceptor.GitPipelineParams) batchKey {
+	return batchKey{
+		syncer:      params.Syncer.Ref,
+		clusterWide: params.Syncer.ClusterWide,
+		username:    params.Author.Username,
+		gitUser:     params.GitUserInfo,
+		repository:  params.RemoteTarget.Spec.TargetRepository,
+		branch:      params.RemoteTarget.Spec.TargetBranch,
+	}
+}
+
+// batch collects the changes that come in during a batching window. It is
+// pushed by the request that opened it; the other requests wait for it.
+type batch struct {
+	changes []interceptor.GitPipelineParams
+	// withdrawn marks the changes whose request gave up on the batch before
+	// it was built: they are left out of the commit.
+	withdrawn []bool
+	responses []interceptor.GitPushResponse
+	err       error
+	done      chan struct{}
+}
+
+var (
+	batchesMu sync.Mutex
+	// The batches whose window is still open.
+	batches = map[batchKey]*batch{}
+)
+
+// batchWindow returns how long the changes of params wait for others to be
+// committed with, or zero when they are committed on their own. A change
+// request is opened per object, so its changes are never batched.
+func batchWindow(params interceptor.GitPipelineParams) time.Duration {
+	if params.Syncer.Spec.BatchWindowMilliseconds <= 0 || isChangeRequestDelivery(params) {
+		return 0
+	}
+	return time.Duration(params.Syncer.Spec.BatchWindowMilliseconds) * time.Millisecond
+}
+
+// runBatchedGitPipeline adds the change of params to the open batch of its
+// user and target, or opens one. The request that opens a batch waits for the
+// window to end, then commits every change of the batch in one commit, under
+// a single lease of the repository. Every request of the batch gets the same
+// commit hash, with the paths its own change claimed. A request that gives up
+// while the window is open withdraws its change; once the batch is built, it
+// waits for its result like the others.
+func runBatchedGitPipeline(
+	ctx context.Context,
+	cluster client.Reader,
+	params interceptor.GitPipelineParams,
+	window time.Duration,
+) (interceptor.GitPushResponse, error) {
+	key := newBatchKey(params)
+
+	batchesMu.Lock()
+	b, found := batches[key]
+	if !found {
+		b = &batch{done: make(chan struct{})}
+		batches[key] = b
+	}
+	index := len(b.changes)
+	b.changes = append(b.changes, params)
+	b.withdrawn = append(b.withdrawn, false)
+	batchesMu.Unlock()
+
+	if found {
+		select {
+		case <-b.done:
+			return b.responses[index], b.err
+		case <-ctx.Done():
+		}
+
+		batchesMu.Lock()
+		open := batches[key] == b
+		if open {
+			b.withdrawn[index] = true
+		}
+		batchesMu.Unlock()
+		if open {
+			return ResponseBuilder(make([]string, 0), "", params.RemoteTarget.Spec.TargetRepository), ctx.Err()
+		}
+		// The change is already being committed: its result is the one of
+		// the batch.
+		<-b.done
+		return b.responses[index], b.err
+	}
+
+	timer := time.NewTimer(window)
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		timer.Stop()
+	}
+
+	// Once the batch is out of the map, no change can join it anymore.
+	batchesMu.Lock()
+	delete(batches, key)
+	var changes []interceptor.GitPipelineParams
+	var indexes []int
+	for i, change := range b.changes {
+		if !b.withdrawn[i] {
+			changes = append(changes, change)
+			indexes = append(indexes, i)
+		}
+	}
+	batchesMu.Unlock()
+
+	responses, _, err := runGitPipeline(ctx, cluster, changes)
+	b.responses = make([]interceptor.GitPushResponse, len(b.changes))
+	for i, response := range responses {
+		b.responses[indexes[i]] = response
+	}
+	b.err = err
+	close(b.done)
+	return b.responses[index], b.err
+}
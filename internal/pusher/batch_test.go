@@ -0,0 +1,166 @@
+package pusher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// batchedChange returns the creation of the ConfigMap name, batched for 500ms
+// on the master branch of remote.
+func batchedChange(remote, name string) interceptor.GitPipelineParams {
+	params := interceptor.GitPipelineParams{
+		InterceptedGVR:  schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+		InterceptedName: name,
+		InterceptedYAML: fmt.Sprintf("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: %s\n  namespace: default\n", name),
+		GitUserInfo:     interceptor.GitUserInfo{User: "test", Email: "test@syngit.io"},
+		Operation:       "CREATE",
+	}
+	params.Syncer.InterceptedNamespace = "default"
+	params.Syncer.Spec.BatchWindowMilliseconds = 500
+	params.RemoteTarget.Spec = syngit.RemoteTargetSpec{
+		UpstreamRepository: remote,
+		UpstreamBranch:     "master",
+		TargetRepository:   remote,
+		TargetBranch:       "master",
+	}
+	return params
+}
+
+func TestRunBatchedGitPipeline(t *testing.T) {
+	remote := seededRemote(t)
+	change := func(name string) interceptor.GitPipelineParams {
+		return batchedChange(remote, name)
+	}
+
+	names := []string{"a", "b", "c"}
+	responses := make([]interceptor.GitPushResponse, len(names))
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i], errs[i] = RunGitPipeline(context.Background(), nil, change(name))
+		}()
+	}
+	wg.Wait()
+
+	for i := range names {
+		if errs[i] != nil {
+			t.Fatalf("RunGitPipeline(%s): %v", names[i], errs[i])
+		}
+		if responses[i].CommitHash != responses[0].CommitHash {
+			t.Errorf("the changes were not committed together: %s != %s", responses[i].CommitHash, responses[0].CommitHash)
+		}
+		if len(responses[i].Paths) != 1 || !strings.HasSuffix(responses[i].Paths[0], names[i]+".yaml") {
+			t.Errorf("the paths of %s are %v", names[i], responses[i].Paths)
+		}
+	}
+
+	repository, err := git.PlainOpen(remote)
+	if err != nil {
+		t.Fatalf("failed to open the remote: %v", err)
+	}
+	head, _ := repository.Head()
+	commit, err := repository.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatalf("failed to read the commit: %v", err)
+	}
+	if head.Hash().String() != responses[0].CommitHash {
+		t.Errorf("the remote is at %s, want %s", head.Hash(), responses[0].CommitHash)
+	}
+	if !strings.HasPrefix(commit.Message, "3+ 3 objects\n\n") {
+		t.Errorf("unexpected commit message %q", commit.Message)
+	}
+	if parent, _ := commit.Parent(0); parent == nil || parent.Message != "add README.md" {
+		t.Errorf("the batch must be a single commit on top of the seed")
+	}
+}
+
+func TestRunBatchedGitPipeline_withdrawnChange(t *testing.T) {
+	remote := seededRemote(t)
+
+	opened := make(chan struct{})
+	var response interceptor.GitPushResponse
+	var err error
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		close(opened)
+		response, err = RunGitPipeline(context.Background(), nil, batchedChange(remote, "kept"))
+	}()
+	<-opened
+	time.Sleep(50 * time.Millisecond)
+
+	// The request gives up while the window is open: its change is left out.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, withdrawnErr := RunGitPipeline(ctx, nil, batchedChange(remote, "withdrawn")); !errors.Is(withdrawnErr, context.DeadlineExceeded) {
+		t.Errorf("RunGitPipeline(withdrawn) error = %v, want %v", withdrawnErr, context.DeadlineExceeded)
+	}
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("RunGitPipeline(kept): %v", err)
+	}
+
+	repository, err := git.PlainOpen(remote)
+	if err != nil {
+		t.Fatalf("failed to open the remote: %v", err)
+	}
+	commit, err := repository.CommitObject(plumbing.NewHash(response.CommitHash))
+	if err != nil {
+		t.Fatalf("failed to read the commit: %v", err)
+	}
+	tree, _ := commit.Tree()
+	var files []string
+	_ = tree.Files().ForEach(func(f *object.File) error {
+		files = append(files, f.Name)
+		return nil
+	})
+	if !slices.ContainsFunc(files, func(name string) bool { return strings.HasSuffix(name, "kept.yaml") }) {
+		t.Errorf("the kept change is not committed: %v", files)
+	}
+	if slices.ContainsFunc(files, func(name string) bool { return strings.HasSuffix(name, "withdrawn.yaml") }) {
+		t.Errorf("the withdrawn change is committed: %v", files)
+	}
+}
+
+// seededRemote returns the path of a bare repository whose master branch holds
+// a README.md commit.
+func seededRemote(t *testing.T) string {
+	t.Helper()
+
+	remote := t.TempDir()
+	if _, err := git.PlainInit(remote, true); err != nil {
+		t.Fatalf("failed to init the remote: %v", err)
+	}
+	seed, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatalf("failed to init the seed: %v", err)
+	}
+	if _, err := seed.CreateRemote(&config.RemoteConfig{Name: originRemote, URLs: []string{remote}}); err != nil {
+		t.Fatalf("failed to create the remote: %v", err)
+	}
+	commitFile(t, seed, "README.md", "seed")
+	if err := seed.Push(&git.PushOptions{}); err != nil {
+		t.Fatalf("failed to push the seed: %v", err)
+	}
+	return remote
+}
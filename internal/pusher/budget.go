@@ -0,0 +1,44 @@
+package pusher
+
+import (
+	"context"
+	"time"
+)
+
+// The time left to an admission request is split between the stages of the
+// pipeline, so that a git server that hangs in one stage fails the request
+// while there is still time to answer the API server with the reason, rather
+// than having the API server give up on the webhook.
+const (
+	// maxAnswerMargin caps the time kept from the deadline of the request to
+	// answer the API server; a tenth of the time left is kept below it.
+	maxAnswerMargin = time.Second
+	// repositoryShare is the share of the time left that getting the
+	// repositories (waiting for the lease, clone or fetch) may take.
+	repositoryShare = 0.5
+	// worktreeShare is the share of the time left that preparing the worktree
+	// (fetching and merging the upstream branch) may take. The push takes
+	// what is left after the rendering.
+	worktreeShare = 0.5
+)
+
+// pipelineContext returns the context of a pipeline run: ctx, with a deadline
+// early enough to answer the API server once the pipeline gives up.
+func pipelineContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	margin := min(time.Until(deadline)/10, maxAnswerMargin)
+	return context.WithDeadline(ctx, deadline.Add(-margin))
+}
+
+// stageContext returns the context of a stage that may take share of the time
+// left before the deadline of ctx, if any.
+func stageContext(ctx context.Context, share float64) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(float64(time.Until(deadline))*share))
+}
@@ -0,0 +1,61 @@
+package pusher
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPipelineContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipelineCtx, cancelPipeline := pipelineContext(ctx)
+	defer cancelPipeline()
+	deadline, _ := ctx.Deadline()
+	pipelineDeadline, ok := pipelineCtx.Deadline()
+	if !ok {
+		t.Fatal("the pipeline must have a deadline when the request has one")
+	}
+	if margin := deadline.Sub(pipelineDeadline); margin < 900*time.Millisecond || margin > maxAnswerMargin {
+		t.Errorf("the pipeline must leave up to %v to answer, got %v", maxAnswerMargin, margin)
+	}
+
+	stageCtx, cancelStage := stageContext(pipelineCtx, repositoryShare)
+	defer cancelStage()
+	stageDeadline, _ := stageCtx.Deadline()
+	if left := time.Until(stageDeadline); left > 5*time.Second || left < 4*time.Second {
+		t.Errorf("the stage must get half of the time left, got %v", left)
+	}
+
+	unbounded, cancelUnbounded := stageContext(context.Background(), repositoryShare)
+	defer cancelUnbounded()
+	if _, ok := unbounded.Deadline(); ok {
+		t.Error("a stage of a request without deadline must not get one")
+	}
+}
+
+func TestCloneRepository_hungServer(t *testing.T) {
+	hang := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-hang:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(hang)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, _, err := cloneRepository(ctx, GetRepositoryParams{Repository: server.URL + "/repo.git", Branch: "main"})
+	if err == nil {
+		t.Fatal("the clone from a server that never answers must fail")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("the clone must stop at the deadline, took %v", elapsed)
+	}
+}
@@ -1,19 +1,46 @@
 package pusher
 
 import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
+	"time"
 
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
 	git "github.com/go-git/go-git/v5"
+	gitcache "github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/memory"
 	"github.com/syngit-org/syngit/internal/cache"
 )
 
+// repoDirPattern names the directories of the repositories stored on disk.
+// Only the entries matching it are removed when the cache is configured.
+const repoDirPattern = "repo-*"
+
 // repoHandle holds a single cached go-git repository. go-git repositories are
-// not safe for concurrent use, so each handle carries its own mutex and is
+// not safe for concurrent use, so each handle carries its own lock and is
 // leased to a single caller at a time via acquire/release; the lease is held
 // for the whole pipeline (clone/refresh -> worktree -> commit -> push).
 type repoHandle struct {
-	repo *git.Repository // nil until the first clone populates it
-	mu   sync.Mutex      // serializes pipeline use; held for the whole lease
+	repo    *git.Repository // nil until the first clone populates it
+	storage repoStorage     // where repo is stored
+	// lock serializes pipeline use and is held for the whole lease. It is a
+	// channel rather than a mutex so that a caller can give up waiting for it.
+	lock chan struct{}
+
+	// stateMu guards leased and evicted. It is never held while waiting on lock,
+	// so that the cache can evict a handle that is leased.
+	stateMu sync.Mutex
+	leased  bool
+	// evicted is set once the handle has left the cache. Its files are removed
+	// as soon as it is not leased anymore, and it is never leased again.
+	evicted bool
 }
 
 // repoCache is the package-level repository cache, keyed by "<url>#<branch>".
@@ -22,14 +49,140 @@ type repoHandle struct {
 // cache.LFU methods are nil-safe.
 var repoCache *cache.LFU[string, *repoHandle]
 
+// repoCacheOptions is the configuration of repoCache. Its Dir is also used for
+// the repositories that are not cached.
+var repoCacheOptions RepoCacheOptions
+
+// RepoCacheOptions configures the repository cache.
+type RepoCacheOptions struct {
+	// MaxRepos bounds the number of cached repositories. Zero or less disables
+	// caching entirely.
+	MaxRepos int
+	// MaxBytes bounds the total size of the cached repositories, measured on
+	// disk when Dir is set and as the size of the objects otherwise. Zero or
+	// less only bounds the number of repositories.
+	MaxBytes int64
+	// Dir is the directory where the repositories are cloned, cached or not.
+	// When empty, they are kept in memory.
+	Dir string
+	// AccessTTL is how long a credential that could read a repository is
+	// trusted to still read it. A cached repository is only handed to a
+	// credential that passed this check. Zero or less checks on every request.
+	AccessTTL time.Duration
+}
+
 // InitRepoCache (re)configures the package-level repository cache. A maxRepos of
 // zero or less disables caching entirely.
 func InitRepoCache(maxRepos int) {
-	repoCache = cache.NewLFU[string, *repoHandle](maxRepos)
+	// Without a directory there is nothing that can fail.
+	_ = ConfigureRepoCache(RepoCacheOptions{MaxRepos: maxRepos})
+}
+
+// ConfigureRepoCache (re)configures the package-level repository cache. The
+// repositories left in Dir by a previous run are removed, so Dir must not be
+// shared with another instance.
+func ConfigureRepoCache(options RepoCacheOptions) error {
+	if options.Dir != "" {
+		if err := os.MkdirAll(options.Dir, 0o700); err != nil {
+			return fmt.Errorf("failed to create the repository cache directory: %w", err)
+		}
+		repos, err := filepath.Glob(filepath.Join(options.Dir, repoDirPattern))
+		if err != nil {
+			return err
+		}
+		objects, err := filepath.Glob(filepath.Join(options.Dir, objectsDirPattern))
+		if err != nil {
+			return err
+		}
+		leftovers := append(repos, objects...)
+		for _, leftover := range leftovers {
+			if err := os.RemoveAll(leftover); err != nil {
+				return fmt.Errorf("failed to clean the repository cache directory: %w", err)
+			}
+		}
+	}
+
+	repoCacheOptions = options
+	repoCache = cache.NewWeightedLFU(options.MaxRepos, options.MaxBytes, func(_ string, handle *repoHandle) {
+		handle.evict()
+	})
+	return nil
+}
+
+// repoStorage is where a clone is stored: its own directory, if on disk, and
+// the object store it shares with the other clones of its repository.
+type repoStorage struct {
+	dir     string // "" when in memory
+	objects *objectStore
+}
+
+// newRepositoryStorage returns the storage of a new clone of url at depth: a
+// new directory of the cache directory, or memory, on top of the object store
+// of url.
+func newRepositoryStorage(url string, depth int) (storage.Storer, billy.Filesystem, repoStorage, error) {
+	objects, err := acquireObjectStore(url, depth)
+	if err != nil {
+		return nil, nil, repoStorage{}, err
+	}
+	if repoCacheOptions.Dir == "" {
+		return newCloneStorage(memory.NewStorage(), objects), memfs.New(), repoStorage{objects: objects}, nil
+	}
+
+	dir, err := os.MkdirTemp(repoCacheOptions.Dir, repoDirPattern)
+	if err != nil {
+		objects.release()
+		return nil, nil, repoStorage{}, fmt.Errorf("failed to create the repository directory: %w", err)
+	}
+	files := repoStorage{dir: dir, objects: objects}
+	worktree := osfs.New(dir)
+	dotGit, err := worktree.Chroot(git.GitDirName)
+	if err != nil {
+		files.remove()
+		return nil, nil, repoStorage{}, err
+	}
+	private := filesystem.NewStorage(dotGit, gitcache.NewObjectLRUDefault())
+	if err := private.Init(); err != nil {
+		files.remove()
+		return nil, nil, repoStorage{}, err
+	}
+	return newCloneStorage(private, objects), worktree, files, nil
+}
+
+// remove removes the files of a clone, and its object store if no other clone
+// uses it.
+func (s repoStorage) remove() {
+	removeRepositoryDir(s.dir)
+	if s.objects != nil {
+		s.objects.release()
+	}
+}
+
+// size returns the size of a clone: the size of its directory on disk, if
+// any, and its share of the object store.
+func (s repoStorage) size() (int64, error) {
+	var size int64
+	if s.dir != "" {
+		var err error
+		if size, err = dirSize(s.dir); err != nil {
+			return 0, err
+		}
+	}
+	if s.objects == nil {
+		return size, nil
+	}
+	share, err := s.objects.share()
+	return size + share, err
+}
+
+// removeRepositoryDir removes a directory of the cache directory.
+func removeRepositoryDir(dir string) {
+	if dir != "" {
+		_ = os.RemoveAll(dir)
+	}
 }
 
 // repoLease is a borrowed reference to a cached repository. The caller holds the
-// handle's mutex until it calls release (or discard) and must not use the
+// handle's lock until it calls release (or discard) and must not use the
 // repository afterwards.
 type repoLease struct {
 	key    string
@@ -38,31 +191,94 @@ type repoLease struct {
 
 // acquire returns a lease for key, creating the handle on a miss. Concurrent
 // callers for the same key share one handle (and thus one clone) and serialize
-// on its mutex. A handle that is evicted while still leased is finished safely:
-// each handle owns an independent in-memory repository, so the worst case is a
-// redundant re-clone, never two callers touching the same go-git repository.
-// The returned lease's repo() is nil on a cache miss (the caller must clone and
-// call set).
-func acquire(key string) *repoLease {
-	handle, _ := repoCache.LoadOrStore(key, &repoHandle{})
-	handle.mu.Lock()
-	return &repoLease{key: key, handle: handle}
+// on its lock, until ctx is done. A handle that is evicted while leased keeps
+// its repository until the lease is released, then its files are removed; a
+// caller that was waiting for it starts over with a new handle. The returned
+// lease's repo() is nil on a cache miss (the caller must clone and call set).
+func acquire(ctx context.Context, key string) (*repoLease, error) {
+	for {
+		handle, _ := repoCache.LoadOrStore(key, &repoHandle{lock: make(chan struct{}, 1)})
+		select {
+		case handle.lock <- struct{}{}:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("gave up waiting for the repository, in use by another request: %w", ctx.Err())
+		}
+		if handle.lease() {
+			return &repoLease{key: key, handle: handle}, nil
+		}
+		<-handle.lock
+	}
+}
+
+// lease marks h as leased, unless it has been evicted.
+func (h *repoHandle) lease() bool {
+	h.stateMu.Lock()
+	defer h.stateMu.Unlock()
+	if h.evicted {
+		return false
+	}
+	h.leased = true
+	return true
+}
+
+// unlease marks h as not leased, and removes its files if it has been evicted
+// in the meantime.
+func (h *repoHandle) unlease() {
+	h.stateMu.Lock()
+	defer h.stateMu.Unlock()
+	h.leased = false
+	if h.evicted {
+		h.storage.remove()
+	}
+}
+
+func (h *repoHandle) isEvicted() bool {
+	h.stateMu.Lock()
+	defer h.stateMu.Unlock()
+	return h.evicted
+}
+
+// evict is called once h has left the cache.
+func (h *repoHandle) evict() {
+	h.stateMu.Lock()
+	defer h.stateMu.Unlock()
+	h.evicted = true
+	if !h.leased {
+		h.storage.remove()
+	}
 }
 
 func (l *repoLease) repo() *git.Repository { return l.handle.repo }
 
-func (l *repoLease) set(repo *git.Repository) { l.handle.repo = repo }
+// set stores repo, stored in storage, in the leased handle. The storage of
+// the repository it replaces is removed.
+func (l *repoLease) set(repo *git.Repository, storage repoStorage) {
+	if l.handle.repo != nil {
+		l.handle.storage.remove()
+	}
+	l.handle.repo = repo
+	l.handle.storage = storage
+}
 
 // release returns the leased repository to the cache by unlocking its handle.
+// When the cache is bounded in bytes, the repository is weighed first, which
+// may evict other repositories, or this one if it is too large on its own.
 func (l *repoLease) release() {
-	l.handle.mu.Unlock()
+	if repoCacheOptions.MaxBytes > 0 && l.handle.repo != nil {
+		if size, err := l.handle.storage.size(); err == nil {
+			repoCache.SetWeight(l.key, size)
+		}
+	}
+	l.handle.unlease()
+	<-l.handle.lock
 }
 
 // discard drops a never-populated handle from the cache (used when a cache-miss
 // clone fails) and releases the lease, so a later request retries cleanly.
 func (l *repoLease) discard() {
-	if l.handle.repo == nil {
+	if l.handle.repo == nil && !l.handle.isEvicted() {
 		repoCache.Delete(l.key)
 	}
-	l.handle.mu.Unlock()
+	l.handle.unlease()
+	<-l.handle.lock
 }
@@ -1,17 +1,26 @@
 package pusher
 
 import (
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	git "github.com/go-git/go-git/v5"
 )
 
+// acquireNow acquires key without a deadline.
+func acquireNow(key string) *repoLease {
+	l, _ := acquire(context.Background(), key)
+	return l
+}
+
 // touch acquires key, populates it on a miss, and releases the lease.
 func touch(key string) {
-	l := acquire(key)
+	l := acquireNow(key)
 	if l.repo() == nil {
-		l.set(&git.Repository{})
+		l.set(&git.Repository{}, repoStorage{})
 	}
 	l.release()
 }
@@ -20,14 +29,15 @@ func TestRepositoryCacheAcquireSerializes(t *testing.T) {
 	InitRepoCache(2)
 	defer InitRepoCache(0)
 
-	l1 := acquire("a")
-	l1.set(&git.Repository{})
+	l1 := acquireNow("a")
+	l1.set(&git.Repository{}, repoStorage{})
 
-	done := make(chan struct{})
+	done, released := make(chan struct{}), make(chan struct{})
 	go func() {
-		l2 := acquire("a")
+		l2 := acquireNow("a")
 		close(done)
 		l2.release()
+		close(released)
 	}()
 
 	select {
@@ -43,6 +53,7 @@ func TestRepositoryCacheAcquireSerializes(t *testing.T) {
 	case <-time.After(time.Second):
 		t.Fatal("second acquire did not proceed after the first lease was released")
 	}
+	<-released
 }
 
 func TestRepositoryCacheDiscard(t *testing.T) {
@@ -50,7 +61,7 @@ func TestRepositoryCacheDiscard(t *testing.T) {
 	defer InitRepoCache(0)
 
 	// A miss whose clone fails is discarded and must not linger.
-	l := acquire("a")
+	l := acquireNow("a")
 	if l.repo() != nil {
 		t.Fatalf("expected a cache miss for a fresh key")
 	}
@@ -61,7 +72,7 @@ func TestRepositoryCacheDiscard(t *testing.T) {
 	}
 
 	// The next acquisition is a clean miss again.
-	l2 := acquire("a")
+	l2 := acquireNow("a")
 	if l2.repo() != nil {
 		t.Errorf("expected a cache miss after discard")
 	}
@@ -75,7 +86,7 @@ func TestRepositoryCacheReuse(t *testing.T) {
 	touch("a") // populate on a miss
 
 	// A second acquisition of the same key reuses the cached repository.
-	l := acquire("a")
+	l := acquireNow("a")
 	if l.repo() == nil {
 		t.Errorf("expected a cache hit for a populated key")
 	}
@@ -100,3 +111,142 @@ func TestInitRepoCache(t *testing.T) {
 		t.Fatalf("InitRepoCache(7) should enable caching")
 	}
 }
+
+func TestRepositoryCacheOnDisk(t *testing.T) {
+	dir := t.TempDir()
+	leftover := filepath.Join(dir, "repo-leftover")
+	if err := os.Mkdir(leftover, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	unrelated := filepath.Join(dir, "unrelated")
+	if err := os.Mkdir(unrelated, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ConfigureRepoCache(RepoCacheOptions{MaxRepos: 2, Dir: dir}); err != nil {
+		t.Fatalf("ConfigureRepoCache: %v", err)
+	}
+	defer InitRepoCache(0)
+
+	if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+		t.Errorf("the repository left by a previous run must be removed")
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("the entries not created by the cache must be kept: %v", err)
+	}
+
+	params := GetRepositoryParams{Repository: seededRemote(t), Branch: "master"}
+	repository, release, err := getRepository(context.Background(), params)
+	if err != nil {
+		t.Fatalf("getRepository: %v", err)
+	}
+	handle, _ := repoCache.Get(params.cacheKey())
+	if _, err := os.Stat(filepath.Join(handle.storage.dir, "README.md")); err != nil {
+		t.Errorf("the worktree is not on disk: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(handle.storage.dir, git.GitDirName, "HEAD")); err != nil {
+		t.Errorf("the repository is not on disk: %v", err)
+	}
+	release()
+
+	// A hit refreshes the repository in place.
+	again, release, err := getRepository(context.Background(), params)
+	if err != nil {
+		t.Fatalf("getRepository: %v", err)
+	}
+	if again != repository {
+		t.Errorf("expected the cached repository to be reused")
+	}
+	release()
+}
+
+func TestRepositoryCacheEvictsByBytes(t *testing.T) {
+	dir := t.TempDir()
+	// Large enough for one clone of a seeded remote, not for two.
+	if err := ConfigureRepoCache(RepoCacheOptions{MaxRepos: 10, MaxBytes: 3000, Dir: dir}); err != nil {
+		t.Fatalf("ConfigureRepoCache: %v", err)
+	}
+	defer InitRepoCache(0)
+
+	first := GetRepositoryParams{Repository: seededRemote(t), Branch: "master"}
+	_, release, err := getRepository(context.Background(), first)
+	if err != nil {
+		t.Fatalf("getRepository: %v", err)
+	}
+	firstHandle, _ := repoCache.Get(first.cacheKey())
+	firstDir := firstHandle.storage.dir
+	release()
+	if size, _ := firstHandle.storage.size(); size == 0 || size > 3000 {
+		t.Fatalf("the test assumes a clone of 1 to 3000 bytes, got %d", size)
+	}
+
+	second := GetRepositoryParams{Repository: seededRemote(t), Branch: "master"}
+	_, release, err = getRepository(context.Background(), second)
+	if err != nil {
+		t.Fatalf("getRepository: %v", err)
+	}
+	// The first repository is not leased: it is removed as soon as it is evicted.
+	release()
+
+	if _, ok := repoCache.Get(first.cacheKey()); ok {
+		t.Errorf("the first repository should have been evicted")
+	}
+	if _, err := os.Stat(firstDir); !os.IsNotExist(err) {
+		t.Errorf("the files of the evicted repository must be removed")
+	}
+	if _, ok := repoCache.Get(second.cacheKey()); !ok {
+		t.Errorf("the second repository should be cached")
+	}
+}
+
+func TestRepositoryCacheEvictedWhileLeased(t *testing.T) {
+	dir := t.TempDir()
+	if err := ConfigureRepoCache(RepoCacheOptions{MaxRepos: 1, Dir: dir}); err != nil {
+		t.Fatalf("ConfigureRepoCache: %v", err)
+	}
+	defer InitRepoCache(0)
+
+	repoDir, err := os.MkdirTemp(dir, repoDirPattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := acquireNow("a")
+	l.set(&git.Repository{}, repoStorage{dir: repoDir})
+
+	touch("b") // evicts "a" while it is leased
+	if _, err := os.Stat(repoDir); err != nil {
+		t.Fatalf("the files of a leased repository must be kept until it is released: %v", err)
+	}
+
+	l.release()
+	if _, err := os.Stat(repoDir); !os.IsNotExist(err) {
+		t.Errorf("the files of the evicted repository must be removed once released")
+	}
+
+	// The evicted handle is never leased again.
+	l2 := acquireNow("a")
+	if l2.handle == l.handle || l2.repo() != nil {
+		t.Errorf("expected a new handle after the eviction")
+	}
+	l2.release()
+}
+
+func TestRepositoryCacheAcquireGivesUp(t *testing.T) {
+	InitRepoCache(2)
+	defer InitRepoCache(0)
+
+	l := acquireNow("a")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := acquire(ctx, "a"); err == nil {
+		t.Fatal("acquire must give up once the deadline passes while the lease is held")
+	}
+
+	l.release()
+	l2, err := acquire(context.Background(), "a")
+	if err != nil {
+		t.Fatalf("the lease given up on must not stay taken: %v", err)
+	}
+	l2.release()
+}
@@ -0,0 +1,161 @@
+package pusher
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	"github.com/syngit-org/syngit/pkg/forge"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+)
+
+// defaultBranchPrefix is the prefix of the generated branches when the
+// RemoteTarget does not set one.
+const defaultBranchPrefix = "syngit/"
+
+func isChangeRequestDelivery(params interceptor.GitPipelineParams) bool {
+	return params.RemoteTarget.Spec.DeliveryMode == syngit.ChangeRequestDelivery
+}
+
+// ChangeRequestBranch returns the branch the intercepted object is pushed to
+// when the RemoteTarget delivers through change requests. There is one branch
+// per object and target branch, so that every change of the object updates
+// the same change request.
+func ChangeRequestBranch(params interceptor.GitPipelineParams) string {
+	prefix := defaultBranchPrefix
+	if params.RemoteTarget.Spec.ChangeRequest != nil && params.RemoteTarget.Spec.ChangeRequest.BranchPrefix != "" {
+		prefix = params.RemoteTarget.Spec.ChangeRequest.BranchPrefix
+	}
+
+	resource := params.InterceptedGVR.Resource
+	if params.InterceptedGVR.Group != "" {
+		resource += "." + params.InterceptedGVR.Group
+	}
+	namespace := params.Syncer.InterceptedNamespace
+	if namespace == "" {
+		namespace = interceptor.ClusterScopedPathSegment
+	}
+
+	return fmt.Sprintf("%s%s/%s/%s/%s", prefix, params.RemoteTarget.Spec.TargetBranch, resource, namespace, params.InterceptedName)
+}
+
+// openChangeRequest opens the change request from the branch of the object to
+// the target branch, or updates the one that is already open. When the commit
+// did not change anything, nothing was pushed: the change request is only
+// looked up. It returns the URL of the change request, if any.
+func openChangeRequest(
+	ctx context.Context,
+	params interceptor.GitPipelineParams,
+	paths interceptor.ClaimedPaths,
+	commitHash string,
+	changed bool,
+) (string, error) {
+	spec := params.RemoteTarget.Spec.ChangeRequest
+	if spec == nil {
+		return "", fmt.Errorf("the RemoteTarget %s uses the %s delivery mode without spec.changeRequest",
+			params.RemoteTarget.Name, syngit.ChangeRequestDelivery)
+	}
+	if params.GitUserInfo.Token == "" {
+		return "", fmt.Errorf("opening a change request on %s requires a RemoteUser authenticated with a token",
+			params.RemoteTarget.Spec.TargetRepository)
+	}
+
+	httpClient, err := forgeHTTPClient(params)
+	if err != nil {
+		return "", err
+	}
+	f, err := forge.New(forge.Kind(spec.Forge), forge.Options{
+		APIURL:     spec.APIURL,
+		Repository: params.RemoteTarget.Spec.TargetRepository,
+		Token:      params.GitUserInfo.Token,
+		HTTPClient: httpClient,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	head := ChangeRequestBranch(params)
+	base := params.RemoteTarget.Spec.TargetBranch
+
+	if !changed {
+		existing, err := f.FindOpen(ctx, head, base)
+		if err != nil || existing == nil {
+			return "", err
+		}
+		return existing.URL, nil
+	}
+
+	changeRequest, err := forge.Ensure(ctx, f, forge.ChangeRequest{
+		Title: resourceMessage(params),
+		Body:  changeRequestBody(params, paths, commitHash),
+		Head:  head,
+		Base:  base,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to open the change request from %s to %s: %w", head, base, err)
+	}
+	return changeRequest.URL, nil
+}
+
+func changeRequestBody(params interceptor.GitPipelineParams, paths interceptor.ClaimedPaths, commitHash string) string {
+	syncerKind := "RemoteSyncer"
+	if params.Syncer.ClusterWide {
+		syncerKind = "ClusterWideRemoteSyncer"
+	}
+	body := fmt.Sprintf("Requested by %s through the %s %s.\n\nLatest commit: %s\n",
+		params.GitUserInfo.User, syncerKind, params.Syncer.String(), commitHash)
+	for _, path := range paths.Add {
+		body += fmt.Sprintf("\n- `%s`", path)
+	}
+	for _, path := range paths.Delete {
+		body += fmt.Sprintf("\n- `%s` (deleted)", path)
+	}
+	return body
+}
+
+// forgeHTTPClient returns the client that calls the API of the forge. It
+// trusts the same certificates, and goes through the same proxy with the same
+// client certificate, as the git operations.
+func forgeHTTPClient(params interceptor.GitPipelineParams) (*http.Client, error) {
+	gitTransport := params.Transport
+	if params.CABundle == nil && !params.Syncer.Spec.InsecureSkipTlsVerify &&
+		gitTransport.ProxyURL == "" && gitTransport.ClientCert == nil {
+		return http.DefaultClient, nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: params.Syncer.Spec.InsecureSkipTlsVerify, // nolint:gosec
+	}
+	if params.CABundle != nil {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+		if !rootCAs.AppendCertsFromPEM(params.CABundle) {
+			return nil, fmt.Errorf("failed to parse the CA bundle of %s", params.RemoteTarget.Spec.TargetRepository)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	if gitTransport.ClientCert != nil {
+		clientCert, err := tls.X509KeyPair(gitTransport.ClientCert, gitTransport.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the client certificate of %s: %w", params.RemoteTarget.Spec.TargetRepository, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	if gitTransport.ProxyURL != "" {
+		proxy := proxyOptions(gitTransport)
+		proxyURL, err := proxy.FullURL()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse the proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{Transport: transport}, nil
+}
@@ -16,6 +16,70 @@ func GetPathsFromClaimedPaths(claimedPaths interceptor.ClaimedPaths) []string {
 }
 
 func Commit(params interceptor.GitPipelineParams, worktree *git.Worktree, paths interceptor.ClaimedPaths, targetRepository *git.Repository) (string, error) {
+	commitMessage, err := interceptor.BuildCommitMessage(
+		params.Syncer.Spec,
+		interceptor.NewCommitMessageData(params, paths, buildCommitMessage(params, paths)),
+		commitSigner(params),
+	)
+	if err != nil {
+		return "", err
+	}
+	return commitWorktree(params, worktree, paths, targetRepository, commitMessage)
+}
+
+// commitBatch commits the changes of several objects at once, with an
+// aggregated message. changePaths holds the paths claimed by each change.
+func commitBatch(
+	changes []interceptor.GitPipelineParams,
+	changePaths []interceptor.ClaimedPaths,
+	worktree *git.Worktree,
+	targetRepository *git.Repository,
+) (string, error) {
+	data := make([]interceptor.CommitMessageData, len(changes))
+	for i, change := range changes {
+		data[i] = interceptor.NewCommitMessageData(change, changePaths[i], buildCommitMessage(change, changePaths[i]))
+	}
+	commitMessage, err := interceptor.BuildBatchCommitMessage(changes[0].Syncer.Spec, data, commitSigner(changes[0]))
+	if err != nil {
+		return "", err
+	}
+	return commitWorktree(changes[0], worktree, mergeClaimedPaths(worktree, changePaths), targetRepository, commitMessage)
+}
+
+// mergeClaimedPaths merges the paths claimed by several changes. A path that
+// one change writes and a later one deletes, or the reverse, is staged
+// according to what the worktree finally holds.
+func mergeClaimedPaths(worktree *git.Worktree, changePaths []interceptor.ClaimedPaths) interceptor.ClaimedPaths {
+	merged := interceptor.NewClaimedPaths()
+	seen := map[string]bool{}
+	for _, paths := range changePaths {
+		for _, path := range GetPathsFromClaimedPaths(paths) {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			if _, err := worktree.Filesystem.Stat(path); err == nil {
+				merged.AppendAddedPath(path)
+			} else {
+				merged.AppendDeletedPath(path)
+			}
+		}
+	}
+	return merged
+}
+
+// commitSigner is the "Name <email>" identity of the Signed-off-by trailer.
+func commitSigner(params interceptor.GitPipelineParams) string {
+	return fmt.Sprintf("%s <%s>", params.GitUserInfo.User, params.GitUserInfo.Email)
+}
+
+func commitWorktree(
+	params interceptor.GitPipelineParams,
+	worktree *git.Worktree,
+	paths interceptor.ClaimedPaths,
+	targetRepository *git.Repository,
+	commitMessage string,
+) (string, error) {
 	for _, path := range paths.Add {
 		_, err := worktree.Add(path)
 		if err != nil {
@@ -30,7 +94,10 @@ func Commit(params interceptor.GitPipelineParams, worktree *git.Worktree, paths
 		}
 	}
 
-	commitMessage := buildCommitMessage(params, paths)
+	signKey, signer, err := commitSigning(params.GitUserInfo)
+	if err != nil {
+		return "", err
+	}
 
 	// Commit the changes
 	commit, err := worktree.Commit(commitMessage, &git.CommitOptions{
@@ -39,6 +106,8 @@ func Commit(params interceptor.GitPipelineParams, worktree *git.Worktree, paths
 			Email: params.GitUserInfo.Email,
 			When:  time.Now(),
 		},
+		SignKey: signKey,
+		Signer:  signer,
 	})
 	if err != nil {
 		if isErrorSkipable(err) {
@@ -58,18 +127,24 @@ func Commit(params interceptor.GitPipelineParams, worktree *git.Worktree, paths
 	return commit.String(), nil
 }
 
-func buildCommitMessage(params interceptor.GitPipelineParams, paths interceptor.ClaimedPaths) string {
+// resourceMessage identifies the intercepted object, as in
+// "deployments.apps/v1: default/nginx".
+func resourceMessage(params interceptor.GitPipelineParams) string {
 	namespacePath := params.Syncer.InterceptedNamespace
 	if namespacePath == "" {
 		namespacePath = interceptor.ClusterScopedPathSegment
 	}
-	resourceMessage := fmt.Sprintf("%s.%s/%s: %s/%s",
+	return fmt.Sprintf("%s.%s/%s: %s/%s",
 		params.InterceptedGVR.Resource,
 		params.InterceptedGVR.Group,
 		params.InterceptedGVR.Version,
 		namespacePath,
 		params.InterceptedName,
 	)
+}
+
+func buildCommitMessage(params interceptor.GitPipelineParams, paths interceptor.ClaimedPaths) string {
+	resourceMessage := resourceMessage(params)
 
 	additionMsg := ""
 	deletionMsg := ""
@@ -0,0 +1,359 @@
+package pusher
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	fdiff "github.com/go-git/go-git/v5/plumbing/format/diff"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/syngit-org/syngit/internal/mutator"
+	"github.com/syngit-org/syngit/internal/walker"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// defaultDiffPreviewMaxSize is the default of spec.diffPreview.maxSize.
+	defaultDiffPreviewMaxSize = 2048
+	diffPreviewContextLines   = 3
+	// sopsMetadataKey is the root key where SOPS stores the encryption
+	// metadata of a document. It changes on every encryption.
+	sopsMetadataKey = "sops"
+)
+
+// fileContents returns the content of a file at some revision, or nil when the
+// file does not exist there.
+type fileContents func(path string) ([]byte, error)
+
+func treeContents(tree *object.Tree) fileContents {
+	return func(path string) ([]byte, error) {
+		if tree == nil {
+			return nil, nil
+		}
+		file, err := tree.File(path)
+		if errors.Is(err, object.ErrFileNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := file.Contents()
+		return []byte(content), err
+	}
+}
+
+func worktreeContents(worktree *git.Worktree) fileContents {
+	return func(path string) ([]byte, error) {
+		content, err := walker.ReadWorktreeFile(worktree, path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return content, err
+	}
+}
+
+// headTree returns the tree of HEAD, or nil when the repository has no commit.
+func headTree(repository *git.Repository) (*object.Tree, error) {
+	head, err := repository.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repository.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	return commit.Tree()
+}
+
+// commitDiffPreview returns the diff preview of the claimed paths in the
+// commit commitHash, against its first parent.
+func commitDiffPreview(params interceptor.GitPipelineParams, repository *git.Repository, commitHash string, paths interceptor.ClaimedPaths) (string, error) {
+	commit, err := repository.CommitObject(plumbing.NewHash(commitHash))
+	if err != nil {
+		return "", err
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", err
+	}
+	var parentTree *object.Tree
+	if commit.NumParents() > 0 {
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return "", err
+		}
+		if parentTree, err = parent.Tree(); err != nil {
+			return "", err
+		}
+	}
+	return diffPreview(params, paths, treeContents(parentTree), treeContents(tree))
+}
+
+// worktreeDiffPreview returns the diff preview of the claimed paths between
+// HEAD and the worktree, before anything is committed.
+func worktreeDiffPreview(params interceptor.GitPipelineParams, repository *git.Repository, worktree *git.Worktree, paths interceptor.ClaimedPaths) (string, error) {
+	tree, err := headTree(repository)
+	if err != nil {
+		return "", err
+	}
+	return diffPreview(params, paths, treeContents(tree), worktreeContents(worktree))
+}
+
+// diffPreview renders the changes of the claimed paths between two revisions,
+// truncated to the maxSize of the syncer. With SOPS, the values are ciphertext
+// that changes on every encryption: only the keys that changed are listed, so
+// that nothing derived from the secrets is returned to the client.
+func diffPreview(params interceptor.GitPipelineParams, paths interceptor.ClaimedPaths, from, to fileContents) (string, error) {
+	redact := mutator.IsSOPSEnabled(params)
+
+	claimed := slices.Concat(paths.Add, paths.Delete)
+	sort.Strings(claimed)
+	claimed = slices.Compact(claimed)
+
+	var out bytes.Buffer
+	for _, path := range claimed {
+		before, err := from(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s before the change: %w", path, err)
+		}
+		after, err := to(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s after the change: %w", path, err)
+		}
+		if bytes.Equal(before, after) {
+			continue
+		}
+
+		if redact {
+			if err := writeKeysDiff(&out, path, before, after); err != nil {
+				return "", err
+			}
+			continue
+		}
+		encoder := fdiff.NewUnifiedEncoder(&out, diffPreviewContextLines)
+		if err := encoder.Encode(newFilePatch(path, before, after)); err != nil {
+			return "", fmt.Errorf("failed to render the diff of %s: %w", path, err)
+		}
+	}
+
+	maxSize := int(params.Syncer.Spec.DiffPreview.MaxSize)
+	if maxSize <= 0 {
+		maxSize = defaultDiffPreviewMaxSize
+	}
+	return truncateDiff(out.String(), maxSize), nil
+}
+
+// truncateDiff cuts diff at the last line that fits in maxSize bytes.
+func truncateDiff(diff string, maxSize int) string {
+	if len(diff) <= maxSize {
+		return diff
+	}
+	cut := strings.LastIndex(diff[:maxSize], "\n") + 1
+	return diff[:cut] + fmt.Sprintf("... %d bytes truncated\n", len(diff)-cut)
+}
+
+// writeKeysDiff lists the keys of path that are added (+), removed (-) or
+// changed (~). The values themselves are never written.
+func writeKeysDiff(out io.Writer, path string, before, after []byte) error {
+	beforeKeys, err := documentKeys(before)
+	if err != nil {
+		return fmt.Errorf("failed to read the keys of %s before the change: %w", path, err)
+	}
+	afterKeys, err := documentKeys(after)
+	if err != nil {
+		return fmt.Errorf("failed to read the keys of %s after the change: %w", path, err)
+	}
+
+	fromName, toName := "a/"+path, "b/"+path
+	if before == nil {
+		fromName = "/dev/null"
+	}
+	if after == nil {
+		toName = "/dev/null"
+	}
+	_, _ = fmt.Fprintf(out, "--- %s\n+++ %s\n", fromName, toName)
+
+	keys := make([]string, 0, len(beforeKeys)+len(afterKeys))
+	for key := range beforeKeys {
+		keys = append(keys, key)
+	}
+	for key := range afterKeys {
+		if _, found := beforeKeys[key]; !found {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		beforeValue, inBefore := beforeKeys[key]
+		afterValue, inAfter := afterKeys[key]
+		switch {
+		case !inBefore:
+			_, _ = fmt.Fprintf(out, "+ %s\n", key)
+		case !inAfter:
+			_, _ = fmt.Fprintf(out, "- %s\n", key)
+		case beforeValue != afterValue:
+			_, _ = fmt.Fprintf(out, "~ %s\n", key)
+		}
+	}
+	return nil
+}
+
+// documentKeys flattens the YAML documents of content into the paths of their
+// leaves, as in "data.password" or "spec.ports[0].port", mapped to the values.
+// When there are several documents, the paths are prefixed by the identity of
+// their document, as in "Secret/db: data.password".
+func documentKeys(content []byte) (map[string]string, error) {
+	keys := map[string]string{}
+	if len(content) == 0 {
+		return keys, nil
+	}
+
+	var docs []map[string]interface{}
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(content)))
+	for {
+		raw, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		doc := map[string]interface{}{}
+		if err := yaml.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+		if len(doc) > 0 {
+			docs = append(docs, doc)
+		}
+	}
+
+	for _, doc := range docs {
+		prefix := ""
+		if len(docs) > 1 {
+			prefix = documentIdentity(doc) + ": "
+		}
+		delete(doc, sopsMetadataKey)
+		flattenKeys(prefix, doc, keys)
+	}
+	return keys, nil
+}
+
+func documentIdentity(doc map[string]interface{}) string {
+	kind, _ := doc["kind"].(string)
+	name := ""
+	if metadata, ok := doc["metadata"].(map[string]interface{}); ok {
+		name, _ = metadata["name"].(string)
+	}
+	return kind + "/" + name
+}
+
+func flattenKeys(path string, node interface{}, keys map[string]string) {
+	switch value := node.(type) {
+	case map[string]interface{}:
+		if len(value) == 0 {
+			keys[path] = "{}"
+		}
+		separator := "."
+		if path == "" || strings.HasSuffix(path, ": ") {
+			separator = ""
+		}
+		for key, child := range value {
+			flattenKeys(path+separator+key, child, keys)
+		}
+	case []interface{}:
+		if len(value) == 0 {
+			keys[path] = "[]"
+		}
+		for i, child := range value {
+			flattenKeys(fmt.Sprintf("%s[%d]", path, i), child, keys)
+		}
+	default:
+		keys[path] = fmt.Sprint(value)
+	}
+}
+
+// The types below implement the patch interfaces of go-git, so that the
+// preview is rendered by its unified encoder like "git show" would.
+
+type filePatch struct {
+	from, to *patchFile
+	chunks   []fdiff.Chunk
+}
+
+type patchFile struct {
+	path string
+	hash plumbing.Hash
+}
+
+type patchChunk struct {
+	content   string
+	operation fdiff.Operation
+}
+
+type patch struct {
+	filePatches []fdiff.FilePatch
+}
+
+func newFilePatch(path string, before, after []byte) patch {
+	fp := &filePatch{}
+	if before != nil {
+		fp.from = &patchFile{path: path, hash: plumbing.ComputeHash(plumbing.BlobObject, before)}
+	}
+	if after != nil {
+		fp.to = &patchFile{path: path, hash: plumbing.ComputeHash(plumbing.BlobObject, after)}
+	}
+	for _, d := range diff.Do(string(before), string(after)) {
+		operation := fdiff.Equal
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			operation = fdiff.Add
+		case diffmatchpatch.DiffDelete:
+			operation = fdiff.Delete
+		}
+		fp.chunks = append(fp.chunks, patchChunk{content: d.Text, operation: operation})
+	}
+	return patch{filePatches: []fdiff.FilePatch{fp}}
+}
+
+func (p patch) FilePatches() []fdiff.FilePatch { return p.filePatches }
+func (p patch) Message() string                { return "" }
+
+func (fp *filePatch) IsBinary() bool { return false }
+func (fp *filePatch) Chunks() []fdiff.Chunk {
+	return fp.chunks
+}
+func (fp *filePatch) Files() (fdiff.File, fdiff.File) {
+	// A nil *patchFile must be returned as a nil interface.
+	var from, to fdiff.File
+	if fp.from != nil {
+		from = fp.from
+	}
+	if fp.to != nil {
+		to = fp.to
+	}
+	return from, to
+}
+
+func (f *patchFile) Hash() plumbing.Hash     { return f.hash }
+func (f *patchFile) Mode() filemode.FileMode { return filemode.Regular }
+func (f *patchFile) Path() string            { return f.path }
+
+func (c patchChunk) Content() string       { return c.content }
+func (c patchChunk) Type() fdiff.Operation { return c.operation }
@@ -0,0 +1,157 @@
+package pusher
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage/memory"
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	features "github.com/syngit-org/syngit/pkg/feature"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+)
+
+func contentsOf(files map[string]string) fileContents {
+	return func(path string) ([]byte, error) {
+		content, found := files[path]
+		if !found {
+			return nil, nil
+		}
+		return []byte(content), nil
+	}
+}
+
+func TestDiffPreview(t *testing.T) {
+	from := contentsOf(map[string]string{
+		"cm.yaml":      "apiVersion: v1\nkind: ConfigMap\ndata:\n  key: before\n",
+		"deleted.yaml": "kind: ConfigMap\n",
+		"same.yaml":    "kind: Secret\n",
+	})
+	to := contentsOf(map[string]string{
+		"cm.yaml":   "apiVersion: v1\nkind: ConfigMap\ndata:\n  key: after\n",
+		"new.yaml":  "kind: Namespace\n",
+		"same.yaml": "kind: Secret\n",
+	})
+	paths := interceptor.ClaimedPaths{
+		Add:    []string{"cm.yaml", "new.yaml", "same.yaml"},
+		Delete: []string{"deleted.yaml"},
+	}
+
+	got, err := diffPreview(interceptor.GitPipelineParams{}, paths, from, to)
+	if err != nil {
+		t.Fatalf("diffPreview: %v", err)
+	}
+	for _, s := range []string{
+		"diff --git a/cm.yaml b/cm.yaml", "-  key: before\n", "+  key: after\n",
+		"diff --git a/new.yaml b/new.yaml\nnew file mode 100644",
+		"diff --git a/deleted.yaml b/deleted.yaml\ndeleted file mode 100644",
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("diff %q missing %q", got, s)
+		}
+	}
+	if strings.Contains(got, "same.yaml") {
+		t.Errorf("diff %q lists an unchanged file", got)
+	}
+}
+
+func TestDiffPreview_truncated(t *testing.T) {
+	params := interceptor.GitPipelineParams{}
+	params.Syncer.Spec.DiffPreview = syngit.DiffPreview{Enabled: true, MaxSize: 256}
+
+	var content strings.Builder
+	for range 100 {
+		content.WriteString("key: value\n")
+	}
+	got, err := diffPreview(params, interceptor.ClaimedPaths{Add: []string{"big.yaml"}},
+		contentsOf(nil), contentsOf(map[string]string{"big.yaml": content.String()}))
+	if err != nil {
+		t.Fatalf("diffPreview: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	if last := lines[len(lines)-1]; !strings.HasPrefix(last, "... ") || !strings.HasSuffix(last, " bytes truncated") {
+		t.Errorf("the diff does not end with the truncation notice: %q", last)
+	}
+	if body := strings.TrimSuffix(got, lines[len(lines)-1]+"\n"); len(body) > 256 || !strings.HasSuffix(body, "\n") {
+		t.Errorf("the diff is not cut at a line within 256 bytes: %q", body)
+	}
+}
+
+func TestDiffPreview_sops(t *testing.T) {
+	previous := features.LoadedFeatureGates[features.SopsEncryption]
+	features.LoadedFeatureGates[features.SopsEncryption] = true
+	t.Cleanup(func() { features.LoadedFeatureGates[features.SopsEncryption] = previous })
+
+	params := interceptor.GitPipelineParams{}
+	params.Syncer.Spec.SOPS.Enabled = true
+
+	before := `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+data:
+  password: ENC[AES256_GCM,data:old,type:str]
+  user: ENC[AES256_GCM,data:same,type:str]
+  removed: ENC[AES256_GCM,data:gone,type:str]
+sops:
+  mac: ENC[AES256_GCM,data:mac1,type:str]
+`
+	after := `apiVersion: v1
+kind: Secret
+metadata:
+  name: db
+data:
+  password: ENC[AES256_GCM,data:new,type:str]
+  user: ENC[AES256_GCM,data:same,type:str]
+  added: ENC[AES256_GCM,data:fresh,type:str]
+sops:
+  mac: ENC[AES256_GCM,data:mac2,type:str]
+`
+	got, err := diffPreview(params, interceptor.ClaimedPaths{Add: []string{"secret.yaml"}},
+		contentsOf(map[string]string{"secret.yaml": before}), contentsOf(map[string]string{"secret.yaml": after}))
+	if err != nil {
+		t.Fatalf("diffPreview: %v", err)
+	}
+
+	want := "--- a/secret.yaml\n+++ b/secret.yaml\n+ data.added\n~ data.password\n- data.removed\n"
+	if got != want {
+		t.Errorf("diffPreview()=%q, want %q", got, want)
+	}
+	if strings.Contains(got, "ENC[") {
+		t.Errorf("the diff leaks the values: %q", got)
+	}
+}
+
+func TestDocumentKeys_multipleDocuments(t *testing.T) {
+	keys, err := documentKeys([]byte("kind: Secret\nmetadata:\n  name: a\ndata:\n  k: v\n---\nkind: Secret\nmetadata:\n  name: b\nlist: [1, 2]\n"))
+	if err != nil {
+		t.Fatalf("documentKeys: %v", err)
+	}
+	for _, key := range []string{"Secret/a: data.k", "Secret/a: metadata.name", "Secret/b: list[1]"} {
+		if _, found := keys[key]; !found {
+			t.Errorf("keys %v missing %q", keys, key)
+		}
+	}
+}
+
+func TestWorktreeDiffPreview(t *testing.T) {
+	repository, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatalf("failed to init the repository: %v", err)
+	}
+	commitFile(t, repository, "cm.yaml", "data: before\n")
+	worktree, _ := repository.Worktree()
+	file, _ := worktree.Filesystem.Create("cm.yaml")
+	_, _ = file.Write([]byte("data: after\n"))
+	_ = file.Close()
+
+	got, err := worktreeDiffPreview(interceptor.GitPipelineParams{}, repository, worktree, interceptor.ClaimedPaths{Add: []string{"cm.yaml"}})
+	if err != nil {
+		t.Fatalf("worktreeDiffPreview: %v", err)
+	}
+	if !strings.Contains(got, "-data: before\n+data: after\n") {
+		t.Errorf("unexpected diff %q", got)
+	}
+}
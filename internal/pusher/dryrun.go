@@ -0,0 +1,90 @@
+package pusher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/syngit-org/syngit/internal/mutator"
+	syngiterrors "github.com/syngit-org/syngit/pkg/errors"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RunGitDryRunPipeline renders the intercepted object in the target repository
+// the same way RunGitPipeline does, but stops before the commit: nothing is
+// pushed. The response lists the paths whose content would change.
+//
+// The worktree of a cached repository is left modified: it is reset the next
+// time the repository is acquired, like after any other pipeline run.
+func RunGitDryRunPipeline(ctx context.Context, cluster client.Reader, params interceptor.GitPipelineParams) (interceptor.GitPushResponse, error) {
+	ctx, cancel := pipelineContext(ctx)
+	defer cancel()
+
+	emptyPaths := make([]string, 0)
+
+	targetRepository, upstreamRepository, release, err := getRepositories(ctx, params)
+	if err != nil {
+		return ResponseBuilder(emptyPaths, "", params.RemoteTarget.Spec.TargetRepository), err
+	}
+	defer release()
+
+	worktreeCtx, cancelWorktree := stageContext(ctx, worktreeShare)
+	worktree, _, err := GetWorkTree(worktreeCtx, params, targetRepository, upstreamRepository)
+	cancelWorktree()
+	if err != nil {
+		return ResponseBuilder(emptyPaths, "", params.RemoteTarget.Spec.TargetRepository),
+			syngiterrors.NewGitPipeline(fmt.Sprintf("failed to get worktree: %v", err))
+	}
+
+	worktree, modifiedPaths, err := mutator.GenerateFinalWorktree(ctx, cluster, params, worktree)
+	if err != nil {
+		return ResponseBuilder(emptyPaths, "", params.RemoteTarget.Spec.TargetRepository),
+			syngiterrors.NewGitPipeline(fmt.Sprintf("failed to generate the worktree: %v", err))
+	}
+
+	changedPaths, err := changedClaimedPaths(worktree, modifiedPaths)
+	if err != nil {
+		return ResponseBuilder(emptyPaths, "", params.RemoteTarget.Spec.TargetRepository),
+			syngiterrors.NewGitPipeline(fmt.Sprintf("failed to compare the worktree: %v", err))
+	}
+
+	response := ResponseBuilder(GetPathsFromClaimedPaths(changedPaths), "", params.RemoteTarget.Spec.TargetRepository)
+	if params.Syncer.Spec.DiffPreview.Enabled {
+		response.Diff, err = worktreeDiffPreview(params, targetRepository, worktree, changedPaths)
+		if err != nil {
+			return response, syngiterrors.NewGitPipeline(fmt.Sprintf("failed to compute the diff: %v", err))
+		}
+	}
+	return response, nil
+}
+
+// changedClaimedPaths keeps the claimed paths that the commit would change:
+// the written files that differ from HEAD, and the deleted files that exist.
+func changedClaimedPaths(worktree *git.Worktree, paths interceptor.ClaimedPaths) (interceptor.ClaimedPaths, error) {
+	changed := interceptor.NewClaimedPaths()
+
+	status, err := worktree.Status()
+	if err != nil {
+		return changed, err
+	}
+	// Status only holds the files that differ from HEAD. Status.File is not
+	// used because it reports any missing entry as untracked.
+	isChanged := func(path string) bool {
+		fileStatus, found := status[path]
+		return found && (fileStatus.Worktree != git.Unmodified || fileStatus.Staging != git.Unmodified)
+	}
+
+	for _, path := range paths.Add {
+		if isChanged(path) {
+			changed.AppendAddedPath(path)
+		}
+	}
+	for _, path := range paths.Delete {
+		if _, err := worktree.Filesystem.Stat(path); err == nil || isChanged(path) {
+			changed.AppendDeletedPath(path)
+		}
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,47 @@
+package pusher
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+)
+
+func TestChangedClaimedPaths(t *testing.T) {
+	repository, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatalf("failed to init the repository: %v", err)
+	}
+	commitFile(t, repository, "unchanged.yaml", "same")
+	commitFile(t, repository, "modified.yaml", "before")
+	commitFile(t, repository, "deleted.yaml", "content")
+
+	worktree, _ := repository.Worktree()
+	for name, content := range map[string]string{
+		"unchanged.yaml": "same",
+		"modified.yaml":  "after",
+		"new.yaml":       "new",
+	} {
+		file, _ := worktree.Filesystem.Create(name)
+		_, _ = file.Write([]byte(content))
+		_ = file.Close()
+	}
+
+	paths := interceptor.ClaimedPaths{
+		Add:    []string{"unchanged.yaml", "modified.yaml", "new.yaml"},
+		Delete: []string{"deleted.yaml", "absent.yaml"},
+	}
+	got, err := changedClaimedPaths(worktree, paths)
+	if err != nil {
+		t.Fatalf("changedClaimedPaths: %v", err)
+	}
+	if want := []string{"modified.yaml", "new.yaml"}; !reflect.DeepEqual(got.Add, want) {
+		t.Errorf("Add=%v, want %v", got.Add, want)
+	}
+	if want := []string{"deleted.yaml"}; !reflect.DeepEqual(got.Delete, want) {
+		t.Errorf("Delete=%v, want %v", got.Delete, want)
+	}
+}
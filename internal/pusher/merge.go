@@ -0,0 +1,324 @@
+package pusher
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	syngiterrors "github.com/syngit-org/syngit/pkg/errors"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+)
+
+// upstreamBasedMerge merges the upstream branch of params in its target
+// branch, with a merge commit, and returns the worktree of the target branch.
+// When one of the branches holds the other, the target branch is fast-forwarded
+// or left as it is instead.
+//
+// The merge is made file by file: a file changed on both sides, but not in
+// the same way, is a conflict, even when the changes touch different lines.
+func upstreamBasedMerge(
+	ctx context.Context,
+	params interceptor.GitPipelineParams,
+	targetRepository *git.Repository,
+) (*git.Worktree, error) {
+	target, upstream, base, err := divergence(ctx, params, targetRepository)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case target == nil || base.Hash == target.Hash:
+		return resetTargetBranch(targetRepository, params, upstream.Hash)
+	case base.Hash == upstream.Hash:
+		return resetTargetBranch(targetRepository, params, target.Hash)
+	}
+
+	worktree, err := resetTargetBranch(targetRepository, params, target.Hash)
+	if err != nil {
+		return nil, err
+	}
+	targetTree, err := target.Tree()
+	if err != nil {
+		return nil, err
+	}
+	conflicts, _, err := applyChanges(worktree, base, upstream, targetTree)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 {
+		return nil, syngiterrors.NewMergeConflict(syngit.TryMergeOrDie, conflicts)
+	}
+
+	message := fmt.Sprintf("Merge branch '%s' of %s into %s\n",
+		params.RemoteTarget.Spec.UpstreamBranch,
+		params.Syncer.Spec.RemoteRepository,
+		params.RemoteTarget.Spec.TargetBranch,
+	)
+	_, err = commitSigned(params, worktree, message, git.CommitOptions{
+		Parents:           []plumbing.Hash{target.Hash, upstream.Hash},
+		AllowEmptyCommits: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit the merge: %w", err)
+	}
+
+	return worktree, nil
+}
+
+// upstreamBasedRebase replays the commits of the target branch of params that
+// the upstream branch does not hold on top of the upstream branch, and returns
+// the worktree of the target branch. It reports whether the target branch has
+// been rewritten, in which case it must be force-pushed.
+//
+// The commits keep their author and message. The merge commits are left out,
+// and so are the commits whose changes the upstream already holds. A file
+// changed by a commit that the upstream changed too, but not in the same way,
+// is a conflict.
+func upstreamBasedRebase(
+	ctx context.Context,
+	params interceptor.GitPipelineParams,
+	targetRepository *git.Repository,
+) (*git.Worktree, bool, error) {
+	target, upstream, base, err := divergence(ctx, params, targetRepository)
+	if err != nil {
+		return nil, false, err
+	}
+	switch {
+	case target == nil || base.Hash == target.Hash:
+		worktree, err := resetTargetBranch(targetRepository, params, upstream.Hash)
+		return worktree, false, err
+	case base.Hash == upstream.Hash:
+		worktree, err := resetTargetBranch(targetRepository, params, target.Hash)
+		return worktree, false, err
+	}
+
+	commits, err := commitsSince(target, base)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list the commits of the target branch: %w", err)
+	}
+	worktree, err := resetTargetBranch(targetRepository, params, upstream.Hash)
+	if err != nil {
+		return nil, false, err
+	}
+
+	tip := upstream
+	for _, commit := range commits {
+		parent, err := commit.Parent(0)
+		if err != nil {
+			return nil, false, err
+		}
+		tipTree, err := tip.Tree()
+		if err != nil {
+			return nil, false, err
+		}
+		conflicts, applied, err := applyChanges(worktree, parent, commit, tipTree)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(conflicts) > 0 {
+			return nil, false, syngiterrors.NewMergeConflict(syngit.TryRebaseOrDie, conflicts)
+		}
+		if applied == 0 {
+			continue
+		}
+
+		hash, err := commitSigned(params, worktree, commit.Message, git.CommitOptions{Author: &commit.Author})
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to replay the commit %s: %w", commit.Hash, err)
+		}
+		if tip, err = targetRepository.CommitObject(hash); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return worktree, true, nil
+}
+
+// divergence fetches the upstream and the target branches of params, and
+// returns their commits and the best common ancestor of both. The target
+// commit and the ancestor are nil when the target branch does not exist yet.
+func divergence(
+	ctx context.Context,
+	params interceptor.GitPipelineParams,
+	targetRepository *git.Repository,
+) (*object.Commit, *object.Commit, *object.Commit, error) {
+	if err := fetchUpstream(ctx, params, targetRepository); err != nil {
+		return nil, nil, nil, err
+	}
+	upstreamRemoteRef := plumbing.NewRemoteReferenceName(upstreamRemote, params.RemoteTarget.Spec.UpstreamBranch)
+	upstreamRef, err := targetRepository.Reference(upstreamRemoteRef, true)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to find remote reference %s: %w", upstreamRemoteRef.String(), err)
+	}
+	upstream, err := targetRepository.CommitObject(upstreamRef.Hash())
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	targetHash, err := fetchTargetBranch(ctx, params, targetRepository)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if targetHash.IsZero() {
+		return nil, upstream, nil, nil
+	}
+	target, err := targetRepository.CommitObject(targetHash)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	bases, err := target.MergeBase(upstream)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to find the common ancestor of the branches: %w", err)
+	}
+	if len(bases) == 0 {
+		return nil, nil, nil, fmt.Errorf("the target branch %s and the upstream branch %s have no common history",
+			params.RemoteTarget.Spec.TargetBranch, params.RemoteTarget.Spec.UpstreamBranch)
+	}
+	return target, upstream, bases[0], nil
+}
+
+// commitsSince returns the commits of tip that base does not hold, parents
+// first. The merge commits are left out: the commits they merge are listed.
+func commitsSince(tip, base *object.Commit) ([]*object.Commit, error) {
+	var commits []*object.Commit
+	seen := map[plumbing.Hash]bool{}
+	var visit func(commit *object.Commit) error
+	visit = func(commit *object.Commit) error {
+		if seen[commit.Hash] || commit.Hash == base.Hash {
+			return nil
+		}
+		seen[commit.Hash] = true
+		held, err := commit.IsAncestor(base)
+		if err != nil || held {
+			return err
+		}
+		if err := commit.Parents().ForEach(visit); err != nil {
+			return err
+		}
+		if commit.NumParents() == 1 {
+			commits = append(commits, commit)
+		}
+		return nil
+	}
+	return commits, visit(tip)
+}
+
+// applyChanges writes to worktree the changes made from the tree of from to
+// the one of to, and stages them. The worktree is on the tree onto: a path
+// that onto holds neither as from nor as to has been changed on both sides. It
+// returns these paths as conflicts, without writing anything, or else the
+// number of paths written.
+func applyChanges(worktree *git.Worktree, from, to *object.Commit, onto *object.Tree) ([]string, int, error) {
+	fromTree, err := from.Tree()
+	if err != nil {
+		return nil, 0, err
+	}
+	toTree, err := to.Tree()
+	if err != nil {
+		return nil, 0, err
+	}
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to diff %s: %w", to.Hash, err)
+	}
+
+	var conflicts []string
+	var writes object.Changes
+	for _, change := range changes {
+		name := change.To.Name
+		if name == "" {
+			name = change.From.Name
+		}
+		switch entryHash(onto, name) {
+		case change.To.TreeEntry.Hash:
+			// Both sides made the same change.
+		case change.From.TreeEntry.Hash:
+			writes = append(writes, change)
+		default:
+			conflicts = append(conflicts, name)
+		}
+	}
+	if len(conflicts) > 0 {
+		return conflicts, 0, nil
+	}
+
+	for _, change := range writes {
+		if change.To.Name == "" {
+			if _, err := worktree.Remove(change.From.Name); err != nil {
+				return nil, 0, fmt.Errorf("failed to delete file in staging area: %v", err)
+			}
+			continue
+		}
+		if err := restoreFile(worktree, toTree, change.To.Name); err != nil {
+			return nil, 0, err
+		}
+		if _, err := worktree.Add(change.To.Name); err != nil {
+			return nil, 0, fmt.Errorf("failed to add file to staging area: %v", err)
+		}
+	}
+	return nil, len(writes), nil
+}
+
+// resetTargetBranch points the target branch of params at commit, checks it
+// out and hard resets the worktree on it.
+func resetTargetBranch(targetRepository *git.Repository, params interceptor.GitPipelineParams, commit plumbing.Hash) (*git.Worktree, error) {
+	targetBranch := params.RemoteTarget.Spec.TargetBranch
+	targetBranchRef := plumbing.NewBranchReferenceName(targetBranch)
+
+	worktree, err := targetRepository.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	err = worktree.Checkout(&git.CheckoutOptions{
+		Hash: commit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to checkout commit %s: %w", commit, err)
+	}
+
+	err = targetRepository.Storer.SetReference(plumbing.NewHashReference(targetBranchRef, commit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create local branch %s: %w", targetBranchRef.String(), err)
+	}
+
+	err = checkoutToBranch(targetRepository, worktree, targetBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := worktree.Reset(&git.ResetOptions{
+		Commit: commit,
+		Mode:   git.HardReset,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to hard reset: %w", err)
+	}
+
+	return worktree, nil
+}
+
+// commitSigned commits the changes staged in worktree with message, signed
+// with the key of the RemoteUser of params when it has one. The RemoteUser is
+// the committer, and the author unless options sets another one.
+func commitSigned(params interceptor.GitPipelineParams, worktree *git.Worktree, message string, options git.CommitOptions) (plumbing.Hash, error) {
+	signKey, signer, err := commitSigning(params.GitUserInfo)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	committer := &object.Signature{
+		Name:  params.GitUserInfo.User,
+		Email: params.GitUserInfo.Email,
+		When:  time.Now(),
+	}
+	if options.Author == nil {
+		options.Author = committer
+	}
+	options.Committer = committer
+	options.SignKey = signKey
+	options.Signer = signer
+	return worktree.Commit(message, &options)
+}
@@ -0,0 +1,139 @@
+package pusher
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	syngiterrors "github.com/syngit-org/syngit/pkg/errors"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+)
+
+// divergedRemote returns a seeded remote whose user branch and master branch
+// each got a commit of their own since the seed. The user commit writes
+// userFile and the master one writes upstreamFile.
+func divergedRemote(t *testing.T, userFile, upstreamFile string) string {
+	t.Helper()
+
+	remote := seededRemote(t)
+	clone, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{URL: remote})
+	if err != nil {
+		t.Fatalf("failed to clone the remote: %v", err)
+	}
+	worktree, _ := clone.Worktree()
+	master, _ := clone.Head()
+
+	err = worktree.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("user"), Create: true})
+	if err != nil {
+		t.Fatalf("failed to create the user branch: %v", err)
+	}
+	commitFile(t, clone, userFile, "user: change\n")
+	err = clone.Push(&git.PushOptions{RefSpecs: []config.RefSpec{"refs/heads/user:refs/heads/user"}})
+	if err != nil {
+		t.Fatalf("failed to push the user branch: %v", err)
+	}
+
+	err = worktree.Checkout(&git.CheckoutOptions{Branch: master.Name()})
+	if err != nil {
+		t.Fatalf("failed to checkout master: %v", err)
+	}
+	commitFile(t, clone, upstreamFile, "upstream: change\n")
+	if err := clone.Push(&git.PushOptions{}); err != nil {
+		t.Fatalf("failed to push master: %v", err)
+	}
+	return remote
+}
+
+func mergeStrategyParams(remote string, strategy syngit.MergeStrategy) interceptor.GitPipelineParams {
+	params := multipleTargetParams(remote, "user")
+	params.Syncer.Spec.RemoteRepository = remote
+	params.RemoteTarget.Spec.UpstreamBranch = "master"
+	params.RemoteTarget.Spec.MergeStrategy = strategy
+	return params
+}
+
+func TestGetWorkTree_merge(t *testing.T) {
+	remote := divergedRemote(t, "user.yaml", "upstream.yaml")
+	masterTip := remoteBranch(t, remote, "master")
+
+	response, err := RunGitPipeline(context.Background(), nil, mergeStrategyParams(remote, syngit.TryMergeOrDie))
+	if err != nil {
+		t.Fatalf("RunGitPipeline: %v", err)
+	}
+
+	repository, _ := git.PlainOpen(remote)
+	commit, err := repository.CommitObject(plumbing.NewHash(response.CommitHash))
+	if err != nil {
+		t.Fatalf("failed to read the pushed commit: %v", err)
+	}
+	merge, err := commit.Parent(0)
+	if err != nil {
+		t.Fatalf("failed to read the merge commit: %v", err)
+	}
+	if merge.NumParents() != 2 || merge.ParentHashes[1] != masterTip {
+		t.Fatalf("the object must be committed on top of a merge of master, got the parents %v", merge.ParentHashes)
+	}
+	tree, _ := commit.Tree()
+	for _, path := range []string{"user.yaml", "upstream.yaml"} {
+		if _, err := tree.File(path); err != nil {
+			t.Errorf("the merged branch must hold %s: %v", path, err)
+		}
+	}
+}
+
+func TestGetWorkTree_rebase(t *testing.T) {
+	remote := divergedRemote(t, "user.yaml", "upstream.yaml")
+	masterTip := remoteBranch(t, remote, "master")
+
+	response, err := RunGitPipeline(context.Background(), nil, mergeStrategyParams(remote, syngit.TryRebaseOrDie))
+	if err != nil {
+		t.Fatalf("RunGitPipeline: %v", err)
+	}
+
+	repository, _ := git.PlainOpen(remote)
+	commit, err := repository.CommitObject(plumbing.NewHash(response.CommitHash))
+	if err != nil {
+		t.Fatalf("failed to read the pushed commit: %v", err)
+	}
+	replayed, err := commit.Parent(0)
+	if err != nil {
+		t.Fatalf("failed to read the replayed commit: %v", err)
+	}
+	if replayed.NumParents() != 1 || replayed.ParentHashes[0] != masterTip {
+		t.Fatalf("the user commit must be replayed on master, got the parents %v", replayed.ParentHashes)
+	}
+	if replayed.Message != "add user.yaml" || replayed.Author.Name != "test" {
+		t.Errorf("the replayed commit must keep its message and author, got %q by %s", replayed.Message, replayed.Author.Name)
+	}
+	tree, _ := commit.Tree()
+	if _, err := tree.File("user.yaml"); err != nil {
+		t.Errorf("the rebased branch must hold the user change: %v", err)
+	}
+}
+
+func TestGetWorkTree_conflict(t *testing.T) {
+	for _, strategy := range []syngit.MergeStrategy{syngit.TryMergeOrDie, syngit.TryRebaseOrDie} {
+		t.Run(string(strategy), func(t *testing.T) {
+			remote := divergedRemote(t, "shared.yaml", "shared.yaml")
+			userTip := remoteBranch(t, remote, "user")
+
+			_, err := RunGitPipeline(context.Background(), nil, mergeStrategyParams(remote, strategy))
+			if !errors.Is(err, syngiterrors.ErrMergeConflict) {
+				t.Fatalf("the change of both branches must conflict, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "- shared.yaml") {
+				t.Errorf("the error must list the conflicting paths: %v", err)
+			}
+			if tip := remoteBranch(t, remote, "user"); tip != userTip {
+				t.Errorf("nothing must be pushed on a conflict, the user branch moved to %s", tip)
+			}
+		})
+	}
+}
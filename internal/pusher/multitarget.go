@@ -0,0 +1,555 @@
+package pusher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	syngiterrors "github.com/syngit-org/syngit/pkg/errors"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// rollbackTimeout bounds the undoing of the pushes of a change that did not
+// land on every RemoteTarget. It is not bound to the deadline of the request:
+// a rollback stopped halfway would leave the targets even further apart.
+const rollbackTimeout = 30 * time.Second
+
+// errUnsharedCommit is returned when the commit of a branch cannot be pushed
+// out of the clone of another branch, since they do not share their objects.
+var errUnsharedCommit = errors.New("the commit is not in the object store of the pushing clone")
+
+// errGroupUnavailable is returned when the clone of a branch of a group cannot
+// be leased: the branches are then pushed on their own, and only the ones that
+// still cannot be cloned fail.
+var errGroupUnavailable = errors.New("the clones of the branches cannot all be leased")
+
+// MaxConcurrentPushes returns how many RemoteTargets of syncer are pushed at
+// the same time.
+func MaxConcurrentPushes(syncer interceptor.SyncerContext) int {
+	if n := syncer.Spec.MultipleTarget.MaxConcurrentPushes; n > 0 {
+		return int(n)
+	}
+	return int(syngit.DefaultMaxConcurrentPushes)
+}
+
+// RunConcurrently calls run for i from 0 to n-1, at most MaxConcurrentPushes
+// of syncer at a time, and waits for all of them.
+func RunConcurrently(syncer interceptor.SyncerContext, n int, run func(i int)) {
+	workers := make(chan struct{}, MaxConcurrentPushes(syncer))
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+			run(i)
+		}()
+	}
+	wg.Wait()
+}
+
+// targetRun is the push of a change to one RemoteTarget.
+type targetRun struct {
+	params   interceptor.GitPipelineParams
+	response interceptor.GitPushResponse
+	err      error
+	// pushed is set when a new commit was pushed straight to the branch of
+	// the target, which is what a rollback undoes.
+	pushed bool
+}
+
+// pushGroupKey groups the RemoteTargets whose branches are pushed together:
+// the branches of one repository, pushed with one credential.
+type pushGroupKey struct {
+	gitUser    interceptor.GitUserInfo
+	repository string
+}
+
+// RunGitPipelines pushes a change to several RemoteTargets of a syncer, and
+// returns the response of each of them in their order. The branches of a same
+// repository are committed under their own lease and pushed in a single push;
+// the repositories are pushed concurrently.
+//
+// Every target is tried, even once one has failed. The error names each
+// target that failed. When the syncer makes the MultipleTarget strategy
+// atomic, the commits already pushed to the other targets are then undone.
+func RunGitPipelines(ctx context.Context, cluster client.Reader, targets []interceptor.GitPipelineParams) ([]interceptor.GitPushResponse, error) {
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	runs := make([]*targetRun, len(targets))
+	for i, params := range targets {
+		runs[i] = &targetRun{
+			params:   params,
+			response: ResponseBuilder(make([]string, 0), "", params.RemoteTarget.Spec.TargetRepository),
+		}
+	}
+
+	groups := groupTargets(runs)
+	RunConcurrently(targets[0].Syncer, len(groups), func(i int) {
+		runGroup(ctx, cluster, groups[i])
+	})
+
+	responses := make([]interceptor.GitPushResponse, len(runs))
+	var errs []error
+	for i, run := range runs {
+		responses[i] = run.response
+		if run.err != nil {
+			errs = append(errs, fmt.Errorf("%s (branch %s): %w",
+				run.params.RemoteTarget.Spec.TargetRepository, run.params.RemoteTarget.Spec.TargetBranch, run.err))
+		}
+	}
+	if len(errs) == 0 {
+		return responses, nil
+	}
+
+	if mode := targets[0].Syncer.Spec.MultipleTarget.Atomic; mode != "" {
+		errs = append(errs, undoPushes(ctx, runs, mode))
+	}
+	return responses, errors.Join(errs...)
+}
+
+// groupTargets groups the runs whose branches can be pushed together. Each
+// group is sorted by branch, so that two groups of the same repository take
+// the leases of their clones in the same order.
+func groupTargets(runs []*targetRun) [][]*targetRun {
+	var groups [][]*targetRun
+	groupIndexes := map[pushGroupKey]int{}
+	for _, run := range runs {
+		if !isGroupable(run.params) {
+			groups = append(groups, []*targetRun{run})
+			continue
+		}
+		key := pushGroupKey{gitUser: run.params.GitUserInfo, repository: run.params.RemoteTarget.Spec.TargetRepository}
+		i, found := groupIndexes[key]
+		if !found {
+			groupIndexes[key] = len(groups)
+			groups = append(groups, []*targetRun{run})
+			continue
+		}
+		if hasBranch(groups[i], run.params.RemoteTarget.Spec.TargetBranch) {
+			// The same branch cannot be pushed twice in one push.
+			groups = append(groups, []*targetRun{run})
+			continue
+		}
+		groups[i] = append(groups[i], run)
+	}
+
+	for _, group := range groups {
+		slices.SortFunc(group, func(a, b *targetRun) int {
+			return strings.Compare(a.params.RemoteTarget.Spec.TargetBranch, b.params.RemoteTarget.Spec.TargetBranch)
+		})
+	}
+	return groups
+}
+
+// isGroupable reports whether the change of params can be pushed together
+// with the ones of the other branches of its repository: its commit goes
+// straight to the branch it is cloned from, and its clone shares its object
+// store with the clones of the other branches.
+func isGroupable(params interceptor.GitPipelineParams) bool {
+	spec := params.RemoteTarget.Spec
+	return repoCache != nil &&
+		spec.MergeStrategy == "" &&
+		spec.UpstreamRepository == spec.TargetRepository &&
+		spec.UpstreamBranch == spec.TargetBranch &&
+		!isChangeRequestDelivery(params) &&
+		batchWindow(params) == 0
+}
+
+func hasBranch(group []*targetRun, branch string) bool {
+	for _, run := range group {
+		if run.params.RemoteTarget.Spec.TargetBranch == branch {
+			return true
+		}
+	}
+	return false
+}
+
+// runGroup pushes the change to the targets of group. When the branches of
+// the group cannot be pushed together, because one of them moved, cannot be
+// cloned, or their commits do not share an object store, each is pushed on its
+// own instead.
+func runGroup(ctx context.Context, cluster client.Reader, group []*targetRun) {
+	if len(group) == 1 {
+		group[0].runAlone(ctx, cluster)
+		return
+	}
+
+	err := pushGroup(ctx, cluster, group)
+	if err == nil {
+		return
+	}
+	if errors.Is(err, syngiterrors.ErrGitNonFastForward) ||
+		errors.Is(err, errUnsharedCommit) ||
+		errors.Is(err, errGroupUnavailable) {
+		for _, run := range group {
+			if run.err == nil {
+				run.runAlone(ctx, cluster)
+			}
+		}
+		return
+	}
+	for _, run := range group {
+		if run.err == nil {
+			run.response = ResponseBuilder(make([]string, 0), "", run.params.RemoteTarget.Spec.TargetRepository)
+			run.err = err
+		}
+	}
+}
+
+// runAlone pushes the change to the target of run through the pipeline of a
+// single target.
+func (r *targetRun) runAlone(ctx context.Context, cluster client.Reader) {
+	if window := batchWindow(r.params); window > 0 {
+		// The batch may hold the changes of other requests: it is not undone.
+		r.response, r.err = runBatchedGitPipeline(ctx, cluster, r.params, window)
+		return
+	}
+	responses, pushed, err := runGitPipeline(ctx, cluster, []interceptor.GitPipelineParams{r.params})
+	r.response, r.err = responses[0], err
+	r.pushed = pushed && err == nil && !isChangeRequestDelivery(r.params)
+}
+
+// pushGroup commits the change on each branch of group, in the clone of the
+// branch, then pushes every commit in a single push out of the first clone.
+// The clones of the branches share their objects, so that the first one holds
+// the commits of the others.
+//
+// A target whose commit cannot be built fails on its own; the push of the
+// others fails them all.
+func pushGroup(ctx context.Context, cluster client.Reader, group []*targetRun) error {
+	ctx, cancel := pipelineContext(ctx)
+	defer cancel()
+
+	// The leases are held until the commits are pushed out of the first
+	// clone, and given back as soon as one of them cannot be taken.
+	clones := make([]*git.Repository, 0, len(group))
+	releases := make([]func(), 0, len(group))
+	releaseAll := func() {
+		for _, release := range releases {
+			release()
+		}
+		releases = nil
+	}
+	defer func() { releaseAll() }()
+	for _, run := range group {
+		repository, _, release, err := getRepositories(ctx, run.params)
+		if err != nil {
+			releaseAll()
+			return fmt.Errorf("%w: %w", errGroupUnavailable, err)
+		}
+		releases = append(releases, release)
+		clones = append(clones, repository)
+	}
+
+	var refSpecs []config.RefSpec
+	changePaths := make([]interceptor.ClaimedPaths, len(group))
+	changed := make([]bool, len(group))
+	for i, run := range group {
+		paths, commitHash, commitChanged, needForcePush, err := buildCommit(
+			ctx, cluster, []interceptor.GitPipelineParams{run.params}, clones[i], clones[i],
+		)
+		if err != nil {
+			run.err = err
+			continue
+		}
+		run.response = ResponseBuilder(GetPathsFromClaimedPaths(paths[0]), commitHash, run.params.RemoteTarget.Spec.TargetRepository)
+		if !commitChanged {
+			continue
+		}
+		if _, err := clones[0].CommitObject(plumbing.NewHash(commitHash)); err != nil {
+			return errUnsharedCommit
+		}
+
+		refSpecPrefix := ""
+		if needForcePush {
+			refSpecPrefix = "+"
+		}
+		refSpecs = append(refSpecs, config.RefSpec(fmt.Sprintf("%s%s:refs/heads/%s",
+			refSpecPrefix, commitHash, run.params.RemoteTarget.Spec.TargetBranch)))
+		changePaths[i] = paths[0]
+		changed[i] = true
+	}
+	if len(refSpecs) == 0 {
+		return nil
+	}
+
+	if err := pushRefSpecs(ctx, group[0].params, clones[0], refSpecs, false, nil); err != nil {
+		return err
+	}
+
+	for i, run := range group {
+		if changed[i] {
+			run.pushed = true
+			setDiffPreview(run.params, clones[i], &run.response, changePaths[i])
+		}
+	}
+	return nil
+}
+
+// undoPushes undoes the commits pushed by runs, according to mode. The runs
+// that failed pushed nothing, and the commits pushed to the branch of a change
+// request or as part of a batch are left as they are.
+func undoPushes(ctx context.Context, runs []*targetRun, mode syngit.AtomicMode) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, run := range runs {
+		if !run.pushed {
+			continue
+		}
+		// undoPush steps its own copy of the backoff: each target has its
+		// own budget of retries.
+		if err := undoPush(ctx, run.params, plumbing.NewHash(run.response.CommitHash), mode, nonFastForwardBackoff); err != nil {
+			errs = append(errs, fmt.Errorf("failed to undo the push of %s to %s (branch %s): %w",
+				run.response.CommitHash,
+				run.params.RemoteTarget.Spec.TargetRepository,
+				run.params.RemoteTarget.Spec.TargetBranch,
+				err,
+			))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// undoPush undoes the commit pushed to the branch of the target of params,
+// either by pushing a commit that reverts it or by force-pushing the branch
+// back to its parent. A revert rejected as non-fast-forward is committed again
+// on top of the new tip, for as many steps as backoff allows.
+func undoPush(
+	ctx context.Context,
+	params interceptor.GitPipelineParams,
+	pushed plumbing.Hash,
+	mode syngit.AtomicMode,
+	backoff wait.Backoff,
+) error {
+	repository, release, err := GetTargetRepository(ctx, params)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if mode == syngit.ForceResetAtomicMode {
+		worktree, tip, err := checkoutTargetBranch(ctx, params, repository)
+		if err != nil {
+			return err
+		}
+		return forceReset(ctx, params, repository, worktree, tip, pushed)
+	}
+
+	for {
+		worktree, _, err := checkoutTargetBranch(ctx, params, repository)
+		if err != nil {
+			return err
+		}
+		err = revertCommit(ctx, params, repository, worktree, pushed)
+		if !errors.Is(err, syngiterrors.ErrGitNonFastForward) || backoff.Steps <= 0 {
+			return err
+		}
+
+		// Another writer pushed on top of the commit: revert it on top of
+		// the new tip.
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff.Step()):
+		}
+	}
+}
+
+// checkoutTargetBranch fetches the target branch of params and checks it out,
+// at the commit it is on in the target repository, which it returns.
+func checkoutTargetBranch(ctx context.Context, params interceptor.GitPipelineParams, repository *git.Repository) (*git.Worktree, plumbing.Hash, error) {
+	repositoryParams := targetRepositoryParams(params)
+	branch := params.RemoteTarget.Spec.TargetBranch
+	branchRef := plumbing.NewBranchReferenceName(branch)
+
+	tip, err := fetchTargetBranch(ctx, params, repository)
+	if err != nil {
+		return nil, plumbing.ZeroHash, err
+	}
+	if tip.IsZero() {
+		return nil, plumbing.ZeroHash, fmt.Errorf("the branch %s does not exist anymore", branch)
+	}
+	if err := repository.Storer.SetReference(plumbing.NewHashReference(branchRef, tip)); err != nil {
+		return nil, plumbing.ZeroHash, fmt.Errorf("failed to reset branch %s: %w", branchRef.String(), err)
+	}
+
+	worktree, err := repository.Worktree()
+	if err != nil {
+		return nil, plumbing.ZeroHash, fmt.Errorf("failed to get worktree: %w", err)
+	}
+	if err := worktree.Checkout(&git.CheckoutOptions{
+		Branch:                    branchRef,
+		Force:                     true,
+		SparseCheckoutDirectories: repositoryParams.sparseDirs(),
+	}); err != nil {
+		return nil, plumbing.ZeroHash, fmt.Errorf("failed to checkout branch %s: %w", branch, err)
+	}
+	resetOptions := &git.ResetOptions{Commit: tip, Mode: git.HardReset}
+	if err := worktree.ResetSparsely(resetOptions, repositoryParams.sparseDirs()); err != nil {
+		return nil, plumbing.ZeroHash, fmt.Errorf("failed to hard reset: %w", err)
+	}
+
+	return worktree, tip, nil
+}
+
+// forceReset force-pushes the target branch of params back to the parent of
+// pushed. The branch is left as it is when it is not on pushed anymore: the
+// commits pushed on top of it since would be lost.
+func forceReset(
+	ctx context.Context,
+	params interceptor.GitPipelineParams,
+	repository *git.Repository,
+	worktree *git.Worktree,
+	tip, pushed plumbing.Hash,
+) error {
+	if tip != pushed {
+		return fmt.Errorf("the branch has moved to %s since the push: it is left as it is", tip)
+	}
+	commit, err := repository.CommitObject(pushed)
+	if err != nil {
+		return fmt.Errorf("failed to find the pushed commit: %w", err)
+	}
+	if commit.NumParents() == 0 {
+		return fmt.Errorf("the pushed commit has no parent to reset the branch to")
+	}
+
+	resetOptions := &git.ResetOptions{Commit: commit.ParentHashes[0], Mode: git.HardReset}
+	if err := worktree.ResetSparsely(resetOptions, targetRepositoryParams(params).sparseDirs()); err != nil {
+		return fmt.Errorf("failed to hard reset: %w", err)
+	}
+
+	branchRef := plumbing.NewBranchReferenceName(params.RemoteTarget.Spec.TargetBranch)
+	refSpec := config.RefSpec(fmt.Sprintf("+%s:%s", branchRef, branchRef))
+	err = pushRefSpecs(ctx, params, repository, []config.RefSpec{refSpec}, false,
+		&git.ForceWithLease{RefName: branchRef, Hash: pushed})
+	if errors.Is(err, syngiterrors.ErrGitNonFastForward) {
+		return fmt.Errorf("the branch has moved since the push: it is left as it is: %w", err)
+	}
+	return err
+}
+
+// revertCommit commits, on top of the checked out target branch of params,
+// the files changed by pushed as they were before it, and pushes the commit.
+// A file that has been changed again since pushed is not reverted, and fails
+// the revert as a conflict.
+func revertCommit(
+	ctx context.Context,
+	params interceptor.GitPipelineParams,
+	repository *git.Repository,
+	worktree *git.Worktree,
+	pushed plumbing.Hash,
+) error {
+	commit, err := repository.CommitObject(pushed)
+	if err != nil {
+		return fmt.Errorf("failed to find the pushed commit: %w", err)
+	}
+	if commit.NumParents() == 0 {
+		return fmt.Errorf("the pushed commit has no parent to revert to")
+	}
+	parent, err := commit.Parent(0)
+	if err != nil {
+		return fmt.Errorf("failed to find the parent of the pushed commit: %w", err)
+	}
+	pushedTree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+	parentTree, err := parent.Tree()
+	if err != nil {
+		return err
+	}
+	tipTree, err := headTree(repository)
+	if err != nil {
+		return err
+	}
+
+	changes, err := object.DiffTree(parentTree, pushedTree)
+	if err != nil {
+		return fmt.Errorf("failed to diff the pushed commit: %w", err)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	for _, change := range changes {
+		name := change.To.Name
+		if name == "" {
+			name = change.From.Name
+		}
+		if entryHash(tipTree, name) != change.To.TreeEntry.Hash {
+			return fmt.Errorf("%s has been changed again since the push: it cannot be reverted", name)
+		}
+
+		if change.From.Name == "" {
+			if _, err := worktree.Remove(name); err != nil {
+				return fmt.Errorf("failed to delete file in staging area: %v", err)
+			}
+			continue
+		}
+		if err := restoreFile(worktree, parentTree, name); err != nil {
+			return err
+		}
+		if _, err := worktree.Add(name); err != nil {
+			return fmt.Errorf("failed to add file to staging area: %v", err)
+		}
+	}
+
+	subject, _, _ := strings.Cut(commit.Message, "\n")
+	message := fmt.Sprintf("Revert \"%s\"\n\nThis reverts commit %s.\n", subject, pushed)
+	if _, err := commitSigned(params, worktree, message, git.CommitOptions{}); err != nil {
+		return fmt.Errorf("failed to commit the revert: %v", err)
+	}
+
+	return Push(ctx, params, repository, false)
+}
+
+// entryHash returns the hash of the file name in tree, or the zero hash when
+// tree does not hold it.
+func entryHash(tree *object.Tree, name string) plumbing.Hash {
+	if tree == nil {
+		return plumbing.ZeroHash
+	}
+	entry, err := tree.FindEntry(name)
+	if err != nil {
+		return plumbing.ZeroHash
+	}
+	return entry.Hash
+}
+
+// restoreFile writes the content the file name has in tree to the worktree.
+func restoreFile(worktree *git.Worktree, tree *object.Tree, name string) error {
+	file, err := tree.File(name)
+	if err != nil {
+		return fmt.Errorf("failed to find %s: %w", name, err)
+	}
+	content, err := file.Contents()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	out, err := worktree.Filesystem.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to restore %s: %w", name, err)
+	}
+	if _, err := out.Write([]byte(content)); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("failed to restore %s: %w", name, err)
+	}
+	return out.Close()
+}
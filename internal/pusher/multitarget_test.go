@@ -0,0 +1,217 @@
+package pusher
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/storage/memory"
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// multipleTargetParams returns the params of a ConfigMap pushed to the branch
+// of repository.
+func multipleTargetParams(repository, branch string) interceptor.GitPipelineParams {
+	params := interceptor.GitPipelineParams{
+		InterceptedGVR:  schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+		InterceptedName: "app",
+		InterceptedYAML: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n  namespace: default\n",
+		GitUserInfo:     interceptor.GitUserInfo{User: "test", Email: "test@syngit.io"},
+		Operation:       "CREATE",
+	}
+	params.Syncer.InterceptedNamespace = "default"
+	params.RemoteTarget.Spec = syngit.RemoteTargetSpec{
+		UpstreamRepository: repository,
+		UpstreamBranch:     branch,
+		TargetRepository:   repository,
+		TargetBranch:       branch,
+	}
+	return params
+}
+
+// branchRemote returns a seeded remote with a dev branch on the seed commit.
+func branchRemote(t *testing.T) string {
+	t.Helper()
+
+	remote := seededRemote(t)
+	clone, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{URL: remote})
+	if err != nil {
+		t.Fatalf("failed to clone the remote: %v", err)
+	}
+	err = clone.Push(&git.PushOptions{RefSpecs: []config.RefSpec{"refs/heads/master:refs/heads/dev"}})
+	if err != nil {
+		t.Fatalf("failed to push the dev branch: %v", err)
+	}
+	return remote
+}
+
+// remoteBranch returns the commit the branch of remote is on.
+func remoteBranch(t *testing.T, remote, branch string) plumbing.Hash {
+	t.Helper()
+
+	repository, err := git.PlainOpen(remote)
+	if err != nil {
+		t.Fatalf("failed to open the remote: %v", err)
+	}
+	ref, err := repository.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		t.Fatalf("failed to resolve %s: %v", branch, err)
+	}
+	return ref.Hash()
+}
+
+func TestGroupTargets(t *testing.T) {
+	InitRepoCache(4)
+	defer InitRepoCache(0)
+
+	changeRequest := multipleTargetParams("repo", "staging")
+	changeRequest.RemoteTarget.Spec.DeliveryMode = syngit.ChangeRequestDelivery
+	other := multipleTargetParams("repo", "main")
+	other.GitUserInfo.User = "other"
+
+	runs := []*targetRun{
+		{params: multipleTargetParams("repo", "main")},
+		{params: multipleTargetParams("other-repo", "main")},
+		{params: multipleTargetParams("repo", "dev")},
+		{params: changeRequest},
+		{params: other},
+		{params: multipleTargetParams("repo", "dev")},
+	}
+	groups := groupTargets(runs)
+
+	var got []string
+	for _, group := range groups {
+		var targets []string
+		for _, run := range group {
+			targets = append(targets, run.params.RemoteTarget.Spec.TargetRepository+"#"+run.params.RemoteTarget.Spec.TargetBranch)
+		}
+		got = append(got, strings.Join(targets, ","))
+	}
+	want := []string{"repo#dev,repo#main", "other-repo#main", "repo#staging", "repo#main", "repo#dev"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("groupTargets() = %v, want %v", got, want)
+	}
+}
+
+func TestRunGitPipelines_branchesOfOneRepository(t *testing.T) {
+	InitRepoCache(4)
+	defer InitRepoCache(0)
+	remote := branchRemote(t)
+
+	targets := []interceptor.GitPipelineParams{
+		multipleTargetParams(remote, "master"),
+		multipleTargetParams(remote, "dev"),
+	}
+	responses, err := RunGitPipelines(context.Background(), nil, targets)
+	if err != nil {
+		t.Fatalf("RunGitPipelines: %v", err)
+	}
+
+	for i, branch := range []string{"master", "dev"} {
+		if tip := remoteBranch(t, remote, branch); tip.String() != responses[i].CommitHash {
+			t.Errorf("%s is at %s, want the pushed commit %s", branch, tip, responses[i].CommitHash)
+		}
+		if len(responses[i].Paths) != 1 {
+			t.Errorf("the response of %s has the paths %v", branch, responses[i].Paths)
+		}
+	}
+}
+
+func TestRunGitPipelines_branchThatCannotBeCloned(t *testing.T) {
+	InitRepoCache(4)
+	defer InitRepoCache(0)
+	fastBackoff(t)
+	url, alice, _ := privateRepository(t)
+
+	main := multipleTargetParams(url, "main")
+	main.GitUserInfo = alice
+	// The clone of the second branch goes through a proxy that is down.
+	unreachable := multipleTargetParams(url, "release")
+	unreachable.GitUserInfo = alice
+	unreachable.Transport = interceptor.GitTransport{ProxyURL: "http://127.0.0.1:1"}
+	targets := []interceptor.GitPipelineParams{main, unreachable}
+	if groups := groupTargets([]*targetRun{{params: main}, {params: unreachable}}); len(groups) != 1 {
+		t.Fatalf("the branches of the repository must be pushed together, got %d groups", len(groups))
+	}
+
+	responses, err := RunGitPipelines(context.Background(), nil, targets)
+	if err == nil {
+		t.Fatal("the push through the proxy that is down must fail")
+	}
+	if strings.Contains(err.Error(), "(branch main)") || !strings.Contains(err.Error(), "(branch release)") {
+		t.Errorf("only the branch that cannot be cloned must fail: %v", err)
+	}
+	if responses[0].CommitHash == "" {
+		t.Errorf("main must be pushed on its own, got the response %+v", responses[0])
+	}
+
+	// The leases of the group have been given back.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, release, err := GetTargetRepository(ctx, main)
+	if err != nil {
+		t.Fatalf("the lease of main must be free: %v", err)
+	}
+	release()
+}
+
+func TestRunGitPipelines_atomic(t *testing.T) {
+	for _, mode := range []syngit.AtomicMode{syngit.RevertAtomicMode, syngit.ForceResetAtomicMode} {
+		t.Run(string(mode), func(t *testing.T) {
+			remote := seededRemote(t)
+			seed := remoteBranch(t, remote, "master")
+
+			pushed := multipleTargetParams(remote, "master")
+			failing := multipleTargetParams(filepath.Join(t.TempDir(), "missing"), "master")
+			for _, params := range []*interceptor.GitPipelineParams{&pushed, &failing} {
+				params.Syncer.Spec.MultipleTarget.Atomic = mode
+			}
+
+			responses, err := RunGitPipelines(context.Background(), nil, []interceptor.GitPipelineParams{pushed, failing})
+			if err == nil {
+				t.Fatal("a push to a missing repository must fail")
+			}
+			if !strings.Contains(err.Error(), "missing (branch master)") {
+				t.Errorf("the error must name the target that failed: %v", err)
+			}
+			if strings.Contains(err.Error(), "failed to undo") {
+				t.Fatalf("the push must be undone: %v", err)
+			}
+
+			tip := remoteBranch(t, remote, "master")
+			if mode == syngit.ForceResetAtomicMode {
+				if tip != seed {
+					t.Errorf("the branch must be reset to %s, it is on %s", seed, tip)
+				}
+				return
+			}
+
+			repository, _ := git.PlainOpen(remote)
+			revert, err := repository.CommitObject(tip)
+			if err != nil {
+				t.Fatalf("failed to read the tip: %v", err)
+			}
+			if revert.NumParents() != 1 || revert.ParentHashes[0].String() != responses[0].CommitHash {
+				t.Fatalf("the revert must be committed on top of the pushed commit %s", responses[0].CommitHash)
+			}
+			if !strings.HasPrefix(revert.Message, "Revert \"") {
+				t.Errorf("unexpected revert message %q", revert.Message)
+			}
+			seedCommit, _ := repository.CommitObject(seed)
+			revertTree, _ := revert.Tree()
+			seedTree, _ := seedCommit.Tree()
+			if revertTree.Hash != seedTree.Hash {
+				t.Errorf("the revert must bring the files back to the seed")
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package pusher
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	gitcache "github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/go-git/go-git/v5/storage"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+const (
+	// objectsDirPattern names the directories of the object stores on disk.
+	objectsDirPattern = "objects-*"
+	// haveRefPrefix holds the references that tell the git server which
+	// commits a new clone already finds in its object store.
+	haveRefPrefix = "refs/syngit/haves/"
+	// maxHaves bounds the number of branch tips advertised to the git server.
+	maxHaves = 32
+)
+
+// objectStore is the object database shared by the clones of one repository
+// URL, whatever their branch: the history common to the branches is stored
+// once. The clones keep their own references, index, configuration and
+// worktree on top of it.
+//
+// The clones of a store all have the same depth, so that the shallow commits
+// describe the store as a whole: they are shared too.
+type objectStore struct {
+	key string
+	dir string // "" when in memory
+
+	// mu serializes the accesses to the storage, which is not safe for
+	// concurrent use, since the clones of different branches are leased
+	// independently.
+	mu      sync.Mutex
+	objects storer.EncodedObjectStorer
+	shallow storer.ShallowStorer
+	packs   storer.PackfileWriter // nil when the storage has no packfiles
+	// tips are the last known commits of the branches, advertised as haves
+	// when a new branch is cloned. Any commit of the store would do.
+	tips map[string]plumbing.Hash
+
+	users int // guarded by objectStoresMu
+}
+
+var (
+	objectStoresMu sync.Mutex
+	// The object stores in use, by repository URL and depth.
+	objectStores = map[string]*objectStore{}
+)
+
+// acquireObjectStore returns the object store of the clones of url at depth,
+// creating it if no clone uses it yet. It must be released once the clone is
+// removed.
+func acquireObjectStore(url string, depth int) (*objectStore, error) {
+	key := fmt.Sprintf("%s@depth=%d", url, depth)
+
+	objectStoresMu.Lock()
+	defer objectStoresMu.Unlock()
+
+	if store, found := objectStores[key]; found {
+		store.users++
+		return store, nil
+	}
+
+	store := &objectStore{key: key, tips: map[string]plumbing.Hash{}, users: 1}
+	if repoCacheOptions.Dir == "" {
+		objects := memory.NewStorage()
+		store.objects, store.shallow = objects, objects
+	} else {
+		dir, err := os.MkdirTemp(repoCacheOptions.Dir, objectsDirPattern)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the object store directory: %w", err)
+		}
+		objects := filesystem.NewStorage(osfs.New(dir), gitcache.NewObjectLRUDefault())
+		if err := objects.Init(); err != nil {
+			_ = os.RemoveAll(dir)
+			return nil, fmt.Errorf("failed to init the object store: %w", err)
+		}
+		store.dir = dir
+		store.objects, store.shallow, store.packs = objects, objects, objects
+	}
+	objectStores[key] = store
+	return store, nil
+}
+
+// release drops a user of the store, and removes the store with its last user.
+func (s *objectStore) release() {
+	objectStoresMu.Lock()
+	defer objectStoresMu.Unlock()
+
+	s.users--
+	if s.users > 0 {
+		return
+	}
+	if objectStores[s.key] == s {
+		delete(objectStores, s.key)
+	}
+	removeRepositoryDir(s.dir)
+}
+
+// share returns the part of the size of the store that a single user accounts
+// for.
+func (s *objectStore) share() (int64, error) {
+	objectStoresMu.Lock()
+	users := max(s.users, 1)
+	objectStoresMu.Unlock()
+
+	size, err := s.size()
+	return size / int64(users), err
+}
+
+func (s *objectStore) size() (int64, error) {
+	if s.dir != "" {
+		return dirSize(s.dir)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	objects, err := s.objects.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	err = objects.ForEach(func(o plumbing.EncodedObject) error {
+		size += o.Size()
+		return nil
+	})
+	return size, err
+}
+
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// setTip records the commit a clone of the store is at.
+func (s *objectStore) setTip(cacheKey string, tip plumbing.Hash) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, found := s.tips[cacheKey]; !found && len(s.tips) >= maxHaves {
+		return
+	}
+	s.tips[cacheKey] = tip
+}
+
+// seedHaves writes the known tips of the store as references of a new clone,
+// so that the next fetch only downloads what the store misses. They must be
+// removed with removeHaves once the fetch is done.
+func (s *objectStore) seedHaves(references storer.ReferenceStorer) error {
+	s.mu.Lock()
+	tips := make([]plumbing.Hash, 0, len(s.tips))
+	for _, tip := range s.tips {
+		tips = append(tips, tip)
+	}
+	s.mu.Unlock()
+
+	for i, tip := range tips {
+		name := plumbing.ReferenceName(fmt.Sprintf("%s%d", haveRefPrefix, i))
+		if err := references.SetReference(plumbing.NewHashReference(name, tip)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeHaves removes the references written by seedHaves.
+func removeHaves(references storer.ReferenceStorer) error {
+	iter, err := references.IterReferences()
+	if err != nil {
+		return err
+	}
+	var haves []plumbing.ReferenceName
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		if strings.HasPrefix(ref.Name().String(), haveRefPrefix) {
+			haves = append(haves, ref.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, name := range haves {
+		if err := references.RemoveReference(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The methods below implement storer.EncodedObjectStorer and
+// storer.ShallowStorer over the shared storage.
+
+func (s *objectStore) NewEncodedObject() plumbing.EncodedObject {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects.NewEncodedObject()
+}
+
+func (s *objectStore) SetEncodedObject(o plumbing.EncodedObject) (plumbing.Hash, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects.SetEncodedObject(o)
+}
+
+func (s *objectStore) EncodedObject(t plumbing.ObjectType, h plumbing.Hash) (plumbing.EncodedObject, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects.EncodedObject(t, h)
+}
+
+// IterEncodedObjects lists the objects up front, since the iterator of the
+// storage would be used out of the lock.
+func (s *objectStore) IterEncodedObjects(t plumbing.ObjectType) (storer.EncodedObjectIter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	iter, err := s.objects.IterEncodedObjects(t)
+	if err != nil {
+		return nil, err
+	}
+	var objects []plumbing.EncodedObject
+	err = iter.ForEach(func(o plumbing.EncodedObject) error {
+		objects = append(objects, o)
+		return nil
+	})
+	return storer.NewEncodedObjectSliceIter(objects), err
+}
+
+func (s *objectStore) HasEncodedObject(h plumbing.Hash) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects.HasEncodedObject(h)
+}
+
+func (s *objectStore) EncodedObjectSize(h plumbing.Hash) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects.EncodedObjectSize(h)
+}
+
+func (s *objectStore) AddAlternate(remote string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects.AddAlternate(remote)
+}
+
+// SetShallow adds the shallow commits to the ones of the store. A fetch of a
+// branch cannot deepen the commits that another branch made shallow, so they
+// are never removed: the fetches of the different branches then never undo
+// each other.
+func (s *objectStore) SetShallow(commits []plumbing.Hash) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	shallow, err := s.shallow.Shallow()
+	if err != nil {
+		return err
+	}
+	for _, commit := range commits {
+		if !slices.Contains(shallow, commit) {
+			shallow = append(shallow, commit)
+		}
+	}
+	return s.shallow.SetShallow(shallow)
+}
+
+func (s *objectStore) Shallow() ([]plumbing.Hash, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shallow.Shallow()
+}
+
+// packfileWriter receives a packfile into a temporary file, as long as the
+// fetch lasts, and only takes the lock of the store on Close, to index and
+// publish it.
+type packfileWriter struct {
+	*os.File
+	store *objectStore
+}
+
+func (w *packfileWriter) Close() error {
+	defer os.Remove(w.Name()) // nolint:errcheck
+	defer w.File.Close()      // nolint:errcheck
+
+	if _, err := w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	packfile, err := w.store.packs.PackfileWriter()
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(packfile, w.File); err != nil {
+		_ = packfile.Close()
+		return err
+	}
+	return packfile.Close()
+}
+
+func (s *objectStore) packfileWriter() (io.WriteCloser, error) {
+	f, err := os.CreateTemp(s.dir, "incoming-*.pack")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the packfile: %w", err)
+	}
+	return &packfileWriter{File: f, store: s}, nil
+}
+
+// cloneStorage is the storage of a clone: the objects and the shallow commits
+// of the shared store, the rest of its own storage.
+type cloneStorage struct {
+	*objectStore
+	storer.ReferenceStorer
+	storer.IndexStorer
+	config.ConfigStorer
+	storage.ModuleStorer
+}
+
+// packfileCloneStorage is a cloneStorage whose object store takes packfiles as
+// they are fetched, rather than object by object.
+type packfileCloneStorage struct {
+	*cloneStorage
+}
+
+func (s packfileCloneStorage) PackfileWriter() (io.WriteCloser, error) {
+	return s.objectStore.packfileWriter()
+}
+
+// newCloneStorage returns the storage of a clone whose own data is kept in
+// private, and its objects in objects.
+func newCloneStorage(private storage.Storer, objects *objectStore) storage.Storer {
+	clone := &cloneStorage{
+		objectStore:     objects,
+		ReferenceStorer: private,
+		IndexStorer:     private,
+		ConfigStorer:    private,
+		ModuleStorer:    private,
+	}
+	if objects.packs != nil {
+		return packfileCloneStorage{clone}
+	}
+	return clone
+}
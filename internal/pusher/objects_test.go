@@ -0,0 +1,233 @@
+package pusher
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/storage/memory"
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// remoteWithBranches returns a seeded remote with a large file on master and
+// a feature branch that adds a small one on top of it.
+func remoteWithBranches(t *testing.T) string {
+	t.Helper()
+
+	remote := seededRemote(t)
+	clone, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{URL: remote})
+	if err != nil {
+		t.Fatalf("failed to clone the remote: %v", err)
+	}
+	large := make([]byte, 64*1024)
+	_, _ = rand.Read(large)
+	commitFile(t, clone, "large.yaml", "data: "+base64.StdEncoding.EncodeToString(large)+"\n")
+	if err := clone.Push(&git.PushOptions{}); err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+	commitFile(t, clone, "small.yaml", "kind: ConfigMap\n")
+	if err := clone.Push(&git.PushOptions{
+		RefSpecs: []config.RefSpec{"refs/heads/master:refs/heads/feature"},
+	}); err != nil {
+		t.Fatalf("failed to push the feature branch: %v", err)
+	}
+	return remote
+}
+
+func packfilesSize(t *testing.T, store *objectStore) int64 {
+	t.Helper()
+
+	packs, _ := filepath.Glob(filepath.Join(store.dir, "objects", "pack", "*.pack"))
+	var size int64
+	for _, pack := range packs {
+		info, err := os.Stat(pack)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size += info.Size()
+	}
+	return size
+}
+
+func TestObjectStoreSharedAcrossBranches(t *testing.T) {
+	if err := ConfigureRepoCache(RepoCacheOptions{MaxRepos: 2, Dir: t.TempDir()}); err != nil {
+		t.Fatalf("ConfigureRepoCache: %v", err)
+	}
+	defer InitRepoCache(0)
+
+	remote := remoteWithBranches(t)
+	master := GetRepositoryParams{Repository: remote, Branch: "master"}
+	feature := GetRepositoryParams{Repository: remote, Branch: "feature"}
+
+	_, release, err := getRepository(context.Background(), master)
+	if err != nil {
+		t.Fatalf("getRepository(master): %v", err)
+	}
+	release()
+	masterHandle, _ := repoCache.Get(master.cacheKey())
+	store := masterHandle.storage.objects
+	firstClone := packfilesSize(t, store)
+
+	repository, release, err := getRepository(context.Background(), feature)
+	if err != nil {
+		t.Fatalf("getRepository(feature): %v", err)
+	}
+	featureHandle, _ := repoCache.Get(feature.cacheKey())
+	if featureHandle.storage.objects != store {
+		t.Fatalf("the branches of a repository must share their object store")
+	}
+	if added := packfilesSize(t, store) - firstClone; added <= 0 || added > firstClone/10 {
+		t.Errorf("the feature branch downloaded %d bytes on top of the %d of master", added, firstClone)
+	}
+
+	worktree, _ := repository.Worktree()
+	if _, err := worktree.Filesystem.Stat("small.yaml"); err != nil {
+		t.Errorf("the feature branch is not checked out: %v", err)
+	}
+	references, _ := repository.References()
+	_ = references.ForEach(func(ref *plumbing.Reference) error {
+		if strings.HasPrefix(ref.Name().String(), haveRefPrefix) {
+			t.Errorf("the reference %s must be removed after the clone", ref.Name())
+		}
+		return nil
+	})
+	release()
+
+	// The store goes away with the last clone that uses it: the other keys
+	// are used more, so that the two branches are evicted.
+	for _, key := range []string{"a", "b"} {
+		for range 5 {
+			touch(key)
+		}
+	}
+	if _, err := os.Stat(store.dir); !os.IsNotExist(err) {
+		t.Errorf("the object store must be removed with its last clone")
+	}
+	objectStoresMu.Lock()
+	defer objectStoresMu.Unlock()
+	if objectStores[store.key] == store {
+		t.Errorf("the object store is still in use")
+	}
+}
+
+func TestRunGitPipeline_concurrentBranches(t *testing.T) {
+	InitRepoCache(4)
+	defer InitRepoCache(0)
+
+	remote := remoteWithBranches(t)
+	run := func(branch string) error {
+		params := interceptor.GitPipelineParams{
+			InterceptedGVR:  schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+			InterceptedName: branch,
+			InterceptedYAML: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: " + branch + "\n  namespace: default\n",
+			GitUserInfo:     interceptor.GitUserInfo{User: "test", Email: "test@syngit.io"},
+			Operation:       "CREATE",
+		}
+		params.Syncer.InterceptedNamespace = "default"
+		params.RemoteTarget.Spec = syngit.RemoteTargetSpec{
+			UpstreamRepository: remote,
+			UpstreamBranch:     branch,
+			TargetRepository:   remote,
+			TargetBranch:       branch,
+		}
+		_, err := RunGitPipeline(context.Background(), nil, params)
+		return err
+	}
+
+	branches := []string{"master", "feature", "master", "feature"}
+	errs := make([]error, len(branches))
+	var wg sync.WaitGroup
+	for i, branch := range branches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = run(branch)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("RunGitPipeline(%s): %v", branches[i], err)
+		}
+	}
+
+	repository, _ := git.PlainOpen(remote)
+	for _, branch := range []string{"master", "feature"} {
+		ref, err := repository.Reference(plumbing.NewBranchReferenceName(branch), true)
+		if err != nil {
+			t.Fatalf("failed to resolve %s: %v", branch, err)
+		}
+		commit, _ := repository.CommitObject(ref.Hash())
+		tree, _ := commit.Tree()
+		if _, err := tree.File("default/v1/configmaps/" + branch + ".yaml"); err != nil {
+			t.Errorf("the object was not pushed to %s: %v", branch, err)
+		}
+	}
+}
+
+func TestObjectStorePackfileWriterLocksOnlyToPublish(t *testing.T) {
+	if err := ConfigureRepoCache(RepoCacheOptions{MaxRepos: 2, Dir: t.TempDir()}); err != nil {
+		t.Fatalf("ConfigureRepoCache: %v", err)
+	}
+	defer InitRepoCache(0)
+
+	source, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{URL: seededRemote(t)})
+	if err != nil {
+		t.Fatalf("failed to clone the remote: %v", err)
+	}
+	head, err := source.Head()
+	if err != nil {
+		t.Fatalf("Head: %v", err)
+	}
+	objects, err := source.Storer.IterEncodedObjects(plumbing.AnyObject)
+	if err != nil {
+		t.Fatalf("IterEncodedObjects: %v", err)
+	}
+	var hashes []plumbing.Hash
+	_ = objects.ForEach(func(o plumbing.EncodedObject) error {
+		hashes = append(hashes, o.Hash())
+		return nil
+	})
+
+	store, err := acquireObjectStore("https://git.example.com/packfile.git", 1)
+	if err != nil {
+		t.Fatalf("acquireObjectStore: %v", err)
+	}
+	defer store.release()
+
+	w, err := store.packfileWriter()
+	if err != nil {
+		t.Fatalf("packfileWriter: %v", err)
+	}
+	if _, err := packfile.NewEncoder(w, source.Storer, false).Encode(hashes, 10); err != nil {
+		t.Fatalf("failed to write the packfile: %v", err)
+	}
+	// The packfile is still being received: the other clones of the store
+	// must not wait for it.
+	if !store.mu.TryLock() {
+		t.Fatalf("the store is locked while the packfile is received")
+	}
+	store.mu.Unlock()
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := store.HasEncodedObject(head.Hash()); err != nil {
+		t.Errorf("the objects of the packfile are not in the store: %v", err)
+	}
+	if incoming, _ := filepath.Glob(filepath.Join(store.dir, "incoming-*")); len(incoming) > 0 {
+		t.Errorf("the received packfile %v must be removed once published", incoming)
+	}
+}
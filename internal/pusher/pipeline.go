@@ -2,72 +2,245 @@ package pusher
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/go-git/go-git/v5"
 	"github.com/syngit-org/syngit/internal/mutator"
 	syngiterrors "github.com/syngit-org/syngit/pkg/errors"
 	"github.com/syngit-org/syngit/pkg/interceptor"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// nonFastForwardBackoff bounds how many times a commit is rebuilt on top of a
+// branch that moved while it was being pushed. The jitter spreads the retries
+// of syncers that lost the same race, so they do not collide again.
+var nonFastForwardBackoff = wait.Backoff{
+	Steps:    4,
+	Duration: 200 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.5,
+}
+
 func RunGitPipeline(ctx context.Context, cluster client.Reader, params interceptor.GitPipelineParams) (interceptor.GitPushResponse, error) {
+	if window := batchWindow(params); window > 0 {
+		return runBatchedGitPipeline(ctx, cluster, params, window)
+	}
+	responses, _, err := runGitPipeline(ctx, cluster, []interceptor.GitPipelineParams{params})
+	return responses[0], err
+}
+
+// runGitPipeline commits the changes in a single commit and pushes it. The
+// changes share their syncer, their user and their RemoteTarget: the first one
+// gives the repository, the branch and the credentials. It returns one
+// response per change, with the paths that change claimed, and reports whether
+// a new commit was pushed.
+//
+// The deadline of ctx is split between the stages of the pipeline, and the
+// lease of the repository is released as soon as it passes.
+func runGitPipeline(ctx context.Context, cluster client.Reader, changes []interceptor.GitPipelineParams) ([]interceptor.GitPushResponse, bool, error) {
+	ctx, cancel := pipelineContext(ctx)
+	defer cancel()
+
+	params := changes[0]
 	emptyPaths := make([]string, 0)
+	responses := func(changePaths []interceptor.ClaimedPaths, commitHash string) []interceptor.GitPushResponse {
+		responses := make([]interceptor.GitPushResponse, len(changes))
+		for i := range changes {
+			paths := emptyPaths
+			if i < len(changePaths) {
+				paths = GetPathsFromClaimedPaths(changePaths[i])
+			}
+			responses[i] = ResponseBuilder(paths, commitHash, params.RemoteTarget.Spec.TargetRepository)
+		}
+		return responses
+	}
 
-	// Get the targeted repository. The lease is held until the pipeline finishes
-	// because the repository is mutated through fetch, checkout, commit and push.
-	targetRepository, releaseTarget, err := GetTargetRepository(params)
+	targetRepository, upstreamRepository, release, err := getRepositories(ctx, params)
 	if err != nil {
-		return ResponseBuilder(emptyPaths, "", params.RemoteTarget.Spec.TargetRepository), err
+		return responses(nil, ""), false, err
 	}
-	defer releaseTarget()
+	defer release()
 
-	// By default, set the upstream repo the same as the target repo
-	// Considering the target branch to be the same as the upstream one
-	upstreamRepository := targetRepository
+	backoff := nonFastForwardBackoff
+	for {
+		changePaths, commitHash, changed, err := commitAndPush(ctx, cluster, changes, targetRepository, upstreamRepository)
+		if err == nil {
+			responses := responses(changePaths, commitHash)
+			if changed {
+				for i := range responses {
+					setDiffPreview(params, targetRepository, &responses[i], changePaths[i])
+				}
+			}
+			if isChangeRequestDelivery(params) {
+				// Changes are not batched in this delivery mode: the branch
+				// of the change request is the one of the object.
+				responses[0].ChangeRequestURL, err = openChangeRequest(ctx, params, changePaths[0], commitHash, changed)
+				if err != nil {
+					err = syngiterrors.NewGitPipeline(err.Error())
+				}
+			}
+			return responses, changed, err
+		}
+		if !errors.Is(err, syngiterrors.ErrGitNonFastForward) || backoff.Steps <= 0 {
+			return responses(changePaths, commitHash), false, err
+		}
 
-	// If a merge strategy is set and the upstream repository is a different repo
-	// than the target, clone it separately. When both point at the same
-	// repository URL (only the branch differs) they share a cache key, so we
-	// reuse the target lease to avoid acquiring the same per-entry lock twice and
-	// deadlocking. This is safe because the merge-strategy paths in GetWorkTree
-	// operate solely on the target repository.
-	if params.RemoteTarget.Spec.MergeStrategy != "" &&
-		params.RemoteTarget.Spec.UpstreamRepository != params.RemoteTarget.Spec.TargetRepository {
-		var releaseUpstream func()
-		upstreamRepository, releaseUpstream, err = GetUpstreamRepository(params)
-		if err != nil {
-			return ResponseBuilder(emptyPaths, "", params.RemoteTarget.Spec.TargetRepository), err
+		// Another writer pushed to the branch after it was fetched. Rather than
+		// blocking the request, start over from the new tip: the intercepted
+		// object is rendered again against the files it now holds.
+		select {
+		case <-ctx.Done():
+			return responses(changePaths, commitHash), false, err
+		case <-time.After(backoff.Step()):
+		}
+
+		refreshCtx, cancelRefresh := stageContext(ctx, repositoryShare)
+		refreshErr := refreshRepository(refreshCtx, targetRepository, targetRepositoryParams(params))
+		cancelRefresh()
+		if refreshErr != nil {
+			return responses(changePaths, ""), false,
+				syngiterrors.NewGitPipeline(fmt.Sprintf("failed to fetch the target branch again after a non-fast-forward push: %v", refreshErr))
 		}
-		defer releaseUpstream()
 	}
+}
 
-	// Pull the worktree
-	worktree, needForcePush, err := GetWorkTree(params, targetRepository, upstreamRepository)
+// commitAndPush builds the commit of the intercepted objects on top of the
+// current state of the target repository and pushes it. It returns the paths
+// claimed by each change and reports whether the commit changed anything: in
+// the ChangeRequest delivery mode, an empty commit is not pushed, since its
+// branch would not differ from the target one.
+func commitAndPush(
+	ctx context.Context,
+	cluster client.Reader,
+	changes []interceptor.GitPipelineParams,
+	targetRepository, upstreamRepository *git.Repository,
+) ([]interceptor.ClaimedPaths, string, bool, error) {
+	params := changes[0]
+	changePaths, commitHash, changed, needForcePush, err := buildCommit(ctx, cluster, changes, targetRepository, upstreamRepository)
 	if err != nil {
-		return ResponseBuilder(emptyPaths, "", params.RemoteTarget.Spec.TargetRepository),
-			syngiterrors.NewGitPipeline(fmt.Sprintf("failed to get worktree: %v", err))
+		return changePaths, "", false, err
+	}
+
+	if !changed && isChangeRequestDelivery(params) {
+		return changePaths, commitHash, false, nil
+	}
+
+	// Push
+	if err := Push(ctx, params, targetRepository, needForcePush); err != nil {
+		return changePaths, commitHash, changed, err
+	}
+
+	return changePaths, commitHash, changed, nil
+}
+
+// buildCommit builds the commit of the intercepted objects on top of the
+// current state of the target repository. It returns the paths claimed by
+// each change and the hash of the commit, and reports whether the commit
+// changed anything and whether it must be force-pushed.
+func buildCommit(
+	ctx context.Context,
+	cluster client.Reader,
+	changes []interceptor.GitPipelineParams,
+	targetRepository, upstreamRepository *git.Repository,
+) ([]interceptor.ClaimedPaths, string, bool, bool, error) {
+	params := changes[0]
+	changePaths := make([]interceptor.ClaimedPaths, len(changes))
+	for i := range changePaths {
+		changePaths[i] = interceptor.NewClaimedPaths()
 	}
 
-	// Pass over the transformers to generate the final worktree
-	var modifiedPaths interceptor.ClaimedPaths
-	worktree, modifiedPaths, err = mutator.GenerateFinalWorktree(ctx, cluster, params, worktree)
+	// Pull the worktree
+	worktreeCtx, cancelWorktree := stageContext(ctx, worktreeShare)
+	worktree, needForcePush, err := GetWorkTree(worktreeCtx, params, targetRepository, upstreamRepository)
+	cancelWorktree()
+	if errors.Is(err, syngiterrors.ErrMergeConflict) {
+		return changePaths, "", false, false, err
+	}
 	if err != nil {
-		return ResponseBuilder(emptyPaths, "", params.RemoteTarget.Spec.TargetRepository),
-			syngiterrors.NewGitPipeline(fmt.Sprintf("failed to generate the worktree: %v", err))
+		return changePaths, "", false, false, syngiterrors.NewGitPipeline(fmt.Sprintf("failed to get worktree: %v", err))
+	}
+
+	// Pass over the transformers to generate the final worktree. The changes
+	// of a batch are rendered in order, each on top of the previous ones.
+	for i, change := range changes {
+		worktree, changePaths[i], err = mutator.GenerateFinalWorktree(ctx, cluster, change, worktree)
+		if err != nil {
+			return changePaths, "", false, false, syngiterrors.NewGitPipeline(fmt.Sprintf("failed to generate the worktree: %v", err))
+		}
+	}
+
+	var parentHash string
+	if head, err := targetRepository.Head(); err == nil {
+		parentHash = head.Hash().String()
 	}
 
 	// Commit
-	commitHash, err := Commit(params, worktree, modifiedPaths, targetRepository)
+	var commitHash string
+	if len(changes) == 1 {
+		commitHash, err = Commit(params, worktree, changePaths[0], targetRepository)
+	} else {
+		commitHash, err = commitBatch(changes, changePaths, worktree, targetRepository)
+	}
 	if err != nil {
-		return ResponseBuilder(GetPathsFromClaimedPaths(modifiedPaths), "", params.RemoteTarget.Spec.TargetRepository),
-			syngiterrors.NewGitPipeline(fmt.Sprintf("failed to generate the commit: %v", err))
+		return changePaths, "", false, false, syngiterrors.NewGitPipeline(fmt.Sprintf("failed to generate the commit: %v", err))
 	}
 
-	// Push
-	err = Push(params, targetRepository, needForcePush)
+	return changePaths, commitHash, commitHash != parentHash, needForcePush, nil
+}
+
+// setDiffPreview sets the diff of the commit of response when the syncer
+// enables the diff preview. The commit is already pushed at this point: a diff
+// that cannot be computed is reported in place of the diff.
+func setDiffPreview(params interceptor.GitPipelineParams, repository *git.Repository, response *interceptor.GitPushResponse, paths interceptor.ClaimedPaths) {
+	if !params.Syncer.Spec.DiffPreview.Enabled {
+		return
+	}
+	diff, err := commitDiffPreview(params, repository, response.CommitHash, paths)
+	if err != nil {
+		diff = fmt.Sprintf("the diff could not be computed: %v\n", err)
+	}
+	response.Diff = diff
+}
+
+// getRepositories returns the target and the upstream repositories of params,
+// within their share of the deadline of ctx. The leases are held until release
+// is called because the repositories are mutated through fetch, checkout,
+// commit and push.
+func getRepositories(ctx context.Context, params interceptor.GitPipelineParams) (*git.Repository, *git.Repository, func(), error) {
+	ctx, cancel := stageContext(ctx, repositoryShare)
+	defer cancel()
+
+	targetRepository, releaseTarget, err := GetTargetRepository(ctx, params)
 	if err != nil {
-		return ResponseBuilder(GetPathsFromClaimedPaths(modifiedPaths), commitHash, params.RemoteTarget.Spec.TargetRepository), err
+		return nil, nil, nil, err
+	}
+
+	// By default, set the upstream repo the same as the target repo
+	// Considering the target branch to be the same as the upstream one
+	upstreamRepository := targetRepository
+
+	// If a merge strategy is set and the upstream repository is a different repo
+	// than the target, clone it separately. When both point at the same
+	// repository URL (only the branch differs) they share a cache key, so we
+	// reuse the target lease to avoid acquiring the same per-entry lock twice and
+	// deadlocking. This is safe because the merge-strategy paths in GetWorkTree
+	// operate solely on the target repository.
+	if params.RemoteTarget.Spec.MergeStrategy != "" &&
+		params.RemoteTarget.Spec.UpstreamRepository != params.RemoteTarget.Spec.TargetRepository {
+		var releaseUpstream func()
+		upstreamRepository, releaseUpstream, err = GetUpstreamRepository(ctx, params)
+		if err != nil {
+			releaseTarget()
+			return nil, nil, nil, err
+		}
+		return targetRepository, upstreamRepository, func() {
+			releaseUpstream()
+			releaseTarget()
+		}, nil
 	}
 
-	return ResponseBuilder(GetPathsFromClaimedPaths(modifiedPaths), commitHash, params.RemoteTarget.Spec.TargetRepository), nil
+	return targetRepository, upstreamRepository, releaseTarget, nil
 }
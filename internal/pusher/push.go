@@ -2,6 +2,8 @@ package pusher
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -9,60 +11,102 @@ import (
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/syngit-org/syngit/pkg/interceptor"
 )
 
-func Push(params interceptor.GitPipelineParams, targetRepository *git.Repository, needForcePush bool) error {
+func Push(ctx context.Context, params interceptor.GitPipelineParams, targetRepository *git.Repository, needForcePush bool) error {
 	targetBranch := params.RemoteTarget.Spec.TargetBranch
+	remoteBranch := targetBranch
+	refSpecPrefix := ""
+	if isChangeRequestDelivery(params) {
+		// The branch of the change request is generated for the object and
+		// only ever written by syngit: it is overwritten with the new state.
+		remoteBranch = ChangeRequestBranch(params)
+		refSpecPrefix = "+"
+	}
+
+	refSpec := config.RefSpec(fmt.Sprintf("%srefs/heads/%s:refs/heads/%s", refSpecPrefix, targetBranch, remoteBranch))
+	return pushRefSpecs(ctx, params, targetRepository, []config.RefSpec{refSpec}, needForcePush, nil)
+}
 
+// pushRefSpecs pushes refSpecs to the target repository of params, in a
+// single push. Each branch of the refSpecs is updated, or rejected, on its own.
+// A forceWithLease only lets the branches be overwritten while they are still
+// on the commit it expects.
+func pushRefSpecs(
+	ctx context.Context,
+	params interceptor.GitPipelineParams,
+	targetRepository *git.Repository,
+	refSpecs []config.RefSpec,
+	needForcePush bool,
+	forceWithLease *git.ForceWithLease,
+) error {
+	references := make([]string, len(refSpecs))
+	for i, refSpec := range refSpecs {
+		references[i] = refSpec.Dst("").String()
+	}
 	variables := fmt.Sprintf("\nRepository: %s\nReference: %s\nUsername: %s\nEmail: %s\n",
 		params.Syncer.Spec.RemoteRepository,
-		plumbing.ReferenceName(targetBranch),
+		strings.Join(references, ", "),
 		params.GitUserInfo.User,
 		params.GitUserInfo.Email,
 	)
+	auth, err := authMethod(params.GitUserInfo, params.RemoteTarget.Spec.TargetRepository)
+	if err != nil {
+		return err
+	}
+
 	var verboseOutput bytes.Buffer
 	pushOptions := &git.PushOptions{
-		RefSpecs: []config.RefSpec{
-			config.RefSpec(fmt.Sprintf("refs/heads/%s:refs/heads/%s", targetBranch, targetBranch)),
-		},
-		Auth: &http.BasicAuth{
-			Username: params.GitUserInfo.User,
-			Password: params.GitUserInfo.Token,
-		},
+		RefSpecs:        refSpecs,
+		Auth:            auth,
 		InsecureSkipTLS: params.Syncer.Spec.InsecureSkipTlsVerify,
+		ProxyOptions:    proxyOptions(params.Transport),
+		ClientCert:      params.Transport.ClientCert,
+		ClientKey:       params.Transport.ClientKey,
 		Progress:        io.MultiWriter(&verboseOutput), // Capture verbose output
 		Force:           needForcePush,
+		ForceWithLease:  forceWithLease,
 	}
 	if params.CABundle != nil {
 		pushOptions.CABundle = params.CABundle
 	}
-	err := push(targetRepository, pushOptions, verboseOutput, variables)
-	if err != nil {
-		for range params.Syncer.Spec.PushErrorRetryNumber {
-			err = push(targetRepository, pushOptions, verboseOutput, variables)
-			if err == nil {
-				break
-			}
-		}
-	}
-
-	return err
+	// Pushing the same commit again cannot win against a branch that moved: a
+	// non-fast-forward rejection is not retried here but left to the pipeline,
+	// which rebuilds the commit on top of the new tip instead.
+	return withRetries(ctx, params.Syncer.Spec.PushErrorRetryNumber, func() error {
+		return push(ctx, targetRepository, pushOptions, verboseOutput, variables)
+	})
 }
 
 func push(
+	ctx context.Context,
 	targetRepository *git.Repository,
 	pushOptions *git.PushOptions,
 	verboseOutput bytes.Buffer,
 	variables string,
 ) error {
-	err := targetRepository.Push(pushOptions)
+	err := targetRepository.PushContext(ctx, pushOptions)
 	if err != nil {
 		if strings.Contains(err.Error(), "already up-to-date") {
 			return nil
 		}
-		return fmt.Errorf("failed to push changes: %v\nVerbose output:%s\nVariables: %s", err, verboseOutput.String(), variables)
+		return fmt.Errorf("failed to push changes: %w\nVerbose output:%s\nVariables: %s", err, verboseOutput.String(), variables)
 	}
 	return nil
 }
+
+// isNonFastForward reports whether a push was rejected because the remote
+// branch moved since it was fetched. go-git refuses the push itself when the
+// remote tip is unknown locally or is not an ancestor of the pushed commit;
+// otherwise the server rejects the ref update.
+func isNonFastForward(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, plumbing.ErrObjectNotFound) {
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "non-fast-forward") || strings.Contains(msg, "fetch first")
+}
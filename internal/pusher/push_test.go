@@ -0,0 +1,108 @@
+package pusher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+func TestIsNonFastForward(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil error", nil, false},
+		{"client-side non-fast-forward", errors.New("non-fast-forward update: refs/heads/main"), true},
+		{"server-side rejection", errors.New("failed to update ref refs/heads/main: fetch first"), true},
+		{"remote tip unknown locally", plumbing.ErrObjectNotFound, true},
+		{"authentication failure", errors.New("authentication required"), false},
+		{"permission denied", errors.New("failed to push changes: 403 Forbidden"), false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isNonFastForward(tc.err); got != tc.want {
+				t.Errorf("isNonFastForward(%v)=%v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+// commitFile writes a file in the worktree of repository and commits it.
+func commitFile(t *testing.T, repository *git.Repository, name, content string) {
+	t.Helper()
+
+	worktree, err := repository.Worktree()
+	if err != nil {
+		t.Fatalf("failed to get worktree: %v", err)
+	}
+	file, err := worktree.Filesystem.Create(name)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", name, err)
+	}
+	if _, err := file.Write([]byte(content)); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+	_ = file.Close()
+	if _, err := worktree.Add(name); err != nil {
+		t.Fatalf("failed to add %s: %v", name, err)
+	}
+	_, err = worktree.Commit("add "+name, &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@syngit.io", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatalf("failed to commit %s: %v", name, err)
+	}
+}
+
+func TestIsNonFastForward_pushLostARace(t *testing.T) {
+	remote := t.TempDir()
+	if _, err := git.PlainInit(remote, true); err != nil {
+		t.Fatalf("failed to init the remote: %v", err)
+	}
+
+	seed, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatalf("failed to init the seed: %v", err)
+	}
+	if _, err := seed.CreateRemote(&config.RemoteConfig{Name: originRemote, URLs: []string{remote}}); err != nil {
+		t.Fatalf("failed to create the remote: %v", err)
+	}
+	commitFile(t, seed, "README.md", "seed")
+	if err := seed.Push(&git.PushOptions{}); err != nil {
+		t.Fatalf("failed to push the seed: %v", err)
+	}
+
+	clone := func() *git.Repository {
+		repository, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{URL: remote})
+		if err != nil {
+			t.Fatalf("failed to clone: %v", err)
+		}
+		return repository
+	}
+	winner, loser := clone(), clone()
+
+	commitFile(t, winner, "winner.yaml", "winner")
+	if err := winner.Push(&git.PushOptions{}); err != nil {
+		t.Fatalf("the first push must succeed: %v", err)
+	}
+
+	commitFile(t, loser, "loser.yaml", "loser")
+	err = push(context.Background(), loser, &git.PushOptions{}, bytes.Buffer{}, "")
+	if err == nil {
+		t.Fatal("the push of a stale branch must be rejected")
+	}
+	if !isNonFastForward(err) {
+		t.Errorf("the rejection must be detected as non-fast-forward: %v", err)
+	}
+}
@@ -2,108 +2,384 @@ package pusher
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"path"
+	"strings"
+	"time"
 
-	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
 	git "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
-	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/syngit-org/syngit/internal/mutator"
+	features "github.com/syngit-org/syngit/pkg/feature"
 	"github.com/syngit-org/syngit/pkg/interceptor"
 )
 
+// defaultInitialCommitMessage is the message of the initial commit of the
+// syncers that do not set one.
+const defaultInitialCommitMessage = "Initial commit"
+
 type GetRepositoryParams struct {
 	GitUserInfo interceptor.GitUserInfo
 	Syncer      interceptor.SyncerContext
 	CABundle    []byte
+	Transport   interceptor.GitTransport
 	Repository  string
 	Branch      string
+	// Depth limits the cloned history to this many commits. 0 clones the
+	// whole history.
+	Depth int
+	// SparseDir, when set, is the only directory checked out in the worktree.
+	// The other files stay in the index and in the commits.
+	SparseDir string
 }
 
+// cacheKey identifies the repositories that can be reused for params: a
+// shallow or sparse clone is not shared with the requests that need more.
 func (p GetRepositoryParams) cacheKey() string {
-	return p.Repository + "#" + p.Branch
+	key := p.Repository + "#" + p.Branch
+	if p.Depth > 0 {
+		key += fmt.Sprintf("@depth=%d", p.Depth)
+	}
+	if p.SparseDir != "" {
+		key += ":" + p.SparseDir
+	}
+	return key
+}
+
+// sparseDirs returns the directories to check out, or nil for all of them.
+func (p GetRepositoryParams) sparseDirs() []string {
+	if p.SparseDir == "" {
+		return nil
+	}
+	return []string{p.SparseDir + "/"}
 }
 
-func (p GetRepositoryParams) basicAuth() *http.BasicAuth {
-	return &http.BasicAuth{
-		Username: p.GitUserInfo.User,
-		Password: p.GitUserInfo.Token,
+func (p GetRepositoryParams) auth() (transport.AuthMethod, error) {
+	return authMethod(p.GitUserInfo, p.Repository)
+}
+
+// proxyOptions returns the HTTP proxy of gitTransport, if any, as the options
+// of the git operations expect it. go-git reuses one HTTP transport per proxy
+// and client certificate.
+func proxyOptions(gitTransport interceptor.GitTransport) transport.ProxyOptions {
+	return transport.ProxyOptions{
+		URL:      gitTransport.ProxyURL,
+		Username: gitTransport.ProxyUsername,
+		Password: gitTransport.ProxyPassword,
 	}
 }
 
-// getRepository returns an in-memory repository for params together with a
-// release function that the caller must invoke once it is done mutating the
-// repository (clone/refresh -> worktree -> commit -> push).
+// getRepository returns a repository for params together with a release
+// function that the caller must invoke once it is done mutating the repository
+// (clone/refresh -> worktree -> commit -> push). The repository is stored in
+// memory, or on disk when the cache has a directory, and shares its objects
+// with the other clones of the same URL, whatever their branch.
 //
 // When the cache is disabled the repository is cloned on every call and the
-// release function is a no-op. When the cache is enabled the repository is
+// release function removes it. When the cache is enabled the repository is
 // leased: on a miss it is cloned and stored, on a hit it is fetched and hard
-// reset back to a fresh-clone-equivalent state before being reused.
-func getRepository(params GetRepositoryParams) (*git.Repository, func(), error) {
+// reset back to a fresh-clone-equivalent state before being reused. A cached
+// repository is shared by every credential that can read it, so the
+// credential of params is checked against the git server first.
+//
+// Every network call and the wait for the lease stop once ctx is done.
+func getRepository(ctx context.Context, params GetRepositoryParams) (*git.Repository, func(), error) {
 	if repoCache == nil {
-		repository, err := cloneRepository(params)
-		return repository, func() {}, err
+		repository, storage, err := cloneRepository(ctx, params)
+		return repository, storage.remove, err
+	}
+	if err := checkAccess(ctx, params); err != nil {
+		return nil, nil, err
 	}
 
-	lease := acquire(params.cacheKey())
+	lease, err := acquire(ctx, params.cacheKey())
+	if err != nil {
+		return nil, nil, err
+	}
 
 	if lease.repo() == nil {
 		// Cache miss: clone and store.
-		repository, err := cloneRepository(params)
+		repository, storage, err := cloneRepository(ctx, params)
 		if err != nil {
 			lease.discard()
 			return nil, nil, err
 		}
-		lease.set(repository)
+		lease.set(repository, storage)
 		return repository, lease.release, nil
 	}
 
 	// Cache hit: refresh in place. If refreshing fails for any reason, fall back
 	// to a fresh clone and replace the stale cached repository.
-	if err := refreshRepository(lease.repo(), params); err != nil {
-		repository, cloneErr := cloneRepository(params)
+	if err := refreshRepository(ctx, lease.repo(), params); err != nil {
+		if ctx.Err() != nil {
+			// The next request refreshes the repository again.
+			lease.release()
+			return nil, nil, fmt.Errorf("failed to refresh cached repository: %w", err)
+		}
+		repository, storage, cloneErr := cloneRepository(ctx, params)
 		if cloneErr != nil {
 			lease.discard()
 			return nil, nil, fmt.Errorf("failed to refresh cached repository: %v; re-clone failed: %w", err, cloneErr)
 		}
-		lease.set(repository)
+		lease.set(repository, storage)
 		return repository, lease.release, nil
 	}
+	recordTip(lease.repo(), lease.handle.storage, params)
 
 	return lease.repo(), lease.release, nil
 }
 
-// cloneRepository clones the repository described by params into memory.
-func cloneRepository(params GetRepositoryParams) (*git.Repository, error) {
-	var verboseOutput bytes.Buffer
+// recordTip records the commit of repository in its object store, so that
+// the next clones of other branches do not download it again.
+func recordTip(repository *git.Repository, storage repoStorage, params GetRepositoryParams) {
+	if storage.objects == nil {
+		return
+	}
+	if head, err := repository.Head(); err == nil {
+		storage.objects.setTip(params.cacheKey(), head.Hash())
+	}
+}
+
+// cloneRepository clones the repository described by params into memory, or
+// into a new directory of the cache directory. Only the objects missing from
+// the object store of the repository URL are downloaded.
+//
+// A branch that the repository does not have yet is started from the default
+// branch of the repository, or from scratch when the repository is empty: it
+// is created on the remote by the first push.
+func cloneRepository(ctx context.Context, params GetRepositoryParams) (*git.Repository, repoStorage, error) {
+	auth, err := params.auth()
+	if err != nil {
+		return nil, repoStorage{}, err
+	}
+
 	cloneOptions := &git.CloneOptions{
 		URL:             params.Repository,
 		ReferenceName:   plumbing.ReferenceName(params.Branch),
-		Auth:            params.basicAuth(),
+		Auth:            auth,
 		SingleBranch:    true,
+		Depth:           params.Depth,
+		NoCheckout:      params.SparseDir != "",
 		InsecureSkipTLS: params.Syncer.Spec.InsecureSkipTlsVerify,
-		Progress:        io.MultiWriter(&verboseOutput),
+		ProxyOptions:    proxyOptions(params.Transport),
+		ClientCert:      params.Transport.ClientCert,
+		ClientKey:       params.Transport.ClientKey,
 	}
 	if params.CABundle != nil {
 		cloneOptions.CABundle = params.CABundle
 	}
-	repository, err := git.Clone(memory.NewStorage(), memfs.New(), cloneOptions)
+
+	newBranch := false
+	repository, storage, err := clone(ctx, params, cloneOptions)
+	switch {
+	case errors.Is(err, transport.ErrEmptyRemoteRepository):
+		newBranch = true
+		repository, storage, err = initRepository(params)
+	case errors.Is(err, git.NoMatchingRefSpecError{}):
+		newBranch = true
+		defaultBranchOptions := *cloneOptions
+		defaultBranchOptions.ReferenceName = ""
+		defaultBranchOptions.NoCheckout = true
+		repository, storage, err = clone(ctx, params, &defaultBranchOptions)
+	}
+	if err != nil {
+		return nil, repoStorage{}, err
+	}
+
+	if newBranch {
+		err = startBranch(repository, params)
+	} else if params.SparseDir != "" {
+		var worktree *git.Worktree
+		worktree, err = repository.Worktree()
+		if err == nil {
+			err = worktree.Checkout(&git.CheckoutOptions{
+				Branch:                    plumbing.NewBranchReferenceName(params.Branch),
+				SparseCheckoutDirectories: params.sparseDirs(),
+			})
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to check out %s: %w", params.SparseDir, err)
+		}
+	}
+	if err != nil {
+		storage.remove()
+		return nil, repoStorage{}, err
+	}
+	recordTip(repository, storage, params)
+
+	return repository, storage, nil
+}
+
+// clone clones the repository of params with cloneOptions. Each attempt clones
+// into a new storage, since a failed clone leaves its storage behind in an
+// unknown state.
+func clone(ctx context.Context, params GetRepositoryParams, cloneOptions *git.CloneOptions) (*git.Repository, repoStorage, error) {
+	var verboseOutput bytes.Buffer
+	cloneOptions.Progress = io.MultiWriter(&verboseOutput)
+
+	var (
+		repository *git.Repository
+		storage    repoStorage
+	)
+	err := withRetries(ctx, networkRetries, func() error {
+		verboseOutput.Reset()
+		storer, worktree, attempt, err := newRepositoryStorage(params.Repository, params.Depth)
+		if err != nil {
+			return err
+		}
+		if err := attempt.objects.seedHaves(storer); err != nil {
+			attempt.remove()
+			return fmt.Errorf("failed to prepare the clone: %w", err)
+		}
+		repository, err = git.CloneContext(ctx, storer, worktree, cloneOptions)
+		if err == nil {
+			err = removeHaves(storer)
+		}
+		if err != nil {
+			attempt.remove()
+			return err
+		}
+		storage = attempt
+		return nil
+	})
 	if err != nil {
 		variables := fmt.Sprintf("\nRepository: %s\nReference: %s\nUsername: %s\nEmail: %s\n",
 			params.Repository,
-			plumbing.ReferenceName(params.Branch),
+			cloneOptions.ReferenceName,
 			params.GitUserInfo.User,
 			params.GitUserInfo.Email,
 		)
-		return nil, fmt.Errorf(
-			"failed to clone repository: %v\nVerbose output: %s\nVariables: %s",
+		return nil, repoStorage{}, fmt.Errorf(
+			"failed to clone repository: %w\nVerbose output: %s\nVariables: %s",
 			err, verboseOutput.String(), variables,
 		)
 	}
+	return repository, storage, nil
+}
 
-	return repository, nil
+// initRepository initializes an empty repository for params, with the origin
+// remote of a clone, for a remote repository that has no commit yet.
+func initRepository(params GetRepositoryParams) (*git.Repository, repoStorage, error) {
+	storer, worktree, storage, err := newRepositoryStorage(params.Repository, params.Depth)
+	if err != nil {
+		return nil, repoStorage{}, err
+	}
+	repository, err := git.Init(storer, worktree)
+	if err == nil {
+		_, err = repository.CreateRemote(&config.RemoteConfig{
+			Name: originRemote,
+			URLs: []string{params.Repository},
+		})
+	}
+	if err != nil {
+		storage.remove()
+		return nil, repoStorage{}, fmt.Errorf("failed to initialize the repository: %w", err)
+	}
+	return repository, storage, nil
+}
+
+// startBranch checks out the branch of params, that the remote repository does
+// not have, on the commit repository is on, or as an unborn branch when
+// repository has no commit. The files of the initial commit of the syncer are
+// then committed to it.
+func startBranch(repository *git.Repository, params GetRepositoryParams) error {
+	branchRef := plumbing.NewBranchReferenceName(params.Branch)
+	worktree, err := repository.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to get worktree: %w", err)
+	}
+
+	head, err := repository.Head()
+	switch {
+	case errors.Is(err, plumbing.ErrReferenceNotFound):
+		err = repository.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, branchRef))
+	case err == nil:
+		err = repository.Storer.SetReference(plumbing.NewHashReference(branchRef, head.Hash()))
+		if err == nil {
+			err = worktree.Checkout(&git.CheckoutOptions{
+				Branch:                    branchRef,
+				SparseCheckoutDirectories: params.sparseDirs(),
+			})
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start the branch %s: %w", params.Branch, err)
+	}
+
+	return commitInitialFiles(repository, worktree, params)
+}
+
+// commitInitialFiles commits the files of the initial commit of the syncer
+// that the branch checked out in worktree does not hold yet. Nothing is
+// committed when it holds all of them.
+func commitInitialFiles(repository *git.Repository, worktree *git.Worktree, params GetRepositoryParams) error {
+	initialCommit := params.Syncer.Spec.InitialCommit
+	if len(initialCommit.Files) == 0 {
+		return nil
+	}
+
+	var tree *object.Tree
+	if head, err := repository.Head(); err == nil {
+		commit, err := repository.CommitObject(head.Hash())
+		if err != nil {
+			return err
+		}
+		if tree, err = commit.Tree(); err != nil {
+			return err
+		}
+	}
+
+	written := 0
+	for _, file := range initialCommit.Files {
+		name := path.Clean(strings.TrimPrefix(file.Path, "/"))
+		if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("the initial file %s is outside of the repository", file.Path)
+		}
+		if tree != nil {
+			if _, err := tree.FindEntry(name); err == nil {
+				continue
+			}
+		}
+		if err := util.WriteFile(worktree.Filesystem, name, []byte(file.Content), 0o644); err != nil {
+			return fmt.Errorf("failed to write the initial file %s: %w", name, err)
+		}
+		if _, err := worktree.Add(name); err != nil {
+			return fmt.Errorf("failed to add file to staging area: %v", err)
+		}
+		written++
+	}
+	if written == 0 {
+		return nil
+	}
+
+	message := initialCommit.Message
+	if message == "" {
+		message = defaultInitialCommitMessage
+	}
+	signKey, signer, err := commitSigning(params.GitUserInfo)
+	if err != nil {
+		return err
+	}
+	_, err = worktree.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  params.GitUserInfo.User,
+			Email: params.GitUserInfo.Email,
+			When:  time.Now(),
+		},
+		SignKey: signKey,
+		Signer:  signer,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit the initial files: %w", err)
+	}
+	return nil
 }
 
 // refreshRepository brings a cached repository back to the state a fresh clone
@@ -111,28 +387,40 @@ func cloneRepository(params GetRepositoryParams) (*git.Repository, error) {
 // clone branch to origin/<branch>, cleans untracked files, and prunes any
 // leftover branches and the "upstream" remote that a previous pipeline run may
 // have created.
-func refreshRepository(repository *git.Repository, params GetRepositoryParams) error {
+func refreshRepository(ctx context.Context, repository *git.Repository, params GetRepositoryParams) error {
 	branch := params.Branch
 	branchRef := plumbing.NewBranchReferenceName(branch)
 	remoteTrackingRef := plumbing.ReferenceName(fmt.Sprintf("refs/remotes/%s/%s", originRemote, branch))
 
+	auth, err := params.auth()
+	if err != nil {
+		return err
+	}
+
 	var verboseOutput bytes.Buffer
 	fetchOptions := &git.FetchOptions{
 		RemoteName: originRemote,
 		RemoteURL:  params.Repository,
-		Auth:       params.basicAuth(),
+		Auth:       auth,
 		RefSpecs: []config.RefSpec{
 			config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", branch, originRemote, branch)),
 		},
+		Depth:           params.Depth,
 		InsecureSkipTLS: params.Syncer.Spec.InsecureSkipTlsVerify,
+		ProxyOptions:    proxyOptions(params.Transport),
+		ClientCert:      params.Transport.ClientCert,
+		ClientKey:       params.Transport.ClientKey,
 		Progress:        io.MultiWriter(&verboseOutput),
 		Force:           true,
 	}
 	if params.CABundle != nil {
 		fetchOptions.CABundle = params.CABundle
 	}
-	if err := repository.Fetch(fetchOptions); err != nil && err != git.NoErrAlreadyUpToDate {
-		return fmt.Errorf("failed to fetch origin: %v\nVerbose output: %s", err, verboseOutput.String())
+	err = withRetries(ctx, networkRetries, func() error {
+		return ignoreUpToDate(repository.FetchContext(ctx, fetchOptions))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch origin: %w\nVerbose output: %s", err, verboseOutput.String())
 	}
 
 	remoteRef, err := repository.Reference(remoteTrackingRef, true)
@@ -148,10 +436,15 @@ func refreshRepository(repository *git.Repository, params GetRepositoryParams) e
 	if err != nil {
 		return fmt.Errorf("failed to get worktree: %w", err)
 	}
-	if err := worktree.Checkout(&git.CheckoutOptions{Branch: branchRef, Force: true}); err != nil {
+	if err := worktree.Checkout(&git.CheckoutOptions{
+		Branch:                    branchRef,
+		Force:                     true,
+		SparseCheckoutDirectories: params.sparseDirs(),
+	}); err != nil {
 		return fmt.Errorf("failed to checkout branch %s: %w", branch, err)
 	}
-	if err := worktree.Reset(&git.ResetOptions{Commit: remoteRef.Hash(), Mode: git.HardReset}); err != nil {
+	resetOptions := &git.ResetOptions{Commit: remoteRef.Hash(), Mode: git.HardReset}
+	if err := worktree.ResetSparsely(resetOptions, params.sparseDirs()); err != nil {
 		return fmt.Errorf("failed to hard reset: %w", err)
 	}
 	if err := worktree.Clean(&git.CleanOptions{Dir: true}); err != nil {
@@ -196,22 +489,62 @@ func pruneStaleState(repository *git.Repository, keepBranch plumbing.ReferenceNa
 	return nil
 }
 
-func GetUpstreamRepository(params interceptor.GitPipelineParams) (*git.Repository, func(), error) {
-	return getRepository(GetRepositoryParams{
+func GetUpstreamRepository(ctx context.Context, params interceptor.GitPipelineParams) (*git.Repository, func(), error) {
+	return getRepository(ctx, GetRepositoryParams{
 		Syncer:      params.Syncer,
 		CABundle:    params.CABundle,
+		Transport:   params.Transport,
 		GitUserInfo: params.GitUserInfo,
 		Repository:  params.RemoteTarget.Spec.UpstreamRepository,
 		Branch:      params.RemoteTarget.Spec.UpstreamBranch,
 	})
 }
 
-func GetTargetRepository(params interceptor.GitPipelineParams) (*git.Repository, func(), error) {
-	return getRepository(GetRepositoryParams{
+func GetTargetRepository(ctx context.Context, params interceptor.GitPipelineParams) (*git.Repository, func(), error) {
+	return getRepository(ctx, targetRepositoryParams(params))
+}
+
+func targetRepositoryParams(params interceptor.GitPipelineParams) GetRepositoryParams {
+	repositoryParams := GetRepositoryParams{
 		Syncer:      params.Syncer,
 		CABundle:    params.CABundle,
+		Transport:   params.Transport,
 		GitUserInfo: params.GitUserInfo,
 		Repository:  params.RemoteTarget.Spec.TargetRepository,
 		Branch:      params.RemoteTarget.Spec.UpstreamBranch,
-	})
+	}
+
+	// The merge strategies need the history of the branches to merge them,
+	// and check out the whole worktree while doing so.
+	if params.RemoteTarget.Spec.MergeStrategy != "" ||
+		params.RemoteTarget.Spec.UpstreamRepository != params.RemoteTarget.Spec.TargetRepository {
+		return repositoryParams
+	}
+	repositoryParams.Depth = int(params.Syncer.Spec.CloneDepth)
+	repositoryParams.SparseDir = sparseDir(params)
+	return repositoryParams
+}
+
+// sparseDir returns the only directory the pipeline writes to: the root path
+// of the syncer, unless the ResourceFinder may find the object anywhere else
+// in the repository. The SOPS encryption reads its .sops.yaml at the root of
+// the repository, so it needs the whole worktree as well.
+func sparseDir(params interceptor.GitPipelineParams) string {
+	if features.LoadedFeatureGates.Enabled(features.ResourceFinder) && params.Syncer.Spec.ResourceFinder {
+		return ""
+	}
+	if mutator.IsSOPSEnabled(params) {
+		return ""
+	}
+	dir := path.Clean(strings.Trim(params.Syncer.Spec.RootPath, "/"))
+	if dir == "." {
+		return ""
+	}
+	// An object moved by a change of routing rule is deleted from the root
+	// path of the previous rule, which must be checked out as well.
+	if placement := params.Syncer.PreviousPlacement; placement != nil &&
+		path.Clean(strings.Trim(placement.RootPath, "/")) != dir {
+		return ""
+	}
+	return dir
 }
@@ -0,0 +1,379 @@
+package pusher
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/syngit-org/syngit/internal/walker"
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	features "github.com/syngit-org/syngit/pkg/feature"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// remoteWithHistory returns a seeded remote with a few more commits, inside
+// and outside of the deploy directory.
+func remoteWithHistory(t *testing.T) string {
+	t.Helper()
+
+	remote := seededRemote(t)
+	clone, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{URL: remote})
+	if err != nil {
+		t.Fatalf("failed to clone the remote: %v", err)
+	}
+	commitFile(t, clone, "other/app.yaml", "kind: ConfigMap\n")
+	commitFile(t, clone, "deploy/keep.yaml", "kind: Secret\n")
+	if err := clone.Push(&git.PushOptions{}); err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+	return remote
+}
+
+func TestCloneRepository_shallowAndSparse(t *testing.T) {
+	remote := remoteWithHistory(t)
+
+	repository, storage, err := cloneRepository(context.Background(), GetRepositoryParams{
+		Repository: remote,
+		Branch:     "master",
+		Depth:      1,
+		SparseDir:  "deploy",
+	})
+	if err != nil {
+		t.Fatalf("cloneRepository: %v", err)
+	}
+	defer storage.remove()
+
+	shallow, err := repository.Storer.Shallow()
+	if err != nil || len(shallow) != 1 {
+		t.Errorf("expected a clone of depth 1, got the shallow commits %v (%v)", shallow, err)
+	}
+
+	worktree, _ := repository.Worktree()
+	if _, err := worktree.Filesystem.Stat("deploy/keep.yaml"); err != nil {
+		t.Errorf("the sparse directory must be checked out: %v", err)
+	}
+	for _, path := range []string{"README.md", "other/app.yaml"} {
+		if _, err := worktree.Filesystem.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s is outside of the sparse directory and must not be checked out", path)
+		}
+	}
+}
+
+func TestTargetRepositoryParams_narrowClone(t *testing.T) {
+	params := interceptor.GitPipelineParams{}
+	params.Syncer.Spec.RootPath = "/deploy/"
+	params.Syncer.Spec.CloneDepth = 1
+	params.RemoteTarget.Spec = syngit.RemoteTargetSpec{UpstreamRepository: "repo", TargetRepository: "repo"}
+
+	got := targetRepositoryParams(params)
+	if got.Depth != 1 || got.SparseDir != "deploy" {
+		t.Errorf("targetRepositoryParams() = depth %d, sparse %q; want 1, deploy", got.Depth, got.SparseDir)
+	}
+	if got.cacheKey() == (GetRepositoryParams{Repository: "repo"}).cacheKey() {
+		t.Errorf("a narrow clone must not share the cache key of a full clone")
+	}
+
+	params.Syncer.Spec.ResourceFinder = true
+	params.RemoteTarget.Spec.MergeStrategy = syngit.TryHardResetOrDie
+	got = targetRepositoryParams(params)
+	if got.Depth != 0 || got.SparseDir != "" {
+		t.Errorf("a merge strategy needs the whole clone, got depth %d, sparse %q", got.Depth, got.SparseDir)
+	}
+}
+
+func TestTargetRepositoryParams_sopsReadsTheRootConfig(t *testing.T) {
+	previous := features.LoadedFeatureGates[features.SopsEncryption]
+	features.LoadedFeatureGates[features.SopsEncryption] = true
+	t.Cleanup(func() { features.LoadedFeatureGates[features.SopsEncryption] = previous })
+
+	remote := remoteWithHistory(t)
+	clone, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{URL: remote})
+	if err != nil {
+		t.Fatalf("failed to clone the remote: %v", err)
+	}
+	commitFile(t, clone, ".sops.yaml", "creation_rules:\n  - path_regex: .*\n")
+	if err := clone.Push(&git.PushOptions{}); err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+
+	params := interceptor.GitPipelineParams{}
+	params.Syncer.Spec.RootPath = "deploy"
+	params.Syncer.Spec.SOPS.Enabled = true
+	params.RemoteTarget.Spec = syngit.RemoteTargetSpec{
+		UpstreamRepository: remote,
+		UpstreamBranch:     "master",
+		TargetRepository:   remote,
+		TargetBranch:       "master",
+	}
+
+	repositoryParams := targetRepositoryParams(params)
+	if repositoryParams.SparseDir != "" {
+		t.Errorf("a SOPS syncer needs the root of the repository, got the sparse directory %q", repositoryParams.SparseDir)
+	}
+	repository, storage, err := cloneRepository(context.Background(), repositoryParams)
+	if err != nil {
+		t.Fatalf("cloneRepository: %v", err)
+	}
+	defer storage.remove()
+
+	worktree, _ := repository.Worktree()
+	if _, err := walker.ReadWorktreeFile(worktree, ".sops.yaml"); err != nil {
+		t.Errorf("the .sops.yaml of the repository must be checked out: %v", err)
+	}
+}
+
+func TestRunGitPipeline_narrowClone(t *testing.T) {
+	InitRepoCache(2)
+	defer InitRepoCache(0)
+
+	remote := remoteWithHistory(t)
+	params := interceptor.GitPipelineParams{
+		InterceptedGVR:  schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+		InterceptedName: "cm",
+		InterceptedYAML: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n  namespace: default\n",
+		GitUserInfo:     interceptor.GitUserInfo{User: "test", Email: "test@syngit.io"},
+		Operation:       "CREATE",
+	}
+	params.Syncer.InterceptedNamespace = "default"
+	params.Syncer.Spec.RootPath = "deploy"
+	params.Syncer.Spec.CloneDepth = 1
+	params.RemoteTarget.Spec = syngit.RemoteTargetSpec{
+		UpstreamRepository: remote,
+		UpstreamBranch:     "master",
+		TargetRepository:   remote,
+		TargetBranch:       "master",
+	}
+
+	// The second run refreshes the cached narrow clone.
+	object := params.InterceptedYAML
+	for _, content := range []string{"a", "b"} {
+		params.InterceptedYAML = object + "data:\n  key: " + content + "\n"
+		if _, err := RunGitPipeline(context.Background(), nil, params); err != nil {
+			t.Fatalf("RunGitPipeline: %v", err)
+		}
+	}
+
+	repository, err := git.PlainOpen(remote)
+	if err != nil {
+		t.Fatalf("failed to open the remote: %v", err)
+	}
+	head, _ := repository.Head()
+	commit, _ := repository.CommitObject(head.Hash())
+	tree, _ := commit.Tree()
+	for _, path := range []string{"README.md", "other/app.yaml", "deploy/keep.yaml", "deploy/default/v1/configmaps/cm.yaml"} {
+		if _, err := tree.File(path); err != nil {
+			t.Errorf("the pushed tree misses %s: %v", path, err)
+		}
+	}
+	if file, err := tree.File("deploy/default/v1/configmaps/cm.yaml"); err == nil {
+		if content, _ := file.Contents(); !strings.Contains(content, "key: b") {
+			t.Errorf("the pushed object is not the latest one: %q", content)
+		}
+	}
+}
+
+func TestRunGitPipeline_narrowCloneMovesRerouted(t *testing.T) {
+	InitRepoCache(2)
+	defer InitRepoCache(0)
+
+	remote := remoteWithHistory(t)
+	clone, err := git.Clone(memory.NewStorage(), memfs.New(), &git.CloneOptions{URL: remote})
+	if err != nil {
+		t.Fatalf("failed to clone the remote: %v", err)
+	}
+	commitFile(t, clone, "teams/a/default/v1/configmaps/cm.yaml", "kind: ConfigMap\n")
+	if err := clone.Push(&git.PushOptions{}); err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+
+	params := interceptor.GitPipelineParams{
+		InterceptedGVR:  schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+		InterceptedName: "cm",
+		InterceptedYAML: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n  namespace: default\n",
+		GitUserInfo:     interceptor.GitUserInfo{User: "test", Email: "test@syngit.io"},
+		Operation:       "UPDATE",
+	}
+	params.Syncer.InterceptedNamespace = "default"
+	params.Syncer.Spec.RootPath = "teams/b"
+	params.Syncer.Spec.CloneDepth = 1
+	params.Syncer.PreviousPlacement = &syngit.PathPlacement{RootPath: "teams/a"}
+	params.RemoteTarget.Spec = syngit.RemoteTargetSpec{
+		UpstreamRepository: remote,
+		UpstreamBranch:     "master",
+		TargetRepository:   remote,
+		TargetBranch:       "master",
+	}
+	if _, err := RunGitPipeline(context.Background(), nil, params); err != nil {
+		t.Fatalf("RunGitPipeline: %v", err)
+	}
+
+	repository, err := git.PlainOpen(remote)
+	if err != nil {
+		t.Fatalf("failed to open the remote: %v", err)
+	}
+	head, _ := repository.Head()
+	commit, _ := repository.CommitObject(head.Hash())
+	tree, _ := commit.Tree()
+	if _, err := tree.File("teams/b/default/v1/configmaps/cm.yaml"); err != nil {
+		t.Errorf("the object is not pushed to the root path of its new routing rule: %v", err)
+	}
+	if _, err := tree.File("teams/a/default/v1/configmaps/cm.yaml"); err == nil {
+		t.Errorf("the object is still in the root path of its previous routing rule")
+	}
+}
+
+func TestRunGitPipeline_emptyRemote(t *testing.T) {
+	InitRepoCache(2)
+	defer InitRepoCache(0)
+
+	remote := t.TempDir()
+	if _, err := git.PlainInit(remote, true); err != nil {
+		t.Fatalf("failed to init the remote: %v", err)
+	}
+	params := multipleTargetParams(remote, "main")
+	params.Syncer.Spec.InitialCommit = syngit.InitialCommit{
+		Files: []syngit.InitialCommitFile{
+			{Path: "README.md", Content: "# team\n"},
+			{Path: "/.sops.yaml", Content: "creation_rules: []\n"},
+		},
+	}
+
+	// The second run refreshes the cached clone of the branch created by the
+	// first one.
+	for range 2 {
+		if _, err := RunGitPipeline(context.Background(), nil, params); err != nil {
+			t.Fatalf("RunGitPipeline: %v", err)
+		}
+	}
+
+	repository, _ := git.PlainOpen(remote)
+	commit, err := repository.CommitObject(remoteBranch(t, remote, "main"))
+	if err != nil {
+		t.Fatalf("failed to read the pushed commit: %v", err)
+	}
+	initial, err := commit.Parent(0)
+	if err != nil {
+		t.Fatalf("the object must be committed on top of the initial commit: %v", err)
+	}
+	if initial.NumParents() != 0 || initial.Message != defaultInitialCommitMessage {
+		t.Errorf("the initial commit must be the first one of the branch, got %q with %d parents", initial.Message, initial.NumParents())
+	}
+	tree, _ := commit.Tree()
+	for _, path := range []string{"README.md", ".sops.yaml", "default/v1/configmaps/app.yaml"} {
+		if _, err := tree.File(path); err != nil {
+			t.Errorf("the pushed tree misses %s: %v", path, err)
+		}
+	}
+}
+
+func TestRunGitPipeline_missingBranch(t *testing.T) {
+	remote := remoteWithHistory(t)
+	master := remoteBranch(t, remote, "master")
+	params := multipleTargetParams(remote, "team")
+	params.Syncer.Spec.RootPath = "deploy"
+	params.Syncer.Spec.InitialCommit = syngit.InitialCommit{
+		Message: "Start the team branch",
+		Files: []syngit.InitialCommitFile{
+			{Path: "README.md", Content: "# team\n"},
+			{Path: "deploy/.keep"},
+		},
+	}
+
+	if _, err := RunGitPipeline(context.Background(), nil, params); err != nil {
+		t.Fatalf("RunGitPipeline: %v", err)
+	}
+
+	repository, _ := git.PlainOpen(remote)
+	commit, err := repository.CommitObject(remoteBranch(t, remote, "team"))
+	if err != nil {
+		t.Fatalf("failed to read the pushed commit: %v", err)
+	}
+	initial, err := commit.Parent(0)
+	if err != nil {
+		t.Fatalf("the object must be committed on top of the initial commit: %v", err)
+	}
+	if initial.Message != "Start the team branch" || initial.NumParents() != 1 || initial.ParentHashes[0] != master {
+		t.Fatalf("the branch must start from master with the initial commit, got %q on %v", initial.Message, initial.ParentHashes)
+	}
+	tree, _ := commit.Tree()
+	if readme, err := tree.File("README.md"); err != nil {
+		t.Errorf("the branch must hold the files of master: %v", err)
+	} else if content, _ := readme.Contents(); content != "seed" {
+		t.Errorf("a file of master must not be overwritten by the initial commit, got %q", content)
+	}
+	for _, path := range []string{"other/app.yaml", "deploy/.keep", "deploy/default/v1/configmaps/app.yaml"} {
+		if _, err := tree.File(path); err != nil {
+			t.Errorf("the pushed tree misses %s: %v", path, err)
+		}
+	}
+}
+
+// forwardingProxy is an HTTP proxy that forwards the requests authenticated
+// with username and password, and counts them.
+func forwardingProxy(t *testing.T, username, password string) (string, *atomic.Int32) {
+	t.Helper()
+
+	credentials := "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password))
+	forwarded := &atomic.Int32{}
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Proxy-Authorization") != credentials {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		request := r.Clone(r.Context())
+		request.RequestURI = ""
+		request.Header.Del("Proxy-Authorization")
+
+		response, err := http.DefaultTransport.RoundTrip(request)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		defer func() { _ = response.Body.Close() }()
+		forwarded.Add(1)
+		for key, values := range response.Header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(response.StatusCode)
+		_, _ = io.Copy(w, response.Body)
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy.URL, forwarded
+}
+
+func TestRunGitPipeline_proxy(t *testing.T) {
+	url, alice, _ := privateRepository(t)
+	proxyURL, forwarded := forwardingProxy(t, "proxy-user", "proxy-password")
+
+	params := multipleTargetParams(url, "main")
+	params.GitUserInfo = alice
+	params.Transport = interceptor.GitTransport{
+		ProxyURL:      proxyURL,
+		ProxyUsername: "proxy-user",
+		ProxyPassword: "wrong",
+	}
+	if _, err := RunGitPipeline(context.Background(), nil, params); err == nil {
+		t.Fatal("the proxy must reject the wrong credentials")
+	}
+	if forwarded.Load() != 0 {
+		t.Fatalf("no request must be forwarded with the wrong credentials")
+	}
+
+	params.Transport.ProxyPassword = "proxy-password"
+	if _, err := RunGitPipeline(context.Background(), nil, params); err != nil {
+		t.Fatalf("RunGitPipeline: %v", err)
+	}
+	if forwarded.Load() == 0 {
+		t.Error("the clone and the push must go through the proxy")
+	}
+}
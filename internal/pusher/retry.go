@@ -0,0 +1,165 @@
+package pusher
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	syngiterrors "github.com/syngit-org/syngit/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// networkRetries is how many times a clone, a fetch or an ls-remote is tried
+// again when the git server fails it with an error that may not happen again.
+// The retries of a push are set by the syncer.
+const networkRetries = 3
+
+// networkBackoff spaces the retries of the network operations. The jitter
+// spreads the retries of the requests that failed at the same time, so that a
+// git server that is recovering is not hit by all of them at once.
+var networkBackoff = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.5,
+	Cap:      10 * time.Second,
+}
+
+// withRetries runs the network operation op, and runs it again up to retries
+// times while it fails with a rate-limited or a transient error. The retries
+// back off exponentially, or wait for as long as the git server asked to. It
+// gives up early when ctx would be done before the next retry.
+//
+// The returned error is classified by classifyGitError.
+func withRetries(ctx context.Context, retries int, op func() error) error {
+	backoff := networkBackoff
+	backoff.Steps = retries + 1
+	for attempt := 0; ; attempt++ {
+		err := classifyGitError(op())
+		retryAfter, retryable := syngiterrors.GitRemoteRetry(err)
+		if err == nil || !retryable || attempt >= retries {
+			return err
+		}
+
+		delay := max(backoff.Step(), retryAfter)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// classifyGitError wraps an error of the git client into a git remote error
+// of pkg/errors, according to why the git server failed the operation. The
+// errors that it cannot classify, including the ones of a context that is
+// done, are returned as they are and are never retried.
+func classifyGitError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	kind, retryAfter := gitErrorKind(err)
+	if kind == "" {
+		return err
+	}
+	return syngiterrors.NewGitRemote(kind, retryAfter, err)
+}
+
+// IsPermanentError reports whether running the git pipeline again cannot fix
+// err: the git server rejects the credential, does not allow it to push, or
+// does not have the repository.
+func IsPermanentError(err error) bool {
+	err = classifyGitError(err)
+	return errors.Is(err, syngiterrors.ErrGitAuth) ||
+		errors.Is(err, syngiterrors.ErrGitPermission) ||
+		errors.Is(err, syngiterrors.ErrGitNotFound)
+}
+
+// gitErrorKind returns the kind of err, or an empty kind when err is not a
+// failure of the git server or of the network.
+func gitErrorKind(err error) (syngiterrors.GitRemoteErrorKind, time.Duration) {
+	switch {
+	case errors.Is(err, transport.ErrAuthenticationRequired), errors.Is(err, transport.ErrInvalidAuthMethod):
+		return syngiterrors.GitRemoteAuth, 0
+	case errors.Is(err, transport.ErrAuthorizationFailed):
+		return syngiterrors.GitRemotePermission, 0
+	case errors.Is(err, transport.ErrRepositoryNotFound):
+		return syngiterrors.GitRemoteNotFound, 0
+	case isNonFastForward(err):
+		return syngiterrors.GitRemoteNonFastForward, 0
+	}
+
+	// go-git reports the other HTTP statuses through an UnexpectedError, which
+	// does not unwrap.
+	var unexpected *plumbing.UnexpectedError
+	if errors.As(err, &unexpected) {
+		var httpErr *githttp.Err
+		if errors.As(unexpected.Err, &httpErr) {
+			return httpErrorKind(httpErr.Response)
+		}
+		err = unexpected.Err
+	}
+
+	// The ssh client does not wrap the failures of the authentication.
+	if strings.Contains(err.Error(), "ssh: unable to authenticate") {
+		return syngiterrors.GitRemoteAuth, 0
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return syngiterrors.GitRemoteTransient, 0
+	}
+	return "", 0
+}
+
+// httpErrorKind returns the kind of an HTTP response that go-git does not map
+// to an error of its own.
+func httpErrorKind(response *http.Response) (syngiterrors.GitRemoteErrorKind, time.Duration) {
+	switch {
+	case response.StatusCode == http.StatusTooManyRequests:
+		return syngiterrors.GitRemoteRateLimited, retryAfter(response)
+	case response.StatusCode == http.StatusRequestTimeout, response.StatusCode >= http.StatusInternalServerError:
+		// A 503 may carry a Retry-After as well.
+		return syngiterrors.GitRemoteTransient, retryAfter(response)
+	}
+	return "", 0
+}
+
+// retryAfter returns the delay of the Retry-After header of response, given
+// either in seconds or as a date.
+func retryAfter(response *http.Response) time.Duration {
+	value := response.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
+
+// ignoreUpToDate drops the error of a fetch or a pull that had nothing to
+// download.
+func ignoreUpToDate(err error) error {
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,193 @@
+package pusher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	syngiterrors "github.com/syngit-org/syngit/pkg/errors"
+)
+
+// fastBackoff makes the retries of the test immediate.
+func fastBackoff(t *testing.T) {
+	saved := networkBackoff
+	networkBackoff.Duration = time.Millisecond
+	t.Cleanup(func() { networkBackoff = saved })
+}
+
+// httpStatusError is the error go-git returns for an HTTP status it does not
+// map to an error of its own.
+func httpStatusError(status int, header http.Header) error {
+	request := &http.Request{URL: &url.URL{Scheme: "https", Host: "git.example.com"}}
+	return githttp.NewErr(&http.Response{StatusCode: status, Header: header, Request: request})
+}
+
+func TestClassifyGitError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"401", fmt.Errorf("%w: bad token", transport.ErrAuthenticationRequired), syngiterrors.ErrGitAuth},
+		{"403", fmt.Errorf("%w: read only", transport.ErrAuthorizationFailed), syngiterrors.ErrGitPermission},
+		{"404", fmt.Errorf("%w: gone", transport.ErrRepositoryNotFound), syngiterrors.ErrGitNotFound},
+		{"non-fast-forward", errors.New("non-fast-forward update: refs/heads/main"), syngiterrors.ErrGitNonFastForward},
+		{"429", httpStatusError(http.StatusTooManyRequests, http.Header{}), syngiterrors.ErrGitRateLimited},
+		{"503", httpStatusError(http.StatusServiceUnavailable, http.Header{}), syngiterrors.ErrGitTransient},
+		{"network", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, syngiterrors.ErrGitTransient},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := classifyGitError(c.err); !errors.Is(got, c.want) {
+				t.Errorf("classifyGitError(%v) = %v, want %v", c.err, got, c.want)
+			}
+		})
+	}
+
+	for _, err := range []error{context.DeadlineExceeded, errors.New("object not valid")} {
+		if got := classifyGitError(err); got != err {
+			t.Errorf("classifyGitError(%v) = %v, want the error as it is", err, got)
+		}
+	}
+}
+
+func TestIsPermanentError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"rejected credential", fmt.Errorf("%w: bad token", transport.ErrAuthenticationRequired), true},
+		{"classified rejected credential", classifyGitError(transport.ErrAuthenticationRequired), true},
+		{"wrapped permission denied", fmt.Errorf("failed to push: %w", classifyGitError(transport.ErrAuthorizationFailed)), true},
+		{"missing repository", transport.ErrRepositoryNotFound, true},
+		{"non-fast-forward", errors.New("non-fast-forward update: refs/heads/main"), false},
+		{"transient", httpStatusError(http.StatusBadGateway, http.Header{}), false},
+		{"deadline", context.DeadlineExceeded, false},
+		{"pipeline", syngiterrors.NewGitPipeline("failed to generate the worktree"), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := IsPermanentError(c.err); got != c.want {
+				t.Errorf("IsPermanentError(%v) = %v, want %v", c.err, got, c.want)
+			}
+		})
+	}
+}
+
+func TestClassifyGitError_retryAfter(t *testing.T) {
+	err := classifyGitError(httpStatusError(http.StatusTooManyRequests, http.Header{"Retry-After": {"7"}}))
+	if retryAfter, retryable := syngiterrors.GitRemoteRetry(err); !retryable || retryAfter != 7*time.Second {
+		t.Errorf("GitRemoteRetry = (%v, %v), want (7s, true)", retryAfter, retryable)
+	}
+
+	date := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	err = classifyGitError(httpStatusError(http.StatusServiceUnavailable, http.Header{"Retry-After": {date}}))
+	if retryAfter, _ := syngiterrors.GitRemoteRetry(err); retryAfter < 58*time.Second || retryAfter > time.Minute {
+		t.Errorf("a Retry-After date must give the delay until it, got %v", retryAfter)
+	}
+}
+
+func TestWithRetries(t *testing.T) {
+	fastBackoff(t)
+	transient := httpStatusError(http.StatusBadGateway, http.Header{})
+
+	calls := 0
+	err := withRetries(context.Background(), 3, func() error {
+		calls++
+		if calls < 3 {
+			return transient
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("a transient error must be retried until it passes, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = withRetries(context.Background(), 3, func() error {
+		calls++
+		return transient
+	})
+	if !errors.Is(err, syngiterrors.ErrGitTransient) || calls != 4 {
+		t.Errorf("the retries must stop once exhausted, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	err = withRetries(context.Background(), 3, func() error {
+		calls++
+		return transport.ErrAuthenticationRequired
+	})
+	if !errors.Is(err, syngiterrors.ErrGitAuth) || calls != 1 {
+		t.Errorf("an authentication error must not be retried, got %v after %d calls", err, calls)
+	}
+}
+
+func TestWithRetries_honorsRetryAfter(t *testing.T) {
+	fastBackoff(t)
+	limited := httpStatusError(http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+
+	calls := 0
+	start := time.Now()
+	err := withRetries(context.Background(), 1, func() error {
+		calls++
+		if calls == 1 {
+			return limited
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("withRetries: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("the retry must wait for the Retry-After of the server, waited %v", elapsed)
+	}
+
+	// A deadline that passes before the server lets us in again gives up at once.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	calls = 0
+	err = withRetries(ctx, 3, func() error {
+		calls++
+		return limited
+	})
+	if !errors.Is(err, syngiterrors.ErrGitRateLimited) || calls != 1 {
+		t.Errorf("the retry must be given up when it would pass the deadline, got %v after %d calls", err, calls)
+	}
+}
+
+func TestCloneRepository_retries(t *testing.T) {
+	fastBackoff(t)
+
+	serve := func(status int) (string, *atomic.Int32) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			w.WriteHeader(status)
+		}))
+		t.Cleanup(server.Close)
+		return server.URL + "/repo.git", &requests
+	}
+
+	unavailable, requests := serve(http.StatusServiceUnavailable)
+	_, _, err := cloneRepository(context.Background(), GetRepositoryParams{Repository: unavailable, Branch: "main"})
+	if !errors.Is(err, syngiterrors.ErrGitTransient) || requests.Load() != networkRetries+1 {
+		t.Errorf("an unavailable server must be tried %d times, got %v after %d requests",
+			networkRetries+1, err, requests.Load())
+	}
+
+	forbidden, requests := serve(http.StatusForbidden)
+	_, _, err = cloneRepository(context.Background(), GetRepositoryParams{Repository: forbidden, Branch: "main"})
+	if !errors.Is(err, syngiterrors.ErrGitPermission) || requests.Load() != 1 {
+		t.Errorf("a forbidden clone must not be retried, got %v after %d requests", err, requests.Load())
+	}
+}
@@ -0,0 +1,160 @@
+package pusher
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/syngit-org/syngit/pkg/interceptor"
+	"golang.org/x/crypto/ssh"
+)
+
+// signedCommit commits a file in a new repository with the signing material
+// of userInfo and returns the commit.
+func signedCommit(t *testing.T, userInfo interceptor.GitUserInfo) *object.Commit {
+	t.Helper()
+
+	signKey, signer, err := commitSigning(userInfo)
+	if err != nil {
+		t.Fatalf("commitSigning: %v", err)
+	}
+
+	repository, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatalf("failed to init the repository: %v", err)
+	}
+	worktree, _ := repository.Worktree()
+	file, _ := worktree.Filesystem.Create("README.md")
+	_, _ = file.Write([]byte("signed"))
+	_ = file.Close()
+	_, _ = worktree.Add("README.md")
+	hash, err := worktree.Commit("signed", &git.CommitOptions{
+		Author:  &object.Signature{Name: "test", Email: "test@syngit.io", When: time.Now()},
+		SignKey: signKey,
+		Signer:  signer,
+	})
+	if err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+	commit, err := repository.CommitObject(hash)
+	if err != nil {
+		t.Fatalf("failed to read the commit: %v", err)
+	}
+	return commit
+}
+
+func TestCommitSigning_none(t *testing.T) {
+	signKey, signer, err := commitSigning(interceptor.GitUserInfo{})
+	if err != nil || signKey != nil || signer != nil {
+		t.Errorf("commitSigning()=(%v, %v, %v), want nothing", signKey, signer, err)
+	}
+}
+
+func TestCommitSigning_openPGP(t *testing.T) {
+	entity, err := openpgp.NewEntity("test", "", "test@syngit.io", nil)
+	if err != nil {
+		t.Fatalf("failed to generate the key: %v", err)
+	}
+	if err := entity.EncryptPrivateKeys([]byte("secret"), nil); err != nil {
+		t.Fatalf("failed to encrypt the key: %v", err)
+	}
+
+	var private bytes.Buffer
+	w, _ := armor.Encode(&private, openpgp.PrivateKeyType, nil)
+	if err := entity.SerializePrivateWithoutSigning(w, nil); err != nil {
+		t.Fatalf("failed to serialize the private key: %v", err)
+	}
+	_ = w.Close()
+	var public bytes.Buffer
+	w, _ = armor.Encode(&public, openpgp.PublicKeyType, nil)
+	if err := entity.Serialize(w); err != nil {
+		t.Fatalf("failed to serialize the public key: %v", err)
+	}
+	_ = w.Close()
+
+	if _, _, err := commitSigning(interceptor.GitUserInfo{SigningKey: private.String()}); err == nil {
+		t.Error("expected an error for an encrypted key without passphrase")
+	}
+	if _, _, err := commitSigning(interceptor.GitUserInfo{SigningKey: private.String(), SigningKeyPassphrase: "wrong"}); err == nil {
+		t.Error("expected an error for a wrong passphrase")
+	}
+
+	commit := signedCommit(t, interceptor.GitUserInfo{SigningKey: private.String(), SigningKeyPassphrase: "secret"})
+	if _, err := commit.Verify(public.String()); err != nil {
+		t.Errorf("the commit signature does not verify: %v", err)
+	}
+}
+
+func TestCommitSigning_ssh(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate the key: %v", err)
+	}
+	block, err := ssh.MarshalPrivateKey(privateKey, "")
+	if err != nil {
+		t.Fatalf("failed to marshal the key: %v", err)
+	}
+
+	commit := signedCommit(t, interceptor.GitUserInfo{SigningKey: string(pem.EncodeToMemory(block))})
+	if !strings.HasPrefix(commit.PGPSignature, sshSignatureArmorHead+"\n") {
+		t.Fatalf("unexpected signature %q", commit.PGPSignature)
+	}
+
+	// Unwrap the signature and check it against the commit as git would.
+	body := strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(commit.PGPSignature), sshSignatureArmorHead), sshSignatureArmorTail)
+	blob, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(body, "\n", ""))
+	if err != nil {
+		t.Fatalf("failed to decode the signature: %v", err)
+	}
+	if !bytes.HasPrefix(blob, []byte(sshSignatureMagic)) {
+		t.Fatal("the signature does not start with the SSHSIG magic")
+	}
+	var sig struct {
+		Version       uint32
+		PublicKey     []byte
+		Namespace     string
+		Reserved      string
+		HashAlgorithm string
+		Signature     []byte
+	}
+	if err := ssh.Unmarshal(blob[len(sshSignatureMagic):], &sig); err != nil {
+		t.Fatalf("failed to unmarshal the signature: %v", err)
+	}
+	if sig.Namespace != "git" || sig.HashAlgorithm != "sha512" {
+		t.Errorf("unexpected namespace %q or hash %q", sig.Namespace, sig.HashAlgorithm)
+	}
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("failed to convert the public key: %v", err)
+	}
+	if !bytes.Equal(sig.PublicKey, sshPublicKey.Marshal()) {
+		t.Error("the signature does not embed the public key")
+	}
+	signature := &ssh.Signature{}
+	if err := ssh.Unmarshal(sig.Signature, signature); err != nil {
+		t.Fatalf("failed to unmarshal the inner signature: %v", err)
+	}
+
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(encoded); err != nil {
+		t.Fatalf("failed to encode the commit: %v", err)
+	}
+	reader, _ := encoded.Reader()
+	content, _ := io.ReadAll(reader)
+	if err := sshPublicKey.Verify(sshSignedData(content), signature); err != nil {
+		t.Errorf("the commit signature does not verify: %v", err)
+	}
+}
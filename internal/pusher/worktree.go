@@ -2,6 +2,8 @@ package pusher
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -9,7 +11,6 @@ import (
 	git "github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
 	"github.com/syngit-org/syngit/pkg/interceptor"
 )
@@ -20,6 +21,7 @@ const (
 )
 
 func GetWorkTree(
+	ctx context.Context,
 	params interceptor.GitPipelineParams,
 	targetRepository, upstreamRepository *git.Repository,
 ) (*git.Worktree, bool, error) {
@@ -35,9 +37,9 @@ func GetWorkTree(
 
 	switch params.RemoteTarget.Spec.MergeStrategy {
 	case syngit.TryFastForwardOrHardReset:
-		wt, err := upstreamBasedPullFastForward(params, targetRepository)
+		wt, err := upstreamBasedPullFastForward(ctx, params, targetRepository)
 		if err != nil {
-			wt, err = upstreamBasedHardReset(params, targetRepository)
+			wt, err = upstreamBasedHardReset(ctx, params, targetRepository)
 			if err != nil {
 				return nil, false, err
 			}
@@ -45,79 +47,63 @@ func GetWorkTree(
 		}
 		return wt, false, nil
 	case syngit.TryHardResetOrDie:
-		wt, err := upstreamBasedHardReset(params, targetRepository)
+		wt, err := upstreamBasedHardReset(ctx, params, targetRepository)
 		if err != nil {
 			return nil, false, err
 		}
 		return wt, true, nil
 	case syngit.TryFastForwardOrDie:
-		wt, err := upstreamBasedPullFastForward(params, targetRepository)
+		wt, err := upstreamBasedPullFastForward(ctx, params, targetRepository)
 		if err != nil {
 			return nil, false, err
 		}
 		return wt, false, nil
+	case syngit.TryMergeOrDie:
+		wt, err := upstreamBasedMerge(ctx, params, targetRepository)
+		if err != nil {
+			return nil, false, err
+		}
+		return wt, false, nil
+	case syngit.TryRebaseOrDie:
+		wt, rebased, err := upstreamBasedRebase(ctx, params, targetRepository)
+		if err != nil {
+			return nil, false, err
+		}
+		return wt, rebased, nil
 	default:
 		return nil, false, fmt.Errorf("wrong target strategy; got %s", params.RemoteTarget.Spec.MergeStrategy)
 	}
 }
 
 func upstreamBasedHardReset(
+	ctx context.Context,
 	params interceptor.GitPipelineParams,
 	targetRepository *git.Repository,
 ) (*git.Worktree, error) {
-	targetBranch := params.RemoteTarget.Spec.TargetBranch
-	targetBranchRef := plumbing.NewBranchReferenceName(targetBranch)
-	upstreamRemoteRef := plumbing.ReferenceName(fmt.Sprintf("refs/remotes/%s/%s", upstreamRemote, params.Syncer.Spec.DefaultBranch))
+	upstreamRemoteRef := plumbing.ReferenceName(fmt.Sprintf("refs/remotes/%s/%s", upstreamRemote, params.RemoteTarget.Spec.UpstreamBranch))
 
-	remErr := fetchUpstream(params, targetRepository)
+	remErr := fetchUpstream(ctx, params, targetRepository)
 	if remErr != nil {
 		return nil, remErr
 	}
 
-	worktree, err := targetRepository.Worktree()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get worktree: %w", err)
-	}
-
 	upstreamLastCommitRef, err := targetRepository.Reference(upstreamRemoteRef, true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find remote reference %s: %w", upstreamRemoteRef.String(), err)
 	}
-	err = worktree.Checkout(&git.CheckoutOptions{
-		Hash: upstreamLastCommitRef.Hash(),
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to checkout upstream commit: %w", err)
-	}
 
-	err = targetRepository.Storer.SetReference(plumbing.NewHashReference(targetBranchRef, upstreamLastCommitRef.Hash()))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create local branch %s: %w", targetBranchRef.String(), err)
-	}
-
-	err = checkoutToBranch(targetRepository, worktree, targetBranch)
-	if err != nil {
-		return nil, err
-	}
-
-	if err := worktree.Reset(&git.ResetOptions{
-		Commit: upstreamLastCommitRef.Hash(),
-		Mode:   git.HardReset,
-	}); err != nil {
-		return nil, fmt.Errorf("failed to hard reset: %w", err)
-	}
-
-	return worktree, nil
+	return resetTargetBranch(targetRepository, params, upstreamLastCommitRef.Hash())
 }
 
 func upstreamBasedPullFastForward(
+	ctx context.Context,
 	params interceptor.GitPipelineParams,
 	targetRepository *git.Repository,
 ) (*git.Worktree, error) {
 	upstreamBranch := params.RemoteTarget.Spec.UpstreamBranch
 	targetBranch := params.RemoteTarget.Spec.TargetBranch
 
-	remErr := fetchUpstream(params, targetRepository)
+	remErr := fetchUpstream(ctx, params, targetRepository)
 	if remErr != nil {
 		return nil, remErr
 	}
@@ -132,24 +118,35 @@ func upstreamBasedPullFastForward(
 		return nil, err
 	}
 
+	upstreamAuth, err := authMethod(params.GitUserInfo, params.Syncer.Spec.RemoteRepository)
+	if err != nil {
+		return nil, err
+	}
+	originAuth, err := authMethod(params.GitUserInfo, params.RemoteTarget.Spec.TargetRepository)
+	if err != nil {
+		return nil, err
+	}
+
 	// STEP 1 : Pull the upstream's commits
 	var verboseOutput bytes.Buffer
 	upstreamBasedPullOptions := &git.PullOptions{
-		RemoteName:    upstreamRemote,
-		ReferenceName: plumbing.NewBranchReferenceName(upstreamBranch),
-		SingleBranch:  true,
-		Auth: &http.BasicAuth{
-			Username: params.GitUserInfo.User,
-			Password: params.GitUserInfo.Token,
-		},
+		RemoteName:      upstreamRemote,
+		ReferenceName:   plumbing.NewBranchReferenceName(upstreamBranch),
+		SingleBranch:    true,
+		Auth:            upstreamAuth,
 		InsecureSkipTLS: params.Syncer.Spec.InsecureSkipTlsVerify,
+		ProxyOptions:    proxyOptions(params.Transport),
+		ClientCert:      params.Transport.ClientCert,
+		ClientKey:       params.Transport.ClientKey,
 		Progress:        io.MultiWriter(&verboseOutput),
 	}
 	if params.CABundle != nil {
 		upstreamBasedPullOptions.CABundle = params.CABundle
 	}
-	err = targetWorktree.Pull(upstreamBasedPullOptions)
-	if err != nil && err != git.NoErrAlreadyUpToDate {
+	err = withRetries(ctx, networkRetries, func() error {
+		return ignoreUpToDate(targetWorktree.PullContext(ctx, upstreamBasedPullOptions))
+	})
+	if err != nil {
 		variables := fmt.Sprintf("\nRemote: %s\nUpstream ref: %s\nReference: %s\nUsername: %s\nEmail: %s\n",
 			upstreamRemote,
 			plumbing.HEAD,
@@ -158,28 +155,30 @@ func upstreamBasedPullFastForward(
 			params.GitUserInfo.Email,
 		)
 		return nil, fmt.Errorf(
-			"failed to pull remote: %v\nVerbose output: %s\nVariables: %s",
+			"failed to pull remote: %w\nVerbose output: %s\nVariables: %s",
 			err, verboseOutput.String(), variables,
 		)
 	}
 
 	// STEP 2 : Pull the origin's commits
 	originBasedPullOptions := &git.PullOptions{
-		RemoteName:    originRemote,
-		ReferenceName: plumbing.NewBranchReferenceName(targetBranch),
-		SingleBranch:  true,
-		Auth: &http.BasicAuth{
-			Username: params.GitUserInfo.User,
-			Password: params.GitUserInfo.Token,
-		},
+		RemoteName:      originRemote,
+		ReferenceName:   plumbing.NewBranchReferenceName(targetBranch),
+		SingleBranch:    true,
+		Auth:            originAuth,
 		InsecureSkipTLS: params.Syncer.Spec.InsecureSkipTlsVerify,
+		ProxyOptions:    proxyOptions(params.Transport),
+		ClientCert:      params.Transport.ClientCert,
+		ClientKey:       params.Transport.ClientKey,
 		Progress:        io.MultiWriter(&verboseOutput),
 	}
 	if params.CABundle != nil {
 		originBasedPullOptions.CABundle = params.CABundle
 	}
-	err = targetWorktree.Pull(originBasedPullOptions)
-	if err != nil && err != git.NoErrAlreadyUpToDate && !strings.Contains(err.Error(), "reference not found") {
+	err = withRetries(ctx, networkRetries, func() error {
+		return ignoreUpToDate(targetWorktree.PullContext(ctx, originBasedPullOptions))
+	})
+	if err != nil && !strings.Contains(err.Error(), "reference not found") {
 		variables := fmt.Sprintf("\nRemote: %s\nUpstream ref: %s\nReference: %s\nUsername: %s\nEmail: %s\n",
 			upstreamRemote,
 			plumbing.HEAD,
@@ -188,7 +187,7 @@ func upstreamBasedPullFastForward(
 			params.GitUserInfo.Email,
 		)
 		return nil, fmt.Errorf(
-			"failed to pull target remote: %v\nVerbose output: %s\nVariables: %s",
+			"failed to pull target remote: %w\nVerbose output: %s\nVariables: %s",
 			err, verboseOutput.String(), variables,
 		)
 	}
@@ -285,9 +284,12 @@ func checkoutToBranch(targetRepository *git.Repository, worktree *git.Worktree,
 	return nil
 }
 
-func fetchUpstream(params interceptor.GitPipelineParams, targetRepository *git.Repository) error {
+// fetchUpstream fetches the upstream branch of the RemoteTarget, and only this
+// one, into the upstream remote of the target repository.
+func fetchUpstream(ctx context.Context, params interceptor.GitPipelineParams, targetRepository *git.Repository) error {
 
 	upstreamURL := params.Syncer.Spec.RemoteRepository
+	upstreamBranch := params.RemoteTarget.Spec.UpstreamBranch
 
 	if _, remErr := targetRepository.Remote(upstreamRemote); remErr == git.ErrRemoteNotFound {
 		_, err := targetRepository.CreateRemote(&config.RemoteConfig{
@@ -301,34 +303,89 @@ func fetchUpstream(params interceptor.GitPipelineParams, targetRepository *git.R
 		return fmt.Errorf("failed to get upstream remote: %w", remErr)
 	}
 
+	auth, err := authMethod(params.GitUserInfo, upstreamURL)
+	if err != nil {
+		return err
+	}
+
 	var verboseOutput bytes.Buffer
 	fetchOptions := &git.FetchOptions{
 		RemoteName: upstreamRemote,
 		RemoteURL:  upstreamURL,
-		Auth: &http.BasicAuth{
-			Username: params.GitUserInfo.User,
-			Password: params.GitUserInfo.Token,
-		},
+		Auth:       auth,
 		RefSpecs: []config.RefSpec{
-			config.RefSpec("+refs/heads/*:refs/remotes/origin/*"),
-			config.RefSpec("+refs/heads/*:refs/remotes/upstream/*"),
+			config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/remotes/%s/%s", upstreamBranch, upstreamRemote, upstreamBranch)),
 		},
 		InsecureSkipTLS: params.Syncer.Spec.InsecureSkipTlsVerify,
+		ProxyOptions:    proxyOptions(params.Transport),
+		ClientCert:      params.Transport.ClientCert,
+		ClientKey:       params.Transport.ClientKey,
 		Progress:        io.MultiWriter(&verboseOutput),
 	}
 	if params.CABundle != nil {
 		fetchOptions.CABundle = params.CABundle
 	}
 
-	err := targetRepository.Fetch(fetchOptions)
-	if err != nil && err != git.NoErrAlreadyUpToDate {
+	err = withRetries(ctx, networkRetries, func() error {
+		return ignoreUpToDate(targetRepository.FetchContext(ctx, fetchOptions))
+	})
+	if err != nil {
 		variables := fmt.Sprintf("\nRepository: %s\nUsername: %s\nEmail: %s\n",
 			upstreamURL,
 			params.GitUserInfo.User,
 			params.GitUserInfo.Email,
 		)
-		return fmt.Errorf("failed to fetch remote: %v\nVerbose output: %s\nVariables: %s", err, verboseOutput.String(), variables)
+		return fmt.Errorf("failed to fetch remote: %w\nVerbose output: %s\nVariables: %s", err, verboseOutput.String(), variables)
 	}
 
 	return nil
 }
+
+// fetchTargetBranch fetches the target branch of params, and only this one,
+// into the origin remote of the target repository. It returns the commit the
+// branch is on, or the zero hash when the branch does not exist yet.
+func fetchTargetBranch(ctx context.Context, params interceptor.GitPipelineParams, targetRepository *git.Repository) (plumbing.Hash, error) {
+	repositoryParams := targetRepositoryParams(params)
+	branchRef := plumbing.NewBranchReferenceName(params.RemoteTarget.Spec.TargetBranch)
+	remoteTrackingRef := plumbing.NewRemoteReferenceName(originRemote, params.RemoteTarget.Spec.TargetBranch)
+
+	auth, err := repositoryParams.auth()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	var verboseOutput bytes.Buffer
+	fetchOptions := &git.FetchOptions{
+		RemoteName: originRemote,
+		RemoteURL:  repositoryParams.Repository,
+		Auth:       auth,
+		RefSpecs: []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+%s:%s", branchRef, remoteTrackingRef)),
+		},
+		Depth:           repositoryParams.Depth,
+		InsecureSkipTLS: params.Syncer.Spec.InsecureSkipTlsVerify,
+		ProxyOptions:    proxyOptions(params.Transport),
+		ClientCert:      params.Transport.ClientCert,
+		ClientKey:       params.Transport.ClientKey,
+		Progress:        io.MultiWriter(&verboseOutput),
+		Force:           true,
+	}
+	if params.CABundle != nil {
+		fetchOptions.CABundle = params.CABundle
+	}
+	err = withRetries(ctx, networkRetries, func() error {
+		return ignoreUpToDate(targetRepository.FetchContext(ctx, fetchOptions))
+	})
+	if errors.Is(err, git.NoMatchingRefSpecError{}) {
+		return plumbing.ZeroHash, nil
+	}
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to fetch the target branch: %w\nVerbose output: %s", err, verboseOutput.String())
+	}
+
+	remoteRef, err := targetRepository.Reference(remoteTrackingRef, true)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("failed to resolve %s: %w", remoteTrackingRef.String(), err)
+	}
+	return remoteRef.Hash(), nil
+}
@@ -37,6 +37,9 @@ func ReplaceDocInContent(content []byte, sel ObjectSelector, newDoc []byte) ([]b
 // newDoc before it is substituted. The transform receives the document being
 // replaced as its existing content, so it sees exactly the bytes that this
 // object currently occupies in the file rather than the whole file.
+//
+// newDoc is merged onto the document it replaces, so that its comments, key
+// order and formatting survive the rewrite.
 func ReplaceDocInContentFunc(content []byte, sel ObjectSelector, newDoc []byte, relPath string, transform DocTransform) ([]byte, bool, error) {
 	docs := bytes.Split(content, docSeparator)
 
@@ -54,7 +57,8 @@ func ReplaceDocInContentFunc(content []byte, sel ObjectSelector, newDoc []byte,
 	if len(newDoc) == 0 {
 		docs = append(docs[:matched], docs[matched+1:]...)
 	} else {
-		transformed, err := transform.Apply(relPath, docs[matched], newDoc)
+		merged := mergeDoc(docs[matched], newDoc)
+		transformed, err := transform.Apply(relPath, docs[matched], merged)
 		if err != nil {
 			return content, true, err
 		}
@@ -0,0 +1,223 @@
+package walker
+
+import (
+	"bytes"
+	"reflect"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// listItemKeys are the fields that identify an item of a list, in the order
+// they are looked up, as the merge keys of the Kubernetes lists: containers,
+// env and volumes by name, ports by containerPort, volumeMounts by mountPath...
+var listItemKeys = []string{"name", "containerPort", "port", "mountPath", "devicePath", "ip", "type", "key"}
+
+// mergeDoc applies the values of newDoc onto the existing document and returns
+// the result. Only the values that changed are rewritten: the comments, the
+// key order, the quoting style and the indentation of the existing document
+// are kept. The keys that are new are appended after the existing ones, and
+// the keys that newDoc does not hold anymore are removed.
+//
+// newDoc is returned as is when one of the documents is not a YAML mapping,
+// and existing is returned as is when both hold the same values.
+func mergeDoc(existing, newDoc []byte) []byte {
+	var existingValue, newValue interface{}
+	if yaml.Unmarshal(existing, &existingValue) != nil || yaml.Unmarshal(newDoc, &newValue) != nil {
+		return newDoc
+	}
+	if reflect.DeepEqual(existingValue, newValue) {
+		return existing
+	}
+
+	var existingNode, newNode yaml.Node
+	if yaml.Unmarshal(existing, &existingNode) != nil || yaml.Unmarshal(newDoc, &newNode) != nil {
+		return newDoc
+	}
+	if !isMappingDoc(&existingNode) || !isMappingDoc(&newNode) {
+		return newDoc
+	}
+	mergeNode(existingNode.Content[0], newNode.Content[0])
+
+	var out bytes.Buffer
+	if bytes.HasPrefix(bytes.TrimLeft(existing, "\n"), []byte("---")) {
+		// The separator of the first document of a file is part of it.
+		out.WriteString("---\n")
+	}
+	encoder := yaml.NewEncoder(&out)
+	indent, compact := detectIndentation(existingNode.Content[0])
+	encoder.SetIndent(indent)
+	if compact {
+		encoder.CompactSeqIndent()
+	}
+	if encoder.Encode(&existingNode) != nil || encoder.Close() != nil {
+		return newDoc
+	}
+	return out.Bytes()
+}
+
+func isMappingDoc(node *yaml.Node) bool {
+	return node.Kind == yaml.DocumentNode && len(node.Content) == 1 && node.Content[0].Kind == yaml.MappingNode
+}
+
+// mergeNode rewrites dst with the values of src, reusing the nodes of dst
+// wherever src did not change them.
+func mergeNode(dst, src *yaml.Node) {
+	switch {
+	case dst.Kind == yaml.MappingNode && src.Kind == yaml.MappingNode:
+		mergeMapping(dst, src)
+	case dst.Kind == yaml.SequenceNode && src.Kind == yaml.SequenceNode:
+		mergeSequence(dst, src)
+	case dst.Kind == yaml.ScalarNode && src.Kind == yaml.ScalarNode:
+		if dst.ShortTag() == src.ShortTag() && dst.Value == src.Value {
+			return
+		}
+		if dst.ShortTag() != src.ShortTag() {
+			// The quoting of the existing value may not fit the new type.
+			dst.Tag = src.Tag
+			dst.Style = src.Style
+		}
+		dst.Value = src.Value
+	default:
+		replaceNode(dst, src)
+	}
+}
+
+// mergeMapping keeps the keys of dst that src still holds, in their order,
+// followed by the keys that only src holds.
+func mergeMapping(dst, src *yaml.Node) {
+	srcValues := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		srcValues[src.Content[i].Value] = src.Content[i+1]
+	}
+
+	content := make([]*yaml.Node, 0, len(src.Content))
+	kept := map[string]bool{}
+	for i := 0; i+1 < len(dst.Content); i += 2 {
+		key, value := dst.Content[i], dst.Content[i+1]
+		srcValue, ok := srcValues[key.Value]
+		if !ok || kept[key.Value] {
+			continue
+		}
+		mergeNode(value, srcValue)
+		content = append(content, key, value)
+		kept[key.Value] = true
+	}
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		if !kept[src.Content[i].Value] {
+			content = append(content, src.Content[i], src.Content[i+1])
+		}
+	}
+	dst.Content = content
+}
+
+// mergeSequence pairs every item of src with the item of dst that has the
+// same identity, or with the item at the same position when neither of them
+// has one. The items are kept in the order of src.
+func mergeSequence(dst, src *yaml.Node) {
+	used := make([]bool, len(dst.Content))
+	content := make([]*yaml.Node, 0, len(src.Content))
+	for index, srcItem := range src.Content {
+		dstIndex := matchingItem(dst.Content, used, srcItem, index)
+		if dstIndex == -1 {
+			content = append(content, srcItem)
+			continue
+		}
+		used[dstIndex] = true
+		mergeNode(dst.Content[dstIndex], srcItem)
+		content = append(content, dst.Content[dstIndex])
+	}
+	dst.Content = content
+}
+
+func matchingItem(items []*yaml.Node, used []bool, item *yaml.Node, index int) int {
+	identity := itemIdentity(item)
+	for i, candidate := range items {
+		if used[i] {
+			continue
+		}
+		if identity != "" && itemIdentity(candidate) == identity {
+			return i
+		}
+		if item.Kind == yaml.ScalarNode && candidate.Kind == yaml.ScalarNode && candidate.Value == item.Value {
+			return i
+		}
+	}
+	if index < len(items) && !used[index] && items[index].Kind == item.Kind &&
+		identity == "" && itemIdentity(items[index]) == "" {
+		return index
+	}
+	return -1
+}
+
+// itemIdentity returns the first identifying field of a list item, or "" when
+// it has none.
+func itemIdentity(item *yaml.Node) string {
+	if item.Kind != yaml.MappingNode {
+		return ""
+	}
+	for _, name := range listItemKeys {
+		for i := 0; i+1 < len(item.Content); i += 2 {
+			if item.Content[i].Value == name && item.Content[i+1].Kind == yaml.ScalarNode {
+				return name + "=" + item.Content[i+1].Value
+			}
+		}
+	}
+	return ""
+}
+
+// replaceNode replaces dst by src, keeping the comments of dst.
+func replaceNode(dst, src *yaml.Node) {
+	headComment, lineComment, footComment := dst.HeadComment, dst.LineComment, dst.FootComment
+	*dst = *src
+	if dst.HeadComment == "" {
+		dst.HeadComment = headComment
+	}
+	if dst.LineComment == "" {
+		dst.LineComment = lineComment
+	}
+	if dst.FootComment == "" {
+		dst.FootComment = footComment
+	}
+}
+
+// detectIndentation returns the indentation of the nested mappings of the
+// document, and whether its lists are indented at the level of their key.
+// It defaults to 2 spaces and to the lists indented at the level of their
+// key, as kubectl writes them.
+func detectIndentation(root *yaml.Node) (int, bool) {
+	indent, compact := 0, true
+	foundSequence := false
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Style&yaml.FlowStyle != 0 {
+				continue
+			}
+			switch value.Kind {
+			case yaml.MappingNode:
+				if indent == 0 && len(value.Content) > 0 && value.Content[0].Line > key.Line {
+					indent = value.Content[0].Column - key.Column
+				}
+				walk(value)
+			case yaml.SequenceNode:
+				if !foundSequence && len(value.Content) > 0 && value.Content[0].Line > key.Line {
+					foundSequence = true
+					// The column of an item is the one of its content, after the "- ".
+					compact = value.Content[0].Column-2 == key.Column
+				}
+				for _, item := range value.Content {
+					walk(item)
+				}
+			}
+		}
+	}
+	walk(root)
+	if indent <= 0 {
+		indent = 2
+	}
+	return indent, compact
+}
@@ -0,0 +1,186 @@
+package walker
+
+import (
+	"testing"
+)
+
+const handWrittenDeploymentYAML = `# The web frontend.
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+  labels:
+    app: web # used by the service
+spec:
+  # Scaled by hand until the HPA lands.
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: web
+        image: 'web:1.2.0'
+        ports:
+        - containerPort: 8080
+      - name: sidecar
+        image: "proxy:2.0.0"
+`
+
+func TestMergeDoc(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		newDoc   string
+		want     string
+	}{
+		{
+			name:     "a changed value keeps comments, key order and quoting",
+			existing: handWrittenDeploymentYAML,
+			newDoc: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  labels:
+    app: web
+  name: web
+  namespace: default
+spec:
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - image: web:1.3.0
+        name: web
+        ports:
+        - containerPort: 8080
+      - image: proxy:2.0.0
+        name: sidecar
+`,
+			want: `# The web frontend.
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+  labels:
+    app: web # used by the service
+spec:
+  # Scaled by hand until the HPA lands.
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: web
+        image: 'web:1.3.0'
+        ports:
+        - containerPort: 8080
+      - name: sidecar
+        image: "proxy:2.0.0"
+`,
+		},
+		{
+			name:     "list items are matched by name, new keys are appended and removed keys dropped",
+			existing: handWrittenDeploymentYAML,
+			newDoc: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+spec:
+  replicas: 2
+  strategy:
+    type: Recreate
+  template:
+    spec:
+      containers:
+      - image: proxy:2.0.0
+        name: sidecar
+      - image: web:1.2.0
+        name: web
+        ports:
+        - containerPort: 8080
+`,
+			want: `# The web frontend.
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+spec:
+  # Scaled by hand until the HPA lands.
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: sidecar
+        image: "proxy:2.0.0"
+      - name: web
+        image: 'web:1.2.0'
+        ports:
+        - containerPort: 8080
+  strategy:
+    type: Recreate
+`,
+		},
+		{
+			name: "the indentation of the existing document is kept",
+			existing: `apiVersion: v1
+kind: ConfigMap
+metadata:
+    name: settings
+data:
+    # The mode of the application.
+    mode: "fast"
+    hosts:
+        - a.example.com
+`,
+			newDoc: `apiVersion: v1
+data:
+  hosts:
+  - a.example.com
+  - b.example.com
+  mode: fast
+kind: ConfigMap
+metadata:
+  name: settings
+`,
+			want: `apiVersion: v1
+kind: ConfigMap
+metadata:
+    name: settings
+data:
+    # The mode of the application.
+    mode: "fast"
+    hosts:
+        - a.example.com
+        - b.example.com
+`,
+		},
+		{
+			name:     "the same values leave the document untouched",
+			existing: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n\n\ndata: {mode: fast}  # inline\n",
+			newDoc:   "apiVersion: v1\ndata:\n  mode: fast\nkind: ConfigMap\nmetadata:\n  name: settings\n",
+			want:     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n\n\ndata: {mode: fast}  # inline\n",
+		},
+		{
+			name:     "a value whose type changed is requoted",
+			existing: "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  port: '8080'\n",
+			newDoc:   "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  port: 8081\n",
+			want:     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\ndata:\n  port: 8081\n",
+		},
+		{
+			name:     "a document that is not a mapping is replaced",
+			existing: handWrittenDeploymentYAML,
+			newDoc:   "REPLACED\n",
+			want:     "REPLACED\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := string(mergeDoc([]byte(tc.existing), []byte(tc.newDoc)))
+			if got != tc.want {
+				t.Errorf("mergeDoc:\n--- got\n%s\n--- want\n%s", got, tc.want)
+			}
+		})
+	}
+}
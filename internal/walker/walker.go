@@ -4,6 +4,7 @@
 //   - worktree_fs.go reading/writing/removing worktree files,
 //   - selector.go    the ObjectSelector type and document matching,
 //   - document.go    in-memory replacement/append of YAML documents,
+//   - merge.go       the comment-preserving merge of a rewritten document,
 //   - object.go      the public object-level API (FindObject, ReplaceObject,
 //     WriteObjectAtPath) layered on top of the above.
 package walker
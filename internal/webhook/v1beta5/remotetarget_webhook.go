@@ -63,6 +63,13 @@ func validateRemoteTargetSpec(r *syngitv1beta5.RemoteTargetSpec) field.ErrorList
 		).Error()))
 	}
 
+	// Validate the ChangeRequest delivery mode
+	if r.DeliveryMode == syngitv1beta5.ChangeRequestDelivery && r.ChangeRequest == nil {
+		errors = append(errors, field.Required(field.NewPath("spec").Child("changeRequest"), syngiterrors.NewWrongRemoteSyncerConfig(
+			"should be set when the delivery mode is ChangeRequest",
+		).Error()))
+	}
+
 	return errors
 }
 
@@ -35,7 +35,7 @@ func (ruwh *RemoteUserPermissionsWebhookHandler) Handle(ctx context.Context, req
 		return admission.Errored(http.StatusBadRequest, err)
 	}
 
-	// The user must be allowed to get the referenced Secret, wherever it lives.
+	// The user must be allowed to get the referenced Secrets, wherever they live.
 	// Its own namespace is checked too: being able to create a RemoteUser must
 	// not become a way to use credentials it cannot read.
 	objectRefs, err := refs.RemoteUserRefs(ru.Spec, ru.GetNamespace())
@@ -50,7 +50,7 @@ func (ruwh *RemoteUserPermissionsWebhookHandler) Handle(ctx context.Context, req
 	if denied != nil {
 		return denyRef(user, denied, ru.GetNamespace(), syngiterrors.NewCredentialsNotFound(
 			fmt.Sprintf("the user %s is not allowed to get the secret for its own remote user", user),
-			ru.Spec.SecretRef.Name,
+			denied.Name,
 		))
 	}
 
@@ -21,9 +21,11 @@ import (
 	"regexp"
 	"slices"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	syngitv1beta5 "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	"github.com/syngit-org/syngit/pkg/interceptor"
 )
 
 // validateSyncer is the validation shared by RemoteSyncer and
@@ -67,13 +69,14 @@ func validateRemoteSyncerSpec(r *syngitv1beta5.RemoteSyncerSpec) field.ErrorList
 		errors = append(errors, field.Forbidden(field.NewPath("spec").Child("defaultBlockAppliedMessage"), fmt.Sprintf("should not be set if strategy is not set to \"%s\"", syngitv1beta5.CommitOnly)))
 	}
 
-	// Validate that Strategy is either CommitApply or CommitOnly
-	if r.Strategy != syngitv1beta5.CommitOnly && r.Strategy != syngitv1beta5.CommitApply {
-		errors = append(errors, field.Invalid(field.NewPath("spec").Child("strategy"), r.Strategy, fmt.Sprintf("must be set to \"%s\" or \"%s\"", syngitv1beta5.CommitApply, syngitv1beta5.CommitOnly)))
+	// Validate that Strategy is either CommitApply, CommitOnly or ApplyThenCommit
+	if r.Strategy != syngitv1beta5.CommitOnly && r.Strategy != syngitv1beta5.CommitApply &&
+		r.Strategy != syngitv1beta5.ApplyThenCommit {
+		errors = append(errors, field.Invalid(field.NewPath("spec").Child("strategy"), r.Strategy, fmt.Sprintf("must be set to \"%s\", \"%s\" or \"%s\"", syngitv1beta5.CommitApply, syngitv1beta5.CommitOnly, syngitv1beta5.ApplyThenCommit)))
 	}
 
-	// Validate Git URI
-	gitURIPattern := regexp.MustCompile(`^(https?|git)\://[^ ]+$`)
+	// Validate Git URI. The scp-like syntax of SSH remotes is accepted as well.
+	gitURIPattern := regexp.MustCompile(`^((https?|git|ssh)\://[^ ]+|([^@/: ]+@)?[^@/: ]+:[^ ]+)$`)
 	if !gitURIPattern.MatchString(r.RemoteRepository) {
 		errors = append(errors, field.Invalid(field.NewPath("spec").Child("remoteRepository"), r.RemoteRepository, "invalid Git URI"))
 	}
@@ -90,6 +93,49 @@ func validateRemoteSyncerSpec(r *syngitv1beta5.RemoteSyncerSpec) field.ErrorList
 		errors = append(errors, field.Required(field.NewPath("spec").Child("defaultBranch"), "must be set when the defaultUnauthorizedUserMode is set to UseDefaultUser"))
 	}
 
+	// Validate that the commit message template and trailers can be rendered
+	if err := interceptor.ValidateCommitMessage(syngitv1beta5.RemoteSyncerSpec{CommitMessageTemplate: r.CommitMessageTemplate}); err != nil {
+		errors = append(errors, field.Invalid(field.NewPath("spec").Child("commitMessageTemplate"), r.CommitMessageTemplate, err.Error()))
+	}
+	for i, trailer := range r.CommitTrailers.Custom {
+		trailerSpec := syngitv1beta5.RemoteSyncerSpec{CommitTrailers: syngitv1beta5.CommitTrailers{Custom: []syngitv1beta5.CommitTrailer{trailer}}}
+		if err := interceptor.ValidateCommitMessage(trailerSpec); err != nil {
+			errors = append(errors, field.Invalid(field.NewPath("spec").Child("commitTrailers").Child("custom").Index(i).Child("value"), trailer.Value, err.Error()))
+		}
+	}
+
+	// Validate that the path template renders paths within the rootPath
+	if r.PathTemplate != "" {
+		if err := interceptor.ValidatePathTemplate(r.PathTemplate); err != nil {
+			errors = append(errors, field.Invalid(field.NewPath("spec").Child("pathTemplate"), r.PathTemplate, err.Error()))
+		}
+	}
+
+	// Validate the routing rules
+	for i, rule := range r.RoutingRules {
+		rulePath := field.NewPath("spec").Child("routingRules").Index(i)
+		if rule.Match.LabelSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(rule.Match.LabelSelector); err != nil {
+				errors = append(errors, field.Invalid(rulePath.Child("match").Child("labelSelector"), rule.Match.LabelSelector, err.Error()))
+			}
+		}
+		if rule.Match.Expression != "" {
+			if _, err := interceptor.CompileRoutingExpression(rule.Match.Expression); err != nil {
+				errors = append(errors, field.Invalid(rulePath.Child("match").Child("expression"), rule.Match.Expression, err.Error()))
+			}
+		}
+		if rule.PathTemplate != "" {
+			if err := interceptor.ValidatePathTemplate(rule.PathTemplate); err != nil {
+				errors = append(errors, field.Invalid(rulePath.Child("pathTemplate"), rule.PathTemplate, err.Error()))
+			}
+		}
+		if rule.RemoteTargetSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(rule.RemoteTargetSelector); err != nil {
+				errors = append(errors, field.Invalid(rulePath.Child("remoteTargetSelector"), rule.RemoteTargetSelector, err.Error()))
+			}
+		}
+	}
+
 	// Referencing another namespace is allowed, but the user must be allowed to get
 	// the referenced object. This is enforced by the syncer rules permissions webhooks.
 
@@ -0,0 +1,182 @@
+/*
+Copyright 2026.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta5
+
+import (
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	PcNamePrefix = "pc"
+
+	// The value is a hash of the target repository and branch: both are needed
+	// to select the queue of a branch, and neither fits in a label value.
+	PcLabelKeyTarget = "syngit.io/pendingcommit.target"
+
+	// The key of the manifest in the Secret of a PendingCommit.
+	PcSecretKeyManifest = "manifest"
+
+	// The condition of a PendingCommit that is not replayed anymore.
+	PcConditionTypeFailed = "Failed"
+)
+
+// PendingCommitSpec defines a commit that could not be pushed and waits
+// to be replayed.
+type PendingCommitSpec struct {
+
+	// syncerRef is the RemoteSyncer or the ClusterWideRemoteSyncer
+	// that intercepted the object.
+	// +kubebuilder:validation:Required
+	SyncerRef corev1.ObjectReference `json:"syncerRef" protobuf:"bytes,1,name=syncerRef"`
+
+	// interceptedNamespace is the namespace of the intercepted object.
+	// It is empty when the object is cluster-scoped.
+	// +kubebuilder:validation:Optional
+	InterceptedNamespace string `json:"interceptedNamespace,omitempty" protobuf:"bytes,opt,2,name=interceptedNamespace"`
+
+	// remoteTargetRef is the RemoteTarget the commit is pushed to.
+	// +kubebuilder:validation:Required
+	RemoteTargetRef corev1.ObjectReference `json:"remoteTargetRef" protobuf:"bytes,3,name=remoteTargetRef"`
+
+	// remoteUserRef is the RemoteUser whose credentials push the commit.
+	// The credentials themselves are read again when the commit is replayed.
+	// +kubebuilder:validation:Required
+	RemoteUserRef corev1.ObjectReference `json:"remoteUserRef" protobuf:"bytes,4,name=remoteUserRef"`
+
+	// author is the Kubernetes user that made the intercepted change.
+	// +kubebuilder:validation:Optional
+	Author authenticationv1.UserInfo `json:"author,omitempty" protobuf:"bytes,opt,5,name=author"`
+
+	// operation is the operation made on the intercepted object.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=CREATE;UPDATE;DELETE
+	Operation string `json:"operation" protobuf:"bytes,6,name=operation"`
+
+	// object identifies the intercepted object.
+	// +kubebuilder:validation:Required
+	Object JsonGVRN `json:"object" protobuf:"bytes,7,name=object"`
+
+	// repository is the target repository of the RemoteTarget
+	// at the time the object was intercepted.
+	// +kubebuilder:validation:Required
+	Repository string `json:"repository" protobuf:"bytes,9,name=repository"`
+
+	// branch is the target branch of the RemoteTarget
+	// at the time the object was intercepted.
+	// +kubebuilder:validation:Required
+	Branch string `json:"branch" protobuf:"bytes,10,name=branch"`
+
+	// enqueueTime orders the commits pending on the same branch.
+	// +kubebuilder:validation:Required
+	EnqueueTime metav1.MicroTime `json:"enqueueTime" protobuf:"bytes,11,name=enqueueTime"`
+
+	// admissionUID is the UID of the intercepted admission request.
+	// +kubebuilder:validation:Optional
+	AdmissionUID types.UID `json:"admissionUID,omitempty" protobuf:"bytes,opt,12,name=admissionUID,casttype=k8s.io/apimachinery/pkg/types.UID"`
+
+	// pathMetadata is the metadata of the intercepted object that the
+	// pathTemplate of the syncer can use. The one of a deletion cannot be
+	// read from its manifest.
+	// +kubebuilder:validation:Optional
+	PathMetadata PathMetadata `json:"pathMetadata,omitempty" protobuf:"bytes,opt,13,name=pathMetadata"`
+
+	// routingRule is the name of the routing rule of the syncer that the
+	// intercepted object matched. Empty when it matched none.
+	// +kubebuilder:validation:Optional
+	RoutingRule string `json:"routingRule,omitempty" protobuf:"bytes,opt,14,name=routingRule"`
+
+	// oldPathMetadata is the metadata the intercepted object had before an
+	// update, from which the path of its previous file is rendered.
+	// +kubebuilder:validation:Optional
+	OldPathMetadata *PathMetadata `json:"oldPathMetadata,omitempty" protobuf:"bytes,opt,15,name=oldPathMetadata"`
+
+	// previousPlacement is where the intercepted object was placed before an
+	// update that changed the routing rule it matches.
+	// +kubebuilder:validation:Optional
+	PreviousPlacement *PathPlacement `json:"previousPlacement,omitempty" protobuf:"bytes,opt,16,name=previousPlacement"`
+}
+
+// PendingCommitStatus defines the observed state of PendingCommit.
+type PendingCommitStatus struct {
+
+	// attempts is the number of times the commit has been replayed without success.
+	// +optional
+	Attempts int `json:"attempts,omitempty" protobuf:"bytes,opt,1,name=attempts"`
+
+	// lastAttemptTime is the time of the last replay.
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty" protobuf:"bytes,opt,2,name=lastAttemptTime"`
+
+	// lastError is the error returned by the last replay.
+	// +optional
+	LastError string `json:"lastError,omitempty" protobuf:"bytes,opt,3,name=lastError"`
+
+	// conditions represent the current state of the PendingCommit.
+	// A Failed condition marks a commit that cannot be pushed and is not
+	// replayed anymore: it is kept to be inspected and does not hold back
+	// the commits queued behind it.
+	// +listType=map
+	// +listMapKey=type
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,4,rep,name=conditions"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="Repository",type=string,JSONPath=`.spec.repository`,priority=0
+// +kubebuilder:printcolumn:name="Branch",type=string,JSONPath=`.spec.branch`,priority=0
+// +kubebuilder:printcolumn:name="Object",type=string,JSONPath=`.spec.object.name`,priority=0
+// +kubebuilder:printcolumn:name="Attempts",type=integer,JSONPath=`.status.attempts`,priority=0
+// +kubebuilder:printcolumn:name="Last error",type=string,JSONPath=`.status.lastError`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=string,JSONPath=`.metadata.creationTimestamp`,priority=0
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=pendingcommits,shortName=pc;pcs,categories=syngit
+// +kubebuilder:storageversion
+
+// PendingCommit is the Schema for the pendingcommits API.
+// It is created in the namespace of the operator when a commit of a
+// syncer using the BlockCacheCommit push error behavior cannot be pushed.
+// The rendered manifest of the intercepted object, which may hold the data
+// of a Secret, is stored in the Secret of the same name that it owns.
+type PendingCommit struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PendingCommitSpec   `json:"spec,omitempty"`
+	Status PendingCommitStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// PendingCommitList contains a list of PendingCommit.
+type PendingCommitList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PendingCommit `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(func(s *runtime.Scheme) error {
+		s.AddKnownTypes(GroupVersion, &PendingCommit{}, &PendingCommitList{})
+		return nil
+	})
+}
@@ -49,9 +49,15 @@ type RemoteSyncerSpec struct {
 	// strategy field specify if the applied Kubernetes object must be
 	// committed and applied (CommitApply) OR only committed (CommitOnly) and blocked
 	// to the Kubernetes API.
+	// ApplyThenCommit applies the object right away and pushes it in the
+	// background: the outcome is reported in the status and the events of the
+	// syncer. The delivery is at most once: the changes that are not pushed yet
+	// when the manager stops, restarts or is evicted are lost, although their
+	// objects have been applied. Only a push that has failed with the
+	// BlockCacheCommit behavior is kept, as a PendingCommit.
 	// +kubebuilder:validation:Required
 	// +kubebuilder:default:value="CommitApply"
-	// +kubebuilder:validation:Enum=CommitOnly;CommitApply
+	// +kubebuilder:validation:Enum=CommitOnly;CommitApply;ApplyThenCommit
 	Strategy Strategy `json:"strategy" protobuf:"bytes,4,name=strategy"`
 
 	// targetStrategy is used to ensure pushing on one or multiple targets.
@@ -93,11 +99,51 @@ type RemoteSyncerSpec struct {
 	// +kubebuilder:validation:Optional
 	ExcludedFieldsConfigMapsRef []*corev1.ObjectReference `json:"excludedFieldsConfigMapsRef,omitempty" protobuf:"bytes,opt,9,name=excludedFieldsConfigMapsRef"` // Ref to ConfigMap(s)
 
+	// ownedFields only pushes the fields of the object that are owned by its
+	// field managers, as recorded in its metadata.managedFields, leaving out
+	// the fields defaulted by the API server. The excludedFields are removed
+	// afterwards.
+	// +kubebuilder:validation:Optional
+	OwnedFields OwnedFields `json:"ownedFields,omitempty" protobuf:"bytes,opt,33,name=ownedFields"`
+
+	// yamlFormat configures the layout of the YAML written for the objects.
+	// Their fields are written in the canonical Kubernetes order: apiVersion,
+	// kind, metadata, then spec or data, then the rest.
+	// +kubebuilder:validation:Optional
+	YAMLFormat YAMLFormat `json:"yamlFormat,omitempty" protobuf:"bytes,opt,34,name=yamlFormat"`
+
 	// rootPath specifies the absolute root path in the remote git repository
 	// where the resources scoped by this RemoteSyncer will be pushed.
 	// +kubebuilder:validation:Optional
 	RootPath string `json:"rootPath,omitempty" protobuf:"bytes,opt,10,name=rootPath"`
 
+	// pathTemplate is a Go text/template that renders the path of the file
+	// of an object, relative to the rootPath. When it does not end with .yaml
+	// or .yml, it is the directory of a "<name>.yaml" file. When empty, the
+	// path is "<namespace>/<group>/<version>/<resource>/<name>.yaml", with
+	// "_cluster" as the namespace of the cluster-scoped objects.
+	// The template is executed with:
+	// .Group, .Version, .Resource, .Kind, .Namespace (empty for a
+	// cluster-scoped object), .Name, .Labels and .Annotations. A label or an
+	// annotation that the object does not have renders empty.
+	// The lower and upper functions are available.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:example="apps/{{ .Namespace }}/{{ lower .Kind }}-{{ .Name }}.yaml"
+	PathTemplate string `json:"pathTemplate,omitempty" protobuf:"bytes,opt,35,name=pathTemplate"`
+
+	// routingRules send the objects they match to their own paths and
+	// RemoteTargets. The rules are tried in order and the first one that
+	// matches the intercepted object overrides the rootPath, the pathTemplate
+	// and the remoteTargetSelector of the syncer, and the target branch of the
+	// RemoteTargets. The objects that no rule matches use the syncer's own.
+	// An update that changes the rule an object matches moves it: it is
+	// deleted from the RemoteTargets of the previous rule, or from its previous
+	// path on the RemoteTargets that both rules push to.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:Optional
+	RoutingRules []RoutingRule `json:"routingRules,omitempty" protobuf:"bytes,rep,36,name=routingRules"`
+
 	// resourceFinder locates the resource amongst the files of the repository.
 	// When the resource is intercepted, the corresponding yaml manifest replaces
 	// the one(s) currently existing in the repository.
@@ -137,11 +183,22 @@ type RemoteSyncerSpec struct {
 	// is reached. Can be one of these values:
 	// - "Block": blocks the action on the kubernetes resource
 	// - "Pass": the action on the kubernetes resource is applied
+	// - "BlockCacheCommit": the commit is stored as a PendingCommit in the
+	//                       namespace of the operator and replayed, in order,
+	//                       once the git repository can be reached again.
+	//                       The action on the kubernetes resource is applied
+	//                       if the strategy is CommitApply.
 	// +kubebuilder:validation:Optional
 	DefaultPushErrorBehavior PushErrorBehavior `json:"defaultPushErrorBehavior,omitempty" protobuf:"bytes,opt,16,name=defaultPushErrorBehavior"`
 
 	// pushErrorRetryNumber is the maximum number of push
 	// retry that will be done when a push error happens.
+	// A push rejected because the branch moved in the meantime is not
+	// retried as is: the commit is rebuilt on top of the new tip of the
+	// branch instead, a bounded number of times.
+	// Only the errors that may not happen again (a rate limit, an unavailable
+	// git server or network) are retried, with an exponential backoff or
+	// after the delay asked by the git server.
 	// +kubebuilder:validation:Optional
 	PushErrorRetryNumber int `json:"pushErrorRetryNumber,omitempty" protobuf:"bytes,opt,17,name=pushErrorRetryNumber"`
 
@@ -182,9 +239,89 @@ type RemoteSyncerSpec struct {
 	// +kubebuilder:validation:Optional
 	CABundleSecretRef corev1.SecretReference `json:"caBundleSecretRef,omitempty" protobuf:"bytes,opt,21,name=caBundleSecretRef"`
 
+	// proxy is the proxy the git server is reached through: an HTTP proxy, or
+	// a SOCKS5 one for the repositories reached over SSH. When unset, the
+	// proxy of the host of the remoteRepository is used, if the
+	// "<host>-proxy" Secret exists in the namespace of syngit: its "url" key
+	// is the URL of the proxy, and its "username" and "password" keys, if
+	// any, authenticate to it.
+	// +kubebuilder:validation:Optional
+	Proxy ProxyConfig `json:"proxy,omitempty" protobuf:"bytes,opt,31,name=proxy"`
+
+	// The clientCertSecretRef is a reference to a secret of type kubernetes.io/tls that stores
+	// the client certificate and key presented to a git server that requires
+	// them (mutual TLS). When unset, the "<host>-client-cert" Secret of the
+	// namespace of syngit is used, if it exists.
+	// If the namespace is not set, it defaults to the namespace of the RemoteSyncer.
+	// Referencing another namespace requires the user to be allowed to get the
+	// referenced object in that namespace.
+	// +kubebuilder:validation:Optional
+	ClientCertSecretRef corev1.SecretReference `json:"clientCertSecretRef,omitempty" protobuf:"bytes,opt,32,name=clientCertSecretRef"`
+
 	// The SOPS field is used to configure the SOPS provider for syngit.
 	// +kubebuilder:validation:Optional
 	SOPS SOPSConfig `json:"sops,omitempty" protobuf:"bytes,opt,22,name=sops"`
+
+	// commitMessageTemplate is a Go text/template that renders the message of
+	// the commits. When empty, the message is "<n>+<m>- resource.group/version: namespace/name".
+	// The template is executed with:
+	// .Operation (CREATE, UPDATE or DELETE), .Group, .Version, .Resource,
+	// .Namespace (empty for a cluster-scoped object), .Name,
+	// .Username and .Groups (the Kubernetes user), .UID (the admission request),
+	// .Syncer, .SyncerNamespace, .Paths.Add, .Paths.Delete and .Default
+	// (the message used without template).
+	// The lower, upper and join functions are available.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:example="{{ if eq .Operation \"DELETE\" }}chore{{ else }}feat{{ end }}({{ .Resource }}): {{ .Namespace }}/{{ .Name }}"
+	CommitMessageTemplate string `json:"commitMessageTemplate,omitempty" protobuf:"bytes,opt,23,name=commitMessageTemplate"`
+
+	// commitTrailers are appended to the message of the commits.
+	// +kubebuilder:validation:Optional
+	CommitTrailers CommitTrailers `json:"commitTrailers,omitempty" protobuf:"bytes,opt,24,name=commitTrailers"`
+
+	// diffPreview returns the diff of the pushed files as warnings of the
+	// admission response, so that kubectl prints what landed in git.
+	// +kubebuilder:validation:Optional
+	DiffPreview DiffPreview `json:"diffPreview,omitempty" protobuf:"bytes,opt,25,name=diffPreview"`
+
+	// batchWindowMilliseconds groups in a single commit the changes made by
+	// the same user to the same RemoteTarget within this many milliseconds,
+	// as when a whole directory is applied. Each request waits for the window
+	// to end before it is answered. 0 commits every change on its own.
+	// The changes pushed through change requests are never grouped.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=5000
+	// +kubebuilder:validation:Optional
+	BatchWindowMilliseconds int32 `json:"batchWindowMilliseconds,omitempty" protobuf:"bytes,opt,26,name=batchWindowMilliseconds"`
+
+	// cloneDepth limits the history cloned from the remote repository to this
+	// many commits. It only applies to the RemoteTargets that push to the
+	// branch they are cloned from, without merge strategy. 0 clones the whole
+	// history.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Optional
+	CloneDepth int32 `json:"cloneDepth,omitempty" protobuf:"bytes,opt,27,name=cloneDepth"`
+
+	// webhookTimeoutSeconds is the timeoutSeconds of the webhook that
+	// intercepts the objects of this syncer: how long the API server waits for
+	// the push. The push is given up shortly before, so that the request is
+	// answered with the reason. Defaults to 10.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=30
+	// +kubebuilder:validation:Optional
+	WebhookTimeoutSeconds int32 `json:"webhookTimeoutSeconds,omitempty" protobuf:"bytes,opt,28,name=webhookTimeoutSeconds"`
+
+	// multipleTarget configures how the RemoteTargets are pushed with the
+	// MultipleTarget targetStrategy.
+	// +kubebuilder:validation:Optional
+	MultipleTarget MultipleTargetConfig `json:"multipleTarget,omitempty" protobuf:"bytes,opt,29,name=multipleTarget"`
+
+	// initialCommit starts the branches that do not exist yet in the remote
+	// repository. Such a branch is created from the default branch of the
+	// repository, or from scratch when the repository is empty, and pushed
+	// with the first intercepted object.
+	// +kubebuilder:validation:Optional
+	InitialCommit InitialCommit `json:"initialCommit,omitempty" protobuf:"bytes,opt,30,name=initialCommit"`
 }
 
 type RemoteSyncerStatus struct {
@@ -212,6 +349,11 @@ type RemoteSyncerStatus struct {
 	// the paths, the commit hashes and the push details of the latest intercepted resource.
 	// +optional
 	LastPushedObjectState LastPushedObjectState `json:"lastPushedObjectState,omitempty" protobuf:"bytes,4,rep,name=lastPushedObjectState"`
+
+	// pendingCommits is the number of commits of this syncer that could not
+	// be pushed and wait to be replayed (defaultPushErrorBehavior: BlockCacheCommit).
+	// +optional
+	PendingCommits int `json:"pendingCommits,omitempty" protobuf:"bytes,5,opt,name=pendingCommits"`
 }
 
 // +kubebuilder:resource:path=remotesyncers,shortName=rsy;rsys,categories=syngit
@@ -262,6 +404,21 @@ const (
 	MultipleTarget TargetStrategy = "MultipleTarget"
 )
 
+// DefaultMaxConcurrentPushes is the maxConcurrentPushes of the syncers that
+// do not set it.
+const DefaultMaxConcurrentPushes int32 = 4
+
+type AtomicMode string
+
+const (
+	RevertAtomicMode     AtomicMode = "Revert"
+	ForceResetAtomicMode AtomicMode = "ForceReset"
+)
+
+// DefaultWebhookTimeoutSeconds is the webhookTimeoutSeconds of the syncers
+// that do not set it. It is also the default of the API server.
+const DefaultWebhookTimeoutSeconds int32 = 10
+
 type TargetPolicy string
 
 const (
@@ -269,11 +426,22 @@ const (
 	OneOrMultipleBranches TargetPolicy = "OneOrMultipleBranches"
 )
 
+type SequenceIndent string
+
+const (
+	CompactSequenceIndent  SequenceIndent = "Compact"
+	IndentedSequenceIndent SequenceIndent = "Indented"
+)
+
+// DefaultYAMLIndent is the indent of the syncers that do not set it.
+const DefaultYAMLIndent int32 = 2
+
 type Strategy string
 
 const (
-	CommitOnly  Strategy = "CommitOnly"
-	CommitApply Strategy = "CommitApply"
+	CommitOnly      Strategy = "CommitOnly"
+	CommitApply     Strategy = "CommitApply"
+	ApplyThenCommit Strategy = "ApplyThenCommit"
 )
 
 type DefaultUnauthorizedUserMode string
@@ -315,7 +483,7 @@ type PushErrorBehavior string
 const (
 	BlockPushError   PushErrorBehavior = "Block"
 	Pass             PushErrorBehavior = "Pass"
-	BlockCacheCommit PushErrorBehavior = "BlockCacheCommit" // TODO
+	BlockCacheCommit PushErrorBehavior = "BlockCacheCommit"
 )
 
 type SOPSConfig struct {
@@ -339,6 +507,241 @@ type SOPSConfig struct {
 	SecretRef corev1.SecretReference `json:"secretRef,omitempty" protobuf:"bytes,opt,2,name=secretRef"`
 }
 
+type ProxyConfig struct {
+	// url of the proxy.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:example="http://proxy.example.com:3128"
+	// +kubebuilder:validation:Format=uri
+	URL string `json:"url,omitempty" protobuf:"bytes,opt,1,name=url"`
+
+	// The secretRef is a reference to a Secret that stores the "username" and
+	// the "password" the proxy is authenticated with, such as a Secret of type
+	// kubernetes.io/basic-auth.
+	// If the namespace is not set, it defaults to the namespace of the RemoteSyncer.
+	// Referencing another namespace requires the user to be allowed to get the
+	// referenced object in that namespace.
+	// +kubebuilder:validation:Optional
+	SecretRef corev1.SecretReference `json:"secretRef,omitempty" protobuf:"bytes,opt,2,name=secretRef"`
+}
+
+type CommitTrailers struct {
+	// signOff appends a "Signed-off-by" trailer with the name and
+	// the email of the RemoteUser that pushes the commit.
+	// +kubebuilder:validation:Optional
+	SignOff bool `json:"signOff,omitempty" protobuf:"bytes,opt,1,name=signOff"`
+
+	// custom trailers are appended, in order, before the "Signed-off-by" one.
+	// +kubebuilder:validation:Optional
+	Custom []CommitTrailer `json:"custom,omitempty" protobuf:"bytes,rep,2,name=custom"`
+}
+
+type CommitTrailer struct {
+	// key of the trailer, as in "Key: value".
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9][A-Za-z0-9-]*$`
+	// +kubebuilder:example="Kubernetes-User"
+	Key string `json:"key" protobuf:"bytes,1,name=key"`
+
+	// value of the trailer. It is a Go text/template executed with the same
+	// data as the commitMessageTemplate. Line breaks are replaced by spaces.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:example="{{ .Username }}"
+	Value string `json:"value" protobuf:"bytes,2,name=value"`
+}
+
+type DiffPreview struct {
+	// Set enabled to true to return the unified diff of the claimed paths,
+	// the hash of the commit and a link to it. When SOPS is enabled, only
+	// the keys that changed are listed: their values are never returned.
+	// +kubebuilder:default:value=false
+	// +kubebuilder:validation:Optional
+	Enabled bool `json:"enabled,omitempty" protobuf:"bytes,opt,1,name=enabled"`
+
+	// maxSize is the number of bytes of the diff after which it is truncated.
+	// The kube-apiserver drops the warnings beyond 4096 bytes.
+	// +kubebuilder:default:value=2048
+	// +kubebuilder:validation:Minimum=256
+	// +kubebuilder:validation:Maximum=4096
+	// +kubebuilder:validation:Optional
+	MaxSize int32 `json:"maxSize,omitempty" protobuf:"bytes,opt,2,name=maxSize"`
+}
+
+type MultipleTargetConfig struct {
+	// maxConcurrentPushes is the number of RemoteTargets pushed at the same
+	// time. The branches of a same repository are pushed together, in a
+	// single push, when they are pushed by the same user.
+	// +kubebuilder:default:value=4
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=32
+	// +kubebuilder:validation:Optional
+	MaxConcurrentPushes int32 `json:"maxConcurrentPushes,omitempty" protobuf:"bytes,opt,1,name=maxConcurrentPushes"`
+
+	// atomic undoes the commits pushed to the other RemoteTargets when one of
+	// them fails, so that the change lands on all of them or on none.
+	// Can be one of these values:
+	// - "Revert": a commit that reverts the change is pushed on top of it
+	// - "ForceReset": the branch is force-pushed back to the commit it was on,
+	//                 unless another commit has been pushed on top meanwhile
+	// When unset, the RemoteTargets that did not fail keep the change. The
+	// change requests and the commits grouped by batchWindowMilliseconds are
+	// never undone.
+	// +kubebuilder:validation:Enum=Revert;ForceReset
+	// +kubebuilder:validation:Optional
+	Atomic AtomicMode `json:"atomic,omitempty" protobuf:"bytes,opt,2,name=atomic"`
+}
+
+type InitialCommit struct {
+	// message of the commit that writes the files to a new branch.
+	// Defaults to "Initial commit".
+	// +kubebuilder:validation:Optional
+	Message string `json:"message,omitempty" protobuf:"bytes,opt,1,name=message"`
+
+	// files are written to a new branch, in a commit of their own, before the
+	// intercepted object is committed. The files that the branch already
+	// holds are left as they are. Without files, the commit of the
+	// intercepted object is the first one of the branch.
+	// +kubebuilder:validation:Optional
+	Files []InitialCommitFile `json:"files,omitempty" protobuf:"bytes,rep,2,name=files"`
+}
+
+type InitialCommitFile struct {
+	// path of the file, from the root of the repository.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:example="README.md"
+	Path string `json:"path" protobuf:"bytes,1,name=path"`
+
+	// content of the file.
+	// +kubebuilder:validation:Optional
+	Content string `json:"content,omitempty" protobuf:"bytes,opt,2,name=content"`
+}
+
+type OwnedFields struct {
+	// Set enabled to true to only push the fields owned by the field managers.
+	// The metadata.managedFields are never pushed.
+	// +kubebuilder:default:value=false
+	// +kubebuilder:validation:Optional
+	Enabled bool `json:"enabled,omitempty" protobuf:"bytes,opt,1,name=enabled"`
+
+	// fieldManagers whose fields are pushed. A name also matches the managers
+	// it prefixes with a dash: "kubectl" matches "kubectl-client-side-apply"
+	// and "kubectl-edit". When unset, the fields of the field manager of the
+	// intercepted request are pushed.
+	// +kubebuilder:example={"kubectl","helm"}
+	// +kubebuilder:validation:Optional
+	FieldManagers []string `json:"fieldManagers,omitempty" protobuf:"bytes,rep,2,name=fieldManagers"`
+}
+
+type YAMLFormat struct {
+	// indent is the number of spaces of each level of indentation.
+	// +kubebuilder:default:value=2
+	// +kubebuilder:validation:Minimum=2
+	// +kubebuilder:validation:Maximum=8
+	// +kubebuilder:validation:Optional
+	Indent int32 `json:"indent,omitempty" protobuf:"bytes,opt,1,name=indent"`
+
+	// sequenceIndent is the indentation of the items of the lists.
+	// Can be one of these values:
+	// - "Compact": the "- " of the items is at the column of their key,
+	//              as kubectl writes them
+	// - "Indented": the items are one level deeper than their key
+	// +kubebuilder:default:value="Compact"
+	// +kubebuilder:validation:Enum=Compact;Indented
+	// +kubebuilder:validation:Optional
+	SequenceIndent SequenceIndent `json:"sequenceIndent,omitempty" protobuf:"bytes,opt,2,name=sequenceIndent"`
+}
+
+type RoutingRule struct {
+	// name identifies the rule. It is recorded on the commits that are
+	// queued to be pushed later, so that they are routed the same way.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name" protobuf:"bytes,1,name=name"`
+
+	// match selects the objects of the rule. An object must meet every
+	// condition that is set; an empty match selects every object.
+	// +kubebuilder:validation:Optional
+	Match RoutingMatch `json:"match,omitempty" protobuf:"bytes,opt,2,name=match"`
+
+	// rootPath replaces the rootPath of the syncer for the objects of the rule.
+	// +kubebuilder:validation:Optional
+	RootPath string `json:"rootPath,omitempty" protobuf:"bytes,opt,3,name=rootPath"`
+
+	// pathTemplate replaces the pathTemplate of the syncer for the objects of
+	// the rule.
+	// +kubebuilder:validation:Optional
+	PathTemplate string `json:"pathTemplate,omitempty" protobuf:"bytes,opt,4,name=pathTemplate"`
+
+	// remoteTargetSelector replaces the remoteTargetSelector of the syncer for
+	// the objects of the rule.
+	// +kubebuilder:validation:Optional
+	RemoteTargetSelector *metav1.LabelSelector `json:"remoteTargetSelector,omitempty" protobuf:"bytes,opt,5,name=remoteTargetSelector"`
+
+	// targetBranch is the branch that the objects of the rule are pushed to,
+	// instead of the targetBranch of the RemoteTargets. A RemoteTarget without
+	// merge strategy commits straight onto this branch.
+	// +kubebuilder:validation:Optional
+	TargetBranch string `json:"targetBranch,omitempty" protobuf:"bytes,opt,6,name=targetBranch"`
+}
+
+type RoutingMatch struct {
+	// kinds that the object must be one of.
+	// +kubebuilder:validation:Optional
+	Kinds []RoutingKind `json:"kinds,omitempty" protobuf:"bytes,rep,1,name=kinds"`
+
+	// namespaces that the object must be in. A cluster-scoped object is in
+	// none of them.
+	// +kubebuilder:validation:Optional
+	Namespaces []string `json:"namespaces,omitempty" protobuf:"bytes,rep,2,name=namespaces"`
+
+	// labelSelector that the labels of the object must match.
+	// +kubebuilder:validation:Optional
+	LabelSelector *metav1.LabelSelector `json:"labelSelector,omitempty" protobuf:"bytes,opt,3,name=labelSelector"`
+
+	// expression is a CEL expression that must evaluate to true. The object
+	// is the "object" variable; the deleted object for a deletion. An
+	// expression that fails for an object, on a field that it does not have
+	// for instance, does not match it.
+	// +kubebuilder:example="object.metadata.name.startsWith('team-a-')"
+	// +kubebuilder:validation:Optional
+	Expression string `json:"expression,omitempty" protobuf:"bytes,opt,4,name=expression"`
+}
+
+type RoutingKind struct {
+	// group of the kind, empty for the core group. "*" matches every group.
+	// +kubebuilder:validation:Optional
+	Group string `json:"group,omitempty" protobuf:"bytes,opt,1,name=group"`
+
+	// version of the kind. When empty, every version matches.
+	// +kubebuilder:validation:Optional
+	Version string `json:"version,omitempty" protobuf:"bytes,opt,2,name=version"`
+
+	// kind of the object. "*" matches every kind.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
+	Kind string `json:"kind" protobuf:"bytes,3,name=kind"`
+}
+
+// PathMetadata is the metadata of an object that the pathTemplate of a
+// syncer can use, besides its group, version, resource, namespace and name.
+type PathMetadata struct {
+	// +kubebuilder:validation:Optional
+	Kind string `json:"kind,omitempty" protobuf:"bytes,opt,1,name=kind"`
+	// +kubebuilder:validation:Optional
+	Labels map[string]string `json:"labels,omitempty" protobuf:"bytes,rep,2,name=labels"`
+	// +kubebuilder:validation:Optional
+	Annotations map[string]string `json:"annotations,omitempty" protobuf:"bytes,rep,3,name=annotations"`
+}
+
+// PathPlacement is where a syncer, or one of its routing rules, places the
+// files of the objects.
+type PathPlacement struct {
+	// +kubebuilder:validation:Optional
+	RootPath string `json:"rootPath,omitempty" protobuf:"bytes,opt,1,name=rootPath"`
+	// +kubebuilder:validation:Optional
+	PathTemplate string `json:"pathTemplate,omitempty" protobuf:"bytes,opt,2,name=pathTemplate"`
+}
+
 /*
 	SPEC CONVERSION EXTENSION
 */
@@ -415,4 +818,7 @@ type LastPushedObjectState struct {
 
 	// +optional
 	LastPushedObjectStatus string `json:"lastPushedObjectState,omitempty"`
+
+	// +optional
+	LastPushedObjectChangeRequests []string `json:"lastPushedObjectChangeRequests,omitempty"`
 }
@@ -88,9 +88,36 @@ type RemoteTargetSpec struct {
 	// - TryFastForwardOrHardReset: First try the fast forward strategy,
 	//                              if there is an error, then try the hard
 	//                              reset strategy.
+	// - TryMergeOrDie:             Merge the upstream in the target branch
+	//                              with a merge commit. If a file has been
+	//                              changed on both sides, the webhook will
+	//                              return an error with the conflicting paths.
+	// - TryRebaseOrDie:            Replay the commits of the target branch on
+	//                              top of the upstream, and force push the
+	//                              target branch. If a file has been changed
+	//                              on both sides, the webhook will return an
+	//                              error with the conflicting paths.
 	// +kubebuilder:validation:Optional
-	// +kubebuilder:validation:Enum=TryFastForwardOrDie;TryFastForwardOrHardReset;TryHardResetOrDie;""
+	// +kubebuilder:validation:Enum=TryFastForwardOrDie;TryFastForwardOrHardReset;TryHardResetOrDie;TryMergeOrDie;TryRebaseOrDie;""
 	MergeStrategy MergeStrategy `json:"mergeStrategy" protobuf:"bytes,5,name=mergeStrategy"`
+
+	// deliveryMode defines how the commits reach the targetBranch.
+	// Can be one of these values:
+	// - Push:          The commits are pushed to the targetBranch.
+	// - ChangeRequest: The commits are pushed to a branch generated for
+	//                  each intercepted object, and a pull request (or
+	//                  merge request) is opened from this branch to the
+	//                  targetBranch. The generated branch is rebuilt on top
+	//                  of the targetBranch and force pushed on each change.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:value="Push"
+	// +kubebuilder:validation:Enum=Push;ChangeRequest
+	DeliveryMode DeliveryMode `json:"deliveryMode,omitempty" protobuf:"bytes,opt,6,name=deliveryMode"`
+
+	// changeRequest configures the ChangeRequest delivery mode.
+	// It must be set if the deliveryMode is ChangeRequest.
+	// +kubebuilder:validation:Optional
+	ChangeRequest *ChangeRequestSpec `json:"changeRequest,omitempty" protobuf:"bytes,opt,7,name=changeRequest"`
 }
 
 // RemoteTargetStatus defines the observed state of RemoteTarget.
@@ -148,4 +175,41 @@ const (
 	TryFastForwardOrDie       MergeStrategy = "TryFastForwardOrDie"
 	TryFastForwardOrHardReset MergeStrategy = "TryFastForwardOrHardReset"
 	TryHardResetOrDie         MergeStrategy = "TryHardResetOrDie"
+	TryMergeOrDie             MergeStrategy = "TryMergeOrDie"
+	TryRebaseOrDie            MergeStrategy = "TryRebaseOrDie"
+)
+
+type DeliveryMode string
+
+const (
+	PushDelivery          DeliveryMode = "Push"
+	ChangeRequestDelivery DeliveryMode = "ChangeRequest"
+)
+
+type ForgeKind string
+
+const (
+	GitHubForge ForgeKind = "GitHub"
+	GitLabForge ForgeKind = "GitLab"
 )
+
+type ChangeRequestSpec struct {
+	// forge is the flavor of the API of the git platform.
+	// The token of the RemoteUser is used to call it.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=GitHub;GitLab
+	Forge ForgeKind `json:"forge" protobuf:"bytes,1,name=forge"`
+
+	// apiURL is the base URL of the API of the git platform.
+	// It defaults to https://api.github.com for github.com,
+	// https://<host>/api/v3 for the other GitHub servers and
+	// https://<host>/api/v4 for GitLab.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:example="https://git.example.com/api/v4"
+	APIURL string `json:"apiURL,omitempty" protobuf:"bytes,opt,2,name=apiURL"`
+
+	// branchPrefix is prepended to the name of the generated branches.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:value="syngit/"
+	BranchPrefix string `json:"branchPrefix,omitempty" protobuf:"bytes,opt,3,name=branchPrefix"`
+}
@@ -24,8 +24,9 @@ import (
 
 type RemoteUserSpec struct {
 
-	// secretRef is the reference to the secret that stores the Personal Access Token to the git account.
-	// The Secret must be of 'kubernetes.io/basic-auth' type.
+	// secretRef is the reference to the secret that stores the credentials of the git account.
+	// The Secret must be either of 'kubernetes.io/basic-auth' type, holding a Personal Access Token,
+	// or of 'kubernetes.io/ssh-auth' type, holding a private key (for example a deploy key).
 	// A reference without a namespace resolves in the namespace of this RemoteUser.
 	// Whoever creates or updates this RemoteUser must be allowed to get the referenced
 	// Secret, wherever it resolves.
@@ -40,6 +41,22 @@ type RemoteUserSpec struct {
 	// For example: "github.com", "gitlab.com", "my-own-git-server.io", etc...
 	// +kubebuilder:validation:Required
 	GitBaseDomainFQDN string `json:"gitBaseDomainFQDN" protobuf:"bytes,3,name=gitBaseDomainFQDN"`
+
+	// knownHostsSecretRef is the reference to the secret that stores the known_hosts entries
+	// used to verify the git server when the secretRef is of 'kubernetes.io/ssh-auth' type.
+	// The entries are read from the 'known_hosts' key of the Secret.
+	// It is required for SSH authentication: the host key of the git server is never trusted blindly.
+	// A reference without a namespace resolves in the namespace of this RemoteUser.
+	// +optional
+	KnownHostsSecretRef *corev1.SecretReference `json:"knownHostsSecretRef,omitempty" protobuf:"bytes,4,opt,name=knownHostsSecretRef"`
+
+	// signingKeySecretRef is the reference to the secret that stores the key the commits are signed with.
+	// The key is read from the 'signing_key' key of the Secret (or from 'ssh-privatekey' for a
+	// 'kubernetes.io/ssh-auth' Secret). It is either an armored OpenPGP private key or an OpenSSH
+	// private key. An encrypted key is unlocked with the optional 'passphrase' key of the Secret.
+	// A reference without a namespace resolves in the namespace of this RemoteUser.
+	// +optional
+	SigningKeySecretRef *corev1.SecretReference `json:"signingKeySecretRef,omitempty" protobuf:"bytes,5,opt,name=signingKeySecretRef"`
 }
 
 type RemoteUserStatus struct {
@@ -68,7 +85,8 @@ type RemoteUserStatus struct {
 	// - "Secret bound" when the secret is correctly bound
 	// - "Secret found" when the secret is found and being processing by the controller
 	// - "Secret not found" when the secret is not found
-	// - "Secret type is not set to BasicAuth" when the secret is of wrong type
+	// - "Secret type is not set to BasicAuth or SSHAuth" when the secret is of wrong type
+	// - "Known hosts not found" when the secret is of SSHAuth type but the known_hosts can not be found
 	// +optional
 	SecretBoundStatus SecretBoundStatus `json:"secretBoundStatus,omitempty" protobuf:"bytes,4,rep,name=secretBoundStatus"`
 }
@@ -128,10 +146,25 @@ const (
 type SecretBoundStatus string
 
 const (
-	SecretBound     SecretBoundStatus = "Secret bound"
-	SecretFound     SecretBoundStatus = "Secret found"
-	SecretNotFound  SecretBoundStatus = "Secret not found"
-	SecretWrongType SecretBoundStatus = "Secret type is not set to BasicAuth"
+	SecretBound        SecretBoundStatus = "Secret bound"
+	SecretFound        SecretBoundStatus = "Secret found"
+	SecretNotFound     SecretBoundStatus = "Secret not found"
+	SecretWrongType    SecretBoundStatus = "Secret type is not set to BasicAuth or SSHAuth"
+	KnownHostsNotFound SecretBoundStatus = "Known hosts not found"
+)
+
+const (
+	// KnownHostsKey is the key of the known_hosts entries in the Secret
+	// referenced by spec.knownHostsSecretRef.
+	KnownHostsKey = "known_hosts"
+
+	// SigningKeyKey is the key of the signing key in the Secret
+	// referenced by spec.signingKeySecretRef.
+	SigningKeyKey = "signing_key"
+
+	// SigningKeyPassphraseKey is the key of the passphrase of the signing
+	// key in the Secret referenced by spec.signingKeySecretRef.
+	SigningKeyPassphraseKey = "passphrase"
 )
 
 const (
@@ -29,6 +29,21 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChangeRequestSpec) DeepCopyInto(out *ChangeRequestSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChangeRequestSpec.
+func (in *ChangeRequestSpec) DeepCopy() *ChangeRequestSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ChangeRequestSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterWideRemoteSyncer) DeepCopyInto(out *ClusterWideRemoteSyncer) {
 	*out = *in
@@ -109,6 +124,56 @@ func (in *ClusterWideRemoteSyncerSpec) DeepCopy() *ClusterWideRemoteSyncerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CommitTrailer) DeepCopyInto(out *CommitTrailer) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommitTrailer.
+func (in *CommitTrailer) DeepCopy() *CommitTrailer {
+	if in == nil {
+		return nil
+	}
+	out := new(CommitTrailer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CommitTrailers) DeepCopyInto(out *CommitTrailers) {
+	*out = *in
+	if in.Custom != nil {
+		in, out := &in.Custom, &out.Custom
+		*out = make([]CommitTrailer, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CommitTrailers.
+func (in *CommitTrailers) DeepCopy() *CommitTrailers {
+	if in == nil {
+		return nil
+	}
+	out := new(CommitTrailers)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DiffPreview) DeepCopyInto(out *DiffPreview) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DiffPreview.
+func (in *DiffPreview) DeepCopy() *DiffPreview {
+	if in == nil {
+		return nil
+	}
+	out := new(DiffPreview)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GroupVersionKindName) DeepCopyInto(out *GroupVersionKindName) {
 	*out = *in
@@ -149,6 +214,41 @@ func (in *GroupVersionResourceName) DeepCopy() *GroupVersionResourceName {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InitialCommit) DeepCopyInto(out *InitialCommit) {
+	*out = *in
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make([]InitialCommitFile, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InitialCommit.
+func (in *InitialCommit) DeepCopy() *InitialCommit {
+	if in == nil {
+		return nil
+	}
+	out := new(InitialCommit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InitialCommitFile) DeepCopyInto(out *InitialCommitFile) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InitialCommitFile.
+func (in *InitialCommitFile) DeepCopy() *InitialCommitFile {
+	if in == nil {
+		return nil
+	}
+	out := new(InitialCommitFile)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *JsonGVRN) DeepCopyInto(out *JsonGVRN) {
 	*out = *in
@@ -219,6 +319,11 @@ func (in *LastPushedObjectState) DeepCopyInto(out *LastPushedObjectState) {
 		copy(*out, *in)
 	}
 	out.LastPushedObject = in.LastPushedObject
+	if in.LastPushedObjectChangeRequests != nil {
+		in, out := &in.LastPushedObjectChangeRequests, &out.LastPushedObjectChangeRequests
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LastPushedObjectState.
@@ -231,6 +336,21 @@ func (in *LastPushedObjectState) DeepCopy() *LastPushedObjectState {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MultipleTargetConfig) DeepCopyInto(out *MultipleTargetConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MultipleTargetConfig.
+func (in *MultipleTargetConfig) DeepCopy() *MultipleTargetConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(MultipleTargetConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NamespaceScopedKinds) DeepCopyInto(out *NamespaceScopedKinds) {
 	*out = *in
@@ -301,6 +421,203 @@ func (in *NamespaceScopedResources) DeepCopy() *NamespaceScopedResources {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OwnedFields) DeepCopyInto(out *OwnedFields) {
+	*out = *in
+	if in.FieldManagers != nil {
+		in, out := &in.FieldManagers, &out.FieldManagers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OwnedFields.
+func (in *OwnedFields) DeepCopy() *OwnedFields {
+	if in == nil {
+		return nil
+	}
+	out := new(OwnedFields)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PathMetadata) DeepCopyInto(out *PathMetadata) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PathMetadata.
+func (in *PathMetadata) DeepCopy() *PathMetadata {
+	if in == nil {
+		return nil
+	}
+	out := new(PathMetadata)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PathPlacement) DeepCopyInto(out *PathPlacement) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PathPlacement.
+func (in *PathPlacement) DeepCopy() *PathPlacement {
+	if in == nil {
+		return nil
+	}
+	out := new(PathPlacement)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PendingCommit) DeepCopyInto(out *PendingCommit) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PendingCommit.
+func (in *PendingCommit) DeepCopy() *PendingCommit {
+	if in == nil {
+		return nil
+	}
+	out := new(PendingCommit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PendingCommit) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PendingCommitList) DeepCopyInto(out *PendingCommitList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PendingCommit, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PendingCommitList.
+func (in *PendingCommitList) DeepCopy() *PendingCommitList {
+	if in == nil {
+		return nil
+	}
+	out := new(PendingCommitList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PendingCommitList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PendingCommitSpec) DeepCopyInto(out *PendingCommitSpec) {
+	*out = *in
+	out.SyncerRef = in.SyncerRef
+	out.RemoteTargetRef = in.RemoteTargetRef
+	out.RemoteUserRef = in.RemoteUserRef
+	in.Author.DeepCopyInto(&out.Author)
+	out.Object = in.Object
+	in.EnqueueTime.DeepCopyInto(&out.EnqueueTime)
+	in.PathMetadata.DeepCopyInto(&out.PathMetadata)
+	if in.OldPathMetadata != nil {
+		in, out := &in.OldPathMetadata, &out.OldPathMetadata
+		*out = new(PathMetadata)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PreviousPlacement != nil {
+		in, out := &in.PreviousPlacement, &out.PreviousPlacement
+		*out = new(PathPlacement)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PendingCommitSpec.
+func (in *PendingCommitSpec) DeepCopy() *PendingCommitSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PendingCommitSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PendingCommitStatus) DeepCopyInto(out *PendingCommitStatus) {
+	*out = *in
+	if in.LastAttemptTime != nil {
+		in, out := &in.LastAttemptTime, &out.LastAttemptTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PendingCommitStatus.
+func (in *PendingCommitStatus) DeepCopy() *PendingCommitStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PendingCommitStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProxyConfig) DeepCopyInto(out *ProxyConfig) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProxyConfig.
+func (in *ProxyConfig) DeepCopy() *ProxyConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(ProxyConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RemoteSyncer) DeepCopyInto(out *RemoteSyncer) {
 	*out = *in
@@ -385,6 +702,15 @@ func (in *RemoteSyncerSpec) DeepCopyInto(out *RemoteSyncerSpec) {
 			}
 		}
 	}
+	in.OwnedFields.DeepCopyInto(&out.OwnedFields)
+	out.YAMLFormat = in.YAMLFormat
+	if in.RoutingRules != nil {
+		in, out := &in.RoutingRules, &out.RoutingRules
+		*out = make([]RoutingRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	if in.RemoteUserBindingSelector != nil {
 		in, out := &in.RemoteUserBindingSelector, &out.RemoteUserBindingSelector
 		*out = new(v1.LabelSelector)
@@ -406,7 +732,13 @@ func (in *RemoteSyncerSpec) DeepCopyInto(out *RemoteSyncerSpec) {
 		**out = **in
 	}
 	out.CABundleSecretRef = in.CABundleSecretRef
+	out.Proxy = in.Proxy
+	out.ClientCertSecretRef = in.ClientCertSecretRef
 	out.SOPS = in.SOPS
+	in.CommitTrailers.DeepCopyInto(&out.CommitTrailers)
+	out.DiffPreview = in.DiffPreview
+	out.MultipleTarget = in.MultipleTarget
+	in.InitialCommit.DeepCopyInto(&out.InitialCommit)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteSyncerSpec.
@@ -449,7 +781,7 @@ func (in *RemoteTarget) DeepCopyInto(out *RemoteTarget) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -506,6 +838,11 @@ func (in *RemoteTargetList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RemoteTargetSpec) DeepCopyInto(out *RemoteTargetSpec) {
 	*out = *in
+	if in.ChangeRequest != nil {
+		in, out := &in.ChangeRequest, &out.ChangeRequest
+		*out = new(ChangeRequestSpec)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteTargetSpec.
@@ -545,7 +882,7 @@ func (in *RemoteUser) DeepCopyInto(out *RemoteUser) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	in.Status.DeepCopyInto(&out.Status)
 }
 
@@ -746,6 +1083,16 @@ func (in *RemoteUserList) DeepCopyObject() runtime.Object {
 func (in *RemoteUserSpec) DeepCopyInto(out *RemoteUserSpec) {
 	*out = *in
 	out.SecretRef = in.SecretRef
+	if in.KnownHostsSecretRef != nil {
+		in, out := &in.KnownHostsSecretRef, &out.KnownHostsSecretRef
+		*out = new(corev1.SecretReference)
+		**out = **in
+	}
+	if in.SigningKeySecretRef != nil {
+		in, out := &in.SigningKeySecretRef, &out.SigningKeySecretRef
+		*out = new(corev1.SecretReference)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RemoteUserSpec.
@@ -781,6 +1128,72 @@ func (in *RemoteUserStatus) DeepCopy() *RemoteUserStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoutingKind) DeepCopyInto(out *RoutingKind) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoutingKind.
+func (in *RoutingKind) DeepCopy() *RoutingKind {
+	if in == nil {
+		return nil
+	}
+	out := new(RoutingKind)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoutingMatch) DeepCopyInto(out *RoutingMatch) {
+	*out = *in
+	if in.Kinds != nil {
+		in, out := &in.Kinds, &out.Kinds
+		*out = make([]RoutingKind, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LabelSelector != nil {
+		in, out := &in.LabelSelector, &out.LabelSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoutingMatch.
+func (in *RoutingMatch) DeepCopy() *RoutingMatch {
+	if in == nil {
+		return nil
+	}
+	out := new(RoutingMatch)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RoutingRule) DeepCopyInto(out *RoutingRule) {
+	*out = *in
+	in.Match.DeepCopyInto(&out.Match)
+	if in.RemoteTargetSelector != nil {
+		in, out := &in.RemoteTargetSelector, &out.RemoteTargetSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RoutingRule.
+func (in *RoutingRule) DeepCopy() *RoutingRule {
+	if in == nil {
+		return nil
+	}
+	out := new(RoutingRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SOPSConfig) DeepCopyInto(out *SOPSConfig) {
 	*out = *in
@@ -828,3 +1241,18 @@ func (in *ScopedResources) DeepCopy() *ScopedResources {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *YAMLFormat) DeepCopyInto(out *YAMLFormat) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new YAMLFormat.
+func (in *YAMLFormat) DeepCopy() *YAMLFormat {
+	if in == nil {
+		return nil
+	}
+	out := new(YAMLFormat)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -1,8 +1,10 @@
 package errors
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
 	authv1 "k8s.io/api/authentication/v1"
@@ -32,6 +34,9 @@ func (e *resourceScopeForbidden) ShouldContains(err error) bool {
 }
 
 func (e *resourceScopeForbidden) Unwrap() error {
+	if e == ErrResourceScopeForbidden {
+		return nil
+	}
 	return ErrResourceScopeForbidden
 }
 
@@ -60,6 +65,9 @@ func (e *denyGetRemoteUser) ShouldContains(err error) bool {
 }
 
 func (e *denyGetRemoteUser) Unwrap() error {
+	if e == ErrRemoteUserDenied {
+		return nil
+	}
 	return ErrRemoteUserDenied
 }
 
@@ -89,6 +97,9 @@ func (e *denyGetRemoteTarget) ShouldContains(err error) bool {
 }
 
 func (e *denyGetRemoteTarget) Unwrap() error {
+	if e == ErrRemoteTargetDenied {
+		return nil
+	}
 	return ErrRemoteTargetDenied
 }
 
@@ -117,6 +128,9 @@ func (e *remoteUserBindingNotFound) ShouldContains(err error) bool {
 }
 
 func (e *remoteUserBindingNotFound) Unwrap() error {
+	if e == ErrRemoteUserBindingNotFound {
+		return nil
+	}
 	return ErrRemoteUserBindingNotFound
 }
 
@@ -148,6 +162,9 @@ func (e *wrongRemoteTargetConfig) ShouldContains(err error) bool {
 }
 
 func (e *wrongRemoteTargetConfig) Unwrap() error {
+	if e == ErrWrongRemoteTargetConfig {
+		return nil
+	}
 	return ErrWrongRemoteTargetConfig
 }
 
@@ -173,6 +190,9 @@ func (e *wrongRemoteSyncerConfig) ShouldContains(err error) bool {
 }
 
 func (e *wrongRemoteSyncerConfig) Unwrap() error {
+	if e == ErrWrongRemoteSyncerConfig {
+		return nil
+	}
 	return ErrWrongRemoteSyncerConfig
 }
 
@@ -198,6 +218,9 @@ func (e *remoteTargetNotFound) ShouldContains(err error) bool {
 }
 
 func (e *remoteTargetNotFound) Unwrap() error {
+	if e == ErrRemoteTargetNotFound {
+		return nil
+	}
 	return ErrRemoteTargetNotFound
 }
 
@@ -225,6 +248,9 @@ func (e *remoteUserNotFound) ShouldContains(err error) bool {
 }
 
 func (e *remoteUserNotFound) Unwrap() error {
+	if e == ErrRemoteUserNotFound {
+		return nil
+	}
 	return ErrRemoteUserNotFound
 }
 
@@ -254,6 +280,9 @@ func (e *credentialsNotFound) ShouldContains(err error) bool {
 }
 
 func (e *credentialsNotFound) Unwrap() error {
+	if e == ErrCredentialsNotFound {
+		return nil
+	}
 	return ErrCredentialsNotFound
 }
 
@@ -280,6 +309,9 @@ func (e *tooMuchRemoteTarget) ShouldContains(err error) bool {
 }
 
 func (e *tooMuchRemoteTarget) Unwrap() error {
+	if e == ErrTooMuchRemoteTarget {
+		return nil
+	}
 	return ErrTooMuchRemoteTarget
 }
 
@@ -306,6 +338,9 @@ func (e *wrongLabelParsing) ShouldContains(err error) bool {
 }
 
 func (e *wrongLabelParsing) Unwrap() error {
+	if e == ErrWrongLabelParsing {
+		return nil
+	}
 	return ErrWrongLabelParsing
 }
 
@@ -335,6 +370,9 @@ func (e *tooMuchRemoteUserBinding) ShouldContains(err error) bool {
 }
 
 func (e *tooMuchRemoteUserBinding) Unwrap() error {
+	if e == ErrTooMuchRemoteUserBinding {
+		return nil
+	}
 	return ErrTooMuchRemoteUserBinding
 }
 
@@ -364,6 +402,9 @@ func (e *tooMuchRemoteUser) ShouldContains(err error) bool {
 }
 
 func (e *tooMuchRemoteUser) Unwrap() error {
+	if e == ErrTooMuchRemoteUser {
+		return nil
+	}
 	return ErrTooMuchRemoteUser
 }
 
@@ -391,6 +432,9 @@ func (e *tooMuchSubject) ShouldContains(err error) bool {
 }
 
 func (e *tooMuchSubject) Unwrap() error {
+	if e == ErrTooMuchSubject {
+		return nil
+	}
 	return ErrTooMuchSubject
 }
 
@@ -417,6 +461,9 @@ func (e *wrongYamlFormat) ShouldContains(err error) bool {
 }
 
 func (e *wrongYamlFormat) Unwrap() error {
+	if e == ErrWrongYAMLFormat {
+		return nil
+	}
 	return ErrWrongYAMLFormat
 }
 
@@ -441,6 +488,9 @@ func (e *gitPipeline) ShouldContains(err error) bool {
 }
 
 func (e *gitPipeline) Unwrap() error {
+	if e == ErrGitPipeline {
+		return nil
+	}
 	return ErrGitPipeline
 }
 
@@ -469,6 +519,9 @@ func (e *interceptorPipeline) ShouldContains(err error) bool {
 }
 
 func (e *interceptorPipeline) Unwrap() error {
+	if e == ErrInterceptorPipeline {
+		return nil
+	}
 	return ErrInterceptorPipeline
 }
 
@@ -514,6 +567,9 @@ func (e *crossNamespaceRefDenied) ShouldContains(err error) bool {
 }
 
 func (e *crossNamespaceRefDenied) Unwrap() error {
+	if e == ErrCrossNamespaceRefDenied {
+		return nil
+	}
 	return ErrCrossNamespaceRefDenied
 }
 
@@ -543,8 +599,125 @@ func (e *missingRefNamespace) ShouldContains(err error) bool {
 }
 
 func (e *missingRefNamespace) Unwrap() error {
+	if e == ErrMissingRefNamespace {
+		return nil
+	}
 	return ErrMissingRefNamespace
 }
 
 // A reference of a cluster-scoped object does not carry a namespace.
 var ErrMissingRefNamespace = &missingRefNamespace{}
+
+// GitRemoteErrorKind classifies why a git server failed an operation.
+type GitRemoteErrorKind string
+
+const (
+	// The git server does not know the credential.
+	GitRemoteAuth GitRemoteErrorKind = "authentication failed"
+	// The credential is known but not allowed to perform the operation.
+	GitRemotePermission GitRemoteErrorKind = "permission denied"
+	// The repository does not exist, or is hidden from the credential.
+	GitRemoteNotFound GitRemoteErrorKind = "repository not found"
+	// The branch moved since it was fetched.
+	GitRemoteNonFastForward GitRemoteErrorKind = "non-fast-forward"
+	// The git server asks to slow down.
+	GitRemoteRateLimited GitRemoteErrorKind = "rate limited"
+	// The git server or the network failed in a way that may not happen again.
+	GitRemoteTransient GitRemoteErrorKind = "transient failure"
+)
+
+// This error should be used when a git server fails a clone, a fetch,
+// an ls-remote or a push. retryAfter is the delay the server asked to wait
+// before trying again, if any.
+func NewGitRemote(kind GitRemoteErrorKind, retryAfter time.Duration, err error) *gitRemote {
+	return &gitRemote{Kind: kind, RetryAfter: retryAfter, Err: err}
+}
+
+type gitRemote struct {
+	Kind       GitRemoteErrorKind
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *gitRemote) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("git remote error (%s, retry after %s): %v", e.Kind, e.RetryAfter, e.Err)
+	}
+	return fmt.Sprintf("git remote error (%s): %v", e.Kind, e.Err)
+}
+
+func (e *gitRemote) ShouldContains(err error) bool {
+	return strings.Contains(err.Error(), "git remote error")
+}
+
+// Unwrap returns the error of the git client, so that it can still be matched.
+func (e *gitRemote) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the sentinel of the kind of the error.
+func (e *gitRemote) Is(target error) bool {
+	t, ok := target.(*gitRemote)
+	return ok && t.Err == nil && t.Kind == e.Kind
+}
+
+// The git server does not know the credential.
+var ErrGitAuth = &gitRemote{Kind: GitRemoteAuth}
+
+// The credential is not allowed to perform the git operation.
+var ErrGitPermission = &gitRemote{Kind: GitRemotePermission}
+
+// The git repository does not exist, or is hidden from the credential.
+var ErrGitNotFound = &gitRemote{Kind: GitRemoteNotFound}
+
+// The branch moved since it was fetched.
+var ErrGitNonFastForward = &gitRemote{Kind: GitRemoteNonFastForward}
+
+// The git server asks to slow down.
+var ErrGitRateLimited = &gitRemote{Kind: GitRemoteRateLimited}
+
+// The git server or the network failed in a way that may not happen again.
+var ErrGitTransient = &gitRemote{Kind: GitRemoteTransient}
+
+// GitRemoteRetry reports whether err is a git remote error worth retrying,
+// and the delay the git server asked to wait before doing so.
+func GitRemoteRetry(err error) (time.Duration, bool) {
+	var e *gitRemote
+	if !errors.As(err, &e) {
+		return 0, false
+	}
+	return e.RetryAfter, e.Kind == GitRemoteRateLimited || e.Kind == GitRemoteTransient
+}
+
+// This error should be used when the merge strategy of a RemoteTarget cannot
+// bring the upstream changes in the target branch, because the paths have
+// been changed on both sides.
+func NewMergeConflict(strategy syngit.MergeStrategy, paths []string) *mergeConflict {
+	return &mergeConflict{Strategy: strategy, Paths: paths}
+}
+
+type mergeConflict struct {
+	Strategy syngit.MergeStrategy
+	Paths    []string
+}
+
+func (e *mergeConflict) Error() string {
+	return fmt.Sprintf("merge conflict: the %s merge strategy cannot apply the upstream changes, these paths conflict: \n- %s",
+		e.Strategy,
+		strings.Join(e.Paths, "\n- "),
+	)
+}
+
+func (e *mergeConflict) ShouldContains(err error) bool {
+	return strings.Contains(err.Error(), "merge conflict")
+}
+
+func (e *mergeConflict) Unwrap() error {
+	if e == ErrMergeConflict {
+		return nil
+	}
+	return ErrMergeConflict
+}
+
+// The upstream changes conflict with the changes of the target branch.
+var ErrMergeConflict = &mergeConflict{}
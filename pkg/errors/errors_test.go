@@ -2,8 +2,10 @@ package errors
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"testing"
+	"time"
 
 	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
 	authv1 "k8s.io/api/authentication/v1"
@@ -34,6 +36,52 @@ func assertErrorContract(t *testing.T, got syngitErr, wantSubstr string, sentine
 	if !errors.Is(got, sentinel) {
 		t.Errorf("errors.Is should recognize the wrapped sentinel")
 	}
+	if errors.Is(got, errUnrelated) {
+		t.Errorf("errors.Is should not recognize an unrelated sentinel")
+	}
+}
+
+var errUnrelated = errors.New("unrelated sentinel")
+
+// errors.Is walks the Unwrap chain until it ends: a sentinel that unwraps to
+// itself makes it loop forever on every target that it does not match.
+func TestSentinelUnwrapChainEnds(t *testing.T) {
+	if err := ErrRemoteTargetNotFound.Unwrap(); err != nil {
+		t.Errorf("ErrRemoteTargetNotFound.Unwrap()=%v, want nil", err)
+	}
+
+	if isWithinDeadline(t, NewRemoteTargetNotFound("no matching remote target found"), ErrGitPipeline) {
+		t.Errorf("errors.Is should not recognize another sentinel")
+	}
+}
+
+// The git pipeline retries the pushes rejected as non-fast-forward: the other
+// failures of the pipeline must not be taken for one.
+func TestGitPipelineIsNotNonFastForward(t *testing.T) {
+	if isWithinDeadline(t, NewGitPipeline("failed to commit"), ErrGitNonFastForward) {
+		t.Errorf("errors.Is should not take a git pipeline error for a non-fast-forward rejection")
+	}
+	if !isWithinDeadline(t, NewGitRemote(GitRemoteNonFastForward, 0, fmt.Errorf("rejected")), ErrGitNonFastForward) {
+		t.Errorf("errors.Is should recognize a non-fast-forward rejection")
+	}
+}
+
+// isWithinDeadline returns errors.Is(err, target), and fails the test if it
+// does not terminate.
+func isWithinDeadline(t *testing.T, err, target error) bool {
+	t.Helper()
+
+	done := make(chan bool, 1)
+	go func() {
+		done <- errors.Is(err, target)
+	}()
+	select {
+	case matched := <-done:
+		return matched
+	case <-time.After(time.Second):
+		t.Fatal("errors.Is does not terminate on a sentinel that it does not match")
+		return false
+	}
 }
 
 func TestNewResourceScopeForbidden(t *testing.T) {
@@ -252,3 +300,38 @@ func TestBuildInterceptorPipelineErr(t *testing.T) {
 		t.Errorf("BuildInterceptorPipelineErr=%q, want %q", got, want)
 	}
 }
+
+func TestNewGitRemote(t *testing.T) {
+	cause := errors.New("authorization failed")
+	e := NewGitRemote(GitRemotePermission, 0, cause)
+
+	if !e.ShouldContains(e) || !strings.Contains(e.Error(), "git remote error (permission denied)") {
+		t.Errorf("Error()=%q, want the kind of the error", e.Error())
+	}
+	if !errors.Is(e, ErrGitPermission) || errors.Is(e, ErrGitAuth) {
+		t.Errorf("errors.Is should only recognize the sentinel of the kind")
+	}
+	if !errors.Is(e, cause) {
+		t.Errorf("errors.Is should recognize the error of the git client")
+	}
+	if _, retryable := GitRemoteRetry(e); retryable {
+		t.Errorf("a permission error must not be retryable")
+	}
+
+	limited := fmt.Errorf("failed to push: %w", NewGitRemote(GitRemoteRateLimited, 30*time.Second, cause))
+	if retryAfter, retryable := GitRemoteRetry(limited); !retryable || retryAfter != 30*time.Second {
+		t.Errorf("GitRemoteRetry=(%v, %v), want (30s, true)", retryAfter, retryable)
+	}
+	if _, retryable := GitRemoteRetry(cause); retryable {
+		t.Errorf("an unclassified error must not be retryable")
+	}
+}
+
+func TestNewMergeConflict(t *testing.T) {
+	e := NewMergeConflict(syngit.TryMergeOrDie, []string{"apps/a.yaml", "apps/b.yaml"})
+
+	if e.Strategy != syngit.TryMergeOrDie || len(e.Paths) != 2 {
+		t.Errorf("unexpected fields %+v", e)
+	}
+	assertErrorContract(t, e, "- apps/b.yaml", ErrMergeConflict)
+}
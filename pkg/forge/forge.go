@@ -0,0 +1,185 @@
+// Package forge opens change requests (pull requests on GitHub, merge requests
+// on GitLab) through the REST API of a git platform.
+package forge
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/syngit-org/syngit/pkg/interceptor"
+)
+
+type Kind string
+
+const (
+	GitHub Kind = "GitHub"
+	GitLab Kind = "GitLab"
+)
+
+// maxErrorBodyLength bounds how much of an error response ends up in the error.
+const maxErrorBodyLength = 512
+
+// ChangeRequest is a request to merge the Head branch into the Base branch.
+type ChangeRequest struct {
+	// Number identifies the change request in its repository: the number of a
+	// pull request, the iid of a merge request. Zero until it is created.
+	Number int
+	// URL is the web page of the change request.
+	URL   string
+	Title string
+	Body  string
+	Head  string
+	Base  string
+}
+
+// Forge manages the change requests of one repository.
+type Forge interface {
+	// FindOpen returns the open change request from head to base,
+	// or nil if there is none.
+	FindOpen(ctx context.Context, head, base string) (*ChangeRequest, error)
+	// Create opens a change request.
+	Create(ctx context.Context, request ChangeRequest) (*ChangeRequest, error)
+	// Update replaces the title and the body of the change request
+	// identified by request.Number.
+	Update(ctx context.Context, request ChangeRequest) (*ChangeRequest, error)
+}
+
+type Options struct {
+	// APIURL is the base URL of the REST API. When empty, it is
+	// derived from the host of the repository.
+	APIURL string
+	// Repository is the URL of the git repository, as it is cloned.
+	Repository string
+	// Token authenticates the calls to the API.
+	Token string
+	// HTTPClient sends the requests. http.DefaultClient when nil.
+	HTTPClient *http.Client
+}
+
+// New returns the Forge of kind for the repository of options.
+func New(kind Kind, options Options) (Forge, error) {
+	repositoryURL, err := interceptor.ParseRepositoryURL(options.Repository)
+	if err != nil {
+		return nil, err
+	}
+	projectPath := strings.TrimSuffix(strings.Trim(repositoryURL.Path, "/"), ".git")
+	if projectPath == "" {
+		return nil, fmt.Errorf("no project path in the repository URL %s", options.Repository)
+	}
+
+	client := restClient{
+		baseURL:    strings.TrimSuffix(options.APIURL, "/"),
+		httpClient: options.HTTPClient,
+	}
+	if client.httpClient == nil {
+		client.httpClient = http.DefaultClient
+	}
+	host := interceptor.RepositoryHost(repositoryURL)
+
+	switch kind {
+	case GitHub:
+		owner, name, found := strings.Cut(projectPath, "/")
+		if !found || strings.Contains(name, "/") {
+			return nil, fmt.Errorf("the GitHub repository path must be <owner>/<repository>, got %s", projectPath)
+		}
+		if client.baseURL == "" {
+			client.baseURL = defaultGitHubAPIURL(host)
+		}
+		client.headers = map[string]string{
+			"Accept":               "application/vnd.github+json",
+			"Authorization":        "Bearer " + options.Token,
+			"X-GitHub-Api-Version": "2022-11-28",
+		}
+		return &gitHub{client: client, owner: owner, name: name}, nil
+	case GitLab:
+		if client.baseURL == "" {
+			client.baseURL = "https://" + host + "/api/v4"
+		}
+		client.headers = map[string]string{
+			"PRIVATE-TOKEN": options.Token,
+		}
+		return &gitLab{client: client, project: url.PathEscape(projectPath)}, nil
+	default:
+		return nil, fmt.Errorf("unknown forge %q", kind)
+	}
+}
+
+// Ensure opens a change request from request.Head to request.Base,
+// or updates the one that is already open.
+func Ensure(ctx context.Context, forge Forge, request ChangeRequest) (*ChangeRequest, error) {
+	existing, err := forge.FindOpen(ctx, request.Head, request.Base)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return forge.Create(ctx, request)
+	}
+	if existing.Title == request.Title && existing.Body == request.Body {
+		return existing, nil
+	}
+	request.Number = existing.Number
+	return forge.Update(ctx, request)
+}
+
+func defaultGitHubAPIURL(host string) string {
+	if host == "github.com" || host == "www.github.com" {
+		return "https://api.github.com"
+	}
+	return "https://" + host + "/api/v3"
+}
+
+// restClient sends JSON requests to a REST API.
+type restClient struct {
+	baseURL    string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+// do sends in as the JSON body of the request, if not nil, and decodes the
+// JSON response into out.
+func (c restClient) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	for key, value := range c.headers {
+		request.Header.Set(key, value)
+	}
+	if in != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = response.Body.Close() }()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBodyLength))
+		return fmt.Errorf("%s %s: %s: %s", method, request.URL.Redacted(), response.Status, strings.TrimSpace(string(message)))
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s %s: failed to decode the response: %w", method, request.URL.Redacted(), err)
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package forge
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeAPI records the requests it receives and answers with the handler of
+// the matching "METHOD path" key.
+type fakeAPI struct {
+	t        *testing.T
+	handlers map[string]func(w http.ResponseWriter, r *http.Request)
+	requests []string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Method + " " + r.URL.EscapedPath()
+	f.requests = append(f.requests, key)
+	handler, ok := f.handlers[key]
+	if !ok {
+		f.t.Errorf("unexpected request %s", key)
+		http.NotFound(w, r)
+		return
+	}
+	handler(w, r)
+}
+
+func writeJSON(t *testing.T, w http.ResponseWriter, v any) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Fatalf("failed to encode the response: %v", err)
+	}
+}
+
+func readJSON(t *testing.T, r *http.Request) map[string]string {
+	t.Helper()
+	body := map[string]string{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Fatalf("failed to decode the request: %v", err)
+	}
+	return body
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name       string
+		kind       Kind
+		repository string
+		wantErr    bool
+	}{
+		{"github https", GitHub, "https://github.com/syngit-org/syngit.git", false},
+		{"github ssh", GitHub, "git@github.com:syngit-org/syngit.git", false},
+		{"github nested path", GitHub, "https://github.com/syngit-org/sub/syngit.git", true},
+		{"gitlab nested path", GitLab, "https://gitlab.com/group/sub/project.git", false},
+		{"no project path", GitLab, "https://gitlab.com/", true},
+		{"unknown forge", Kind("Gitea"), "https://gitea.com/owner/repo.git", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(tc.kind, Options{Repository: tc.repository})
+			if (err != nil) != tc.wantErr {
+				t.Errorf("New(%s, %s) error = %v, wantErr %v", tc.kind, tc.repository, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestNew_defaultAPIURL(t *testing.T) {
+	tests := []struct {
+		kind       Kind
+		repository string
+		want       string
+	}{
+		{GitHub, "https://github.com/owner/repo.git", "https://api.github.com"},
+		{GitHub, "https://git.example.com/owner/repo.git", "https://git.example.com/api/v3"},
+		{GitLab, "git@gitlab.example.com:group/project.git", "https://gitlab.example.com/api/v4"},
+	}
+
+	for _, tc := range tests {
+		f, err := New(tc.kind, Options{Repository: tc.repository})
+		if err != nil {
+			t.Fatalf("New(%s, %s): %v", tc.kind, tc.repository, err)
+		}
+		var got string
+		switch f := f.(type) {
+		case *gitHub:
+			got = f.client.baseURL
+		case *gitLab:
+			got = f.client.baseURL
+		}
+		if got != tc.want {
+			t.Errorf("API URL of %s = %s, want %s", tc.repository, got, tc.want)
+		}
+	}
+}
+
+func TestEnsure_gitHubCreates(t *testing.T) {
+	api := &fakeAPI{t: t}
+	api.handlers = map[string]func(http.ResponseWriter, *http.Request){
+		"GET /repos/owner/repo/pulls": func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("Authorization"); got != "Bearer token" {
+				t.Errorf("Authorization = %q", got)
+			}
+			if got := r.URL.Query().Get("head"); got != "owner:syngit/main/x" {
+				t.Errorf("head = %q", got)
+			}
+			writeJSON(t, w, []any{})
+		},
+		"POST /repos/owner/repo/pulls": func(w http.ResponseWriter, r *http.Request) {
+			body := readJSON(t, r)
+			if body["head"] != "syngit/main/x" || body["base"] != "main" || body["title"] != "title" {
+				t.Errorf("unexpected pull request %v", body)
+			}
+			w.WriteHeader(http.StatusCreated)
+			writeJSON(t, w, map[string]any{"number": 7, "html_url": "https://github.com/owner/repo/pull/7"})
+		},
+	}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	f, err := New(GitHub, Options{APIURL: server.URL, Repository: "https://github.com/owner/repo.git", Token: "token"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	pr, err := Ensure(context.Background(), f, ChangeRequest{Title: "title", Body: "body", Head: "syngit/main/x", Base: "main"})
+	if err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	if pr.Number != 7 || pr.URL != "https://github.com/owner/repo/pull/7" {
+		t.Errorf("Ensure returned %+v", pr)
+	}
+}
+
+func TestEnsure_gitLabUpdates(t *testing.T) {
+	api := &fakeAPI{t: t}
+	api.handlers = map[string]func(http.ResponseWriter, *http.Request){
+		"GET /projects/group%2Fproject/merge_requests": func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("PRIVATE-TOKEN"); got != "token" {
+				t.Errorf("PRIVATE-TOKEN = %q", got)
+			}
+			writeJSON(t, w, []map[string]any{{
+				"iid": 3, "web_url": "https://gitlab.com/group/project/-/merge_requests/3",
+				"title": "title", "description": "old", "source_branch": "syngit/main/x", "target_branch": "main",
+			}})
+		},
+		"PUT /projects/group%2Fproject/merge_requests/3": func(w http.ResponseWriter, r *http.Request) {
+			if body := readJSON(t, r); body["description"] != "new" {
+				t.Errorf("unexpected update %v", body)
+			}
+			writeJSON(t, w, map[string]any{"iid": 3, "web_url": "https://gitlab.com/group/project/-/merge_requests/3"})
+		},
+	}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	f, err := New(GitLab, Options{APIURL: server.URL, Repository: "https://gitlab.com/group/project.git", Token: "token"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	mr, err := Ensure(context.Background(), f, ChangeRequest{Title: "title", Body: "new", Head: "syngit/main/x", Base: "main"})
+	if err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	if mr.Number != 3 {
+		t.Errorf("Ensure returned %+v", mr)
+	}
+	if len(api.requests) != 2 {
+		t.Errorf("requests = %v, want a lookup and an update", api.requests)
+	}
+}
+
+func TestEnsure_unchanged(t *testing.T) {
+	api := &fakeAPI{t: t}
+	api.handlers = map[string]func(http.ResponseWriter, *http.Request){
+		"GET /repos/owner/repo/pulls": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(t, w, []map[string]any{{"number": 1, "title": "title", "body": "body"}})
+		},
+	}
+	server := httptest.NewServer(api)
+	defer server.Close()
+
+	f, err := New(GitHub, Options{APIURL: server.URL, Repository: "https://github.com/owner/repo.git"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := Ensure(context.Background(), f, ChangeRequest{Title: "title", Body: "body", Head: "h", Base: "b"}); err != nil {
+		t.Fatalf("Ensure: %v", err)
+	}
+	if len(api.requests) != 1 {
+		t.Errorf("requests = %v, want only the lookup", api.requests)
+	}
+}
+
+func TestRestClient_errorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, strings.Repeat("x", 2*maxErrorBodyLength), http.StatusUnprocessableEntity)
+	}))
+	defer server.Close()
+
+	client := restClient{baseURL: server.URL, httpClient: server.Client()}
+	err := client.do(context.Background(), http.MethodGet, "/", nil, nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "422") {
+		t.Errorf("error %q does not contain the status", err)
+	}
+	if len(err.Error()) > 2*maxErrorBodyLength {
+		t.Errorf("error body is not truncated: %d bytes", len(err.Error()))
+	}
+}
@@ -0,0 +1,86 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// gitHub opens pull requests through the GitHub REST API.
+type gitHub struct {
+	client restClient
+	owner  string
+	name   string
+}
+
+type gitHubPullRequest struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+	Title   string `json:"title"`
+	Body    string `json:"body"`
+	Head    struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+func (pr gitHubPullRequest) changeRequest() *ChangeRequest {
+	return &ChangeRequest{
+		Number: pr.Number,
+		URL:    pr.HTMLURL,
+		Title:  pr.Title,
+		Body:   pr.Body,
+		Head:   pr.Head.Ref,
+		Base:   pr.Base.Ref,
+	}
+}
+
+func (g *gitHub) pullsPath() string {
+	return fmt.Sprintf("/repos/%s/%s/pulls", url.PathEscape(g.owner), url.PathEscape(g.name))
+}
+
+func (g *gitHub) FindOpen(ctx context.Context, head, base string) (*ChangeRequest, error) {
+	query := url.Values{
+		"state": {"open"},
+		"head":  {g.owner + ":" + head},
+		"base":  {base},
+	}
+	var pullRequests []gitHubPullRequest
+	if err := g.client.do(ctx, http.MethodGet, g.pullsPath()+"?"+query.Encode(), nil, &pullRequests); err != nil {
+		return nil, err
+	}
+	if len(pullRequests) == 0 {
+		return nil, nil
+	}
+	return pullRequests[0].changeRequest(), nil
+}
+
+func (g *gitHub) Create(ctx context.Context, request ChangeRequest) (*ChangeRequest, error) {
+	in := map[string]string{
+		"title": request.Title,
+		"body":  request.Body,
+		"head":  request.Head,
+		"base":  request.Base,
+	}
+	var pullRequest gitHubPullRequest
+	if err := g.client.do(ctx, http.MethodPost, g.pullsPath(), in, &pullRequest); err != nil {
+		return nil, err
+	}
+	return pullRequest.changeRequest(), nil
+}
+
+func (g *gitHub) Update(ctx context.Context, request ChangeRequest) (*ChangeRequest, error) {
+	in := map[string]string{
+		"title": request.Title,
+		"body":  request.Body,
+	}
+	var pullRequest gitHubPullRequest
+	path := fmt.Sprintf("%s/%d", g.pullsPath(), request.Number)
+	if err := g.client.do(ctx, http.MethodPatch, path, in, &pullRequest); err != nil {
+		return nil, err
+	}
+	return pullRequest.changeRequest(), nil
+}
@@ -0,0 +1,82 @@
+package forge
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// gitLab opens merge requests through the GitLab REST API.
+type gitLab struct {
+	client restClient
+	// The URL-encoded path of the project, which GitLab accepts as its id.
+	project string
+}
+
+type gitLabMergeRequest struct {
+	IID          int    `json:"iid"`
+	WebURL       string `json:"web_url"`
+	Title        string `json:"title"`
+	Description  string `json:"description"`
+	SourceBranch string `json:"source_branch"`
+	TargetBranch string `json:"target_branch"`
+}
+
+func (mr gitLabMergeRequest) changeRequest() *ChangeRequest {
+	return &ChangeRequest{
+		Number: mr.IID,
+		URL:    mr.WebURL,
+		Title:  mr.Title,
+		Body:   mr.Description,
+		Head:   mr.SourceBranch,
+		Base:   mr.TargetBranch,
+	}
+}
+
+func (g *gitLab) mergeRequestsPath() string {
+	return "/projects/" + g.project + "/merge_requests"
+}
+
+func (g *gitLab) FindOpen(ctx context.Context, head, base string) (*ChangeRequest, error) {
+	query := url.Values{
+		"state":         {"opened"},
+		"source_branch": {head},
+		"target_branch": {base},
+	}
+	var mergeRequests []gitLabMergeRequest
+	if err := g.client.do(ctx, http.MethodGet, g.mergeRequestsPath()+"?"+query.Encode(), nil, &mergeRequests); err != nil {
+		return nil, err
+	}
+	if len(mergeRequests) == 0 {
+		return nil, nil
+	}
+	return mergeRequests[0].changeRequest(), nil
+}
+
+func (g *gitLab) Create(ctx context.Context, request ChangeRequest) (*ChangeRequest, error) {
+	in := map[string]string{
+		"title":         request.Title,
+		"description":   request.Body,
+		"source_branch": request.Head,
+		"target_branch": request.Base,
+	}
+	var mergeRequest gitLabMergeRequest
+	if err := g.client.do(ctx, http.MethodPost, g.mergeRequestsPath(), in, &mergeRequest); err != nil {
+		return nil, err
+	}
+	return mergeRequest.changeRequest(), nil
+}
+
+func (g *gitLab) Update(ctx context.Context, request ChangeRequest) (*ChangeRequest, error) {
+	in := map[string]string{
+		"title":       request.Title,
+		"description": request.Body,
+	}
+	var mergeRequest gitLabMergeRequest
+	path := fmt.Sprintf("%s/%d", g.mergeRequestsPath(), request.Number)
+	if err := g.client.do(ctx, http.MethodPut, path, in, &mergeRequest); err != nil {
+		return nil, err
+	}
+	return mergeRequest.changeRequest(), nil
+}
@@ -0,0 +1,211 @@
+package interceptor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+)
+
+// CommitMessageData is what the commitMessageTemplate and the values of the
+// commit trailers of a syncer are executed with.
+type CommitMessageData struct {
+	Operation string
+	Group     string
+	Version   string
+	Resource  string
+	// Namespace is empty when the object is cluster-scoped.
+	Namespace string
+	Name      string
+	// The Kubernetes user that made the intercepted request.
+	Username string
+	Groups   []string
+	// The UID of the intercepted admission request.
+	UID             string
+	Syncer          string
+	SyncerNamespace string
+	Paths           ClaimedPaths
+	// Default is the message of the commit when there is no template.
+	Default string
+}
+
+// NewCommitMessageData gathers the data of the commit of the intercepted object.
+func NewCommitMessageData(params GitPipelineParams, paths ClaimedPaths, defaultMessage string) CommitMessageData {
+	return CommitMessageData{
+		Operation:       string(params.Operation),
+		Group:           params.InterceptedGVR.Group,
+		Version:         params.InterceptedGVR.Version,
+		Resource:        params.InterceptedGVR.Resource,
+		Namespace:       params.Syncer.InterceptedNamespace,
+		Name:            params.InterceptedName,
+		Username:        params.Author.Username,
+		Groups:          params.Author.Groups,
+		UID:             string(params.AdmissionUID),
+		Syncer:          params.Syncer.Ref.Name,
+		SyncerNamespace: params.Syncer.Ref.Namespace,
+		Paths:           paths,
+		Default:         defaultMessage,
+	}
+}
+
+var errEmptyCommitMessage = errors.New("the commit message template rendered an empty message")
+
+var commitTemplateFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"join":  func(sep string, elems []string) string { return strings.Join(elems, sep) },
+}
+
+// ParseCommitTemplate parses a commitMessageTemplate or the value of a commit trailer.
+func ParseCommitTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(commitTemplateFuncs).Option("missingkey=error").Parse(text)
+}
+
+func executeCommitTemplate(name, text string, data CommitMessageData) (string, error) {
+	tmpl, err := ParseCommitTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// BuildCommitMessage renders the message of a commit: the commitMessageTemplate
+// of the syncer, or data.Default, followed by the trailers. signer is the
+// "Name <email>" identity of the Signed-off-by trailer.
+func BuildCommitMessage(spec syngit.RemoteSyncerSpec, data CommitMessageData, signer string) (string, error) {
+	message, err := renderCommitMessage(spec, data)
+	if err != nil {
+		return "", err
+	}
+	trailers, err := renderCommitTrailers(spec, data)
+	if err != nil {
+		return "", err
+	}
+	return appendCommitTrailers(spec, message, trailers, signer), nil
+}
+
+// BuildBatchCommitMessage renders the message of a commit that holds the
+// changes of several objects: a summary line, then the first line of the
+// message of each object. The trailers are rendered for each object, and a
+// trailer that several objects render the same is only added once.
+func BuildBatchCommitMessage(spec syngit.RemoteSyncerSpec, data []CommitMessageData, signer string) (string, error) {
+	if len(data) == 1 {
+		return BuildCommitMessage(spec, data[0], signer)
+	}
+
+	additions, deletions := 0, 0
+	lines := make([]string, 0, len(data))
+	trailers := []string{}
+	for _, d := range data {
+		message, err := renderCommitMessage(spec, d)
+		if err != nil {
+			return "", err
+		}
+		subject, _, _ := strings.Cut(message, "\n")
+		lines = append(lines, "- "+subject)
+		objectTrailers, err := renderCommitTrailers(spec, d)
+		if err != nil {
+			return "", err
+		}
+		for _, trailer := range objectTrailers {
+			if !slices.Contains(trailers, trailer) {
+				trailers = append(trailers, trailer)
+			}
+		}
+		additions += len(d.Paths.Add)
+		deletions += len(d.Paths.Delete)
+	}
+
+	counts := ""
+	if additions > 0 {
+		counts += fmt.Sprintf("%d+", additions)
+	}
+	if deletions > 0 {
+		counts += fmt.Sprintf("%d-", deletions)
+	}
+	summary := strings.TrimPrefix(fmt.Sprintf("%s %d objects", counts, len(data)), " ")
+
+	return appendCommitTrailers(spec, summary+"\n\n"+strings.Join(lines, "\n"), trailers, signer), nil
+}
+
+func renderCommitMessage(spec syngit.RemoteSyncerSpec, data CommitMessageData) (string, error) {
+	if spec.CommitMessageTemplate == "" {
+		return data.Default, nil
+	}
+	rendered, err := executeCommitTemplate("commitMessageTemplate", spec.CommitMessageTemplate, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to render the commit message template: %w", err)
+	}
+	message := strings.TrimSpace(rendered)
+	if message == "" {
+		return "", errEmptyCommitMessage
+	}
+	return message, nil
+}
+
+// renderCommitTrailers renders the custom commit trailers of spec for data.
+func renderCommitTrailers(spec syngit.RemoteSyncerSpec, data CommitMessageData) ([]string, error) {
+	trailers := make([]string, 0, len(spec.CommitTrailers.Custom))
+	for _, trailer := range spec.CommitTrailers.Custom {
+		value, err := executeCommitTemplate(trailer.Key, trailer.Value, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render the %s commit trailer: %w", trailer.Key, err)
+		}
+		trailers = append(trailers, fmt.Sprintf("%s: %s", trailer.Key, strings.Join(strings.Fields(value), " ")))
+	}
+	return trailers, nil
+}
+
+// appendCommitTrailers appends the rendered trailers to the message, then the
+// Signed-off-by trailer when spec asks for it.
+func appendCommitTrailers(spec syngit.RemoteSyncerSpec, message string, trailers []string, signer string) string {
+	if spec.CommitTrailers.SignOff {
+		signOff := "Signed-off-by: " + signer
+		if !containsLine(message, signOff) {
+			trailers = append(trailers, signOff)
+		}
+	}
+
+	if len(trailers) == 0 {
+		return message
+	}
+	return message + "\n\n" + strings.Join(trailers, "\n")
+}
+
+// ValidateCommitMessage checks that the commit message of spec can be rendered.
+// Field names are only resolved when the template is executed, so it is
+// executed against sample data. A template that renders nothing for that data
+// may render something for another object: it is not an error here.
+func ValidateCommitMessage(spec syngit.RemoteSyncerSpec) error {
+	data := CommitMessageData{
+		Operation: "CREATE",
+		Version:   "v1",
+		Resource:  "configmaps",
+		Namespace: "namespace",
+		Name:      "name",
+		Paths:     ClaimedPaths{Add: []string{"path"}, Delete: []string{}},
+		Default:   "1+ configmaps./v1: namespace/name",
+	}
+	_, err := BuildCommitMessage(spec, data, "name <email>")
+	if errors.Is(err, errEmptyCommitMessage) {
+		return nil
+	}
+	return err
+}
+
+func containsLine(text, line string) bool {
+	for _, l := range strings.Split(text, "\n") {
+		if strings.TrimSpace(l) == line {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package interceptor
+
+import (
+	"testing"
+
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestBuildCommitMessage(t *testing.T) {
+	params := GitPipelineParams{
+		Syncer: SyncerContext{
+			Ref:                  types.NamespacedName{Namespace: "syncers", Name: "my-syncer"},
+			InterceptedNamespace: "default",
+		},
+		InterceptedGVR:  schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+		InterceptedName: "nginx",
+		Operation:       admissionv1.Delete,
+		Author:          authenticationv1.UserInfo{Username: "alice", Groups: []string{"dev", "ops"}},
+		AdmissionUID:    "1234",
+	}
+	data := NewCommitMessageData(params, ClaimedPaths{Delete: []string{"default/nginx.yaml"}}, "1- deployments.apps/v1: default/nginx")
+	const signer = "Alice <alice@example.com>"
+
+	tests := []struct {
+		name    string
+		spec    syngit.RemoteSyncerSpec
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "default message",
+			want: "1- deployments.apps/v1: default/nginx",
+		},
+		{
+			name: "conventional commit",
+			spec: syngit.RemoteSyncerSpec{
+				CommitMessageTemplate: `{{ if eq .Operation "DELETE" }}chore{{ else }}feat{{ end }}({{ .Resource }}): {{ lower .Operation }} {{ .Namespace }}/{{ .Name }}`,
+			},
+			want: "chore(deployments): delete default/nginx",
+		},
+		{
+			name: "trailers in order, sign-off last",
+			spec: syngit.RemoteSyncerSpec{
+				CommitMessageTemplate: "{{ .Default }}\n\nSyncer: {{ .SyncerNamespace }}/{{ .Syncer }}",
+				CommitTrailers: syngit.CommitTrailers{
+					SignOff: true,
+					Custom: []syngit.CommitTrailer{
+						{Key: "Kubernetes-User", Value: "{{ .Username }} ({{ join \",\" .Groups }})"},
+						{Key: "Admission-UID", Value: "{{ .UID }}\n"},
+					},
+				},
+			},
+			want: "1- deployments.apps/v1: default/nginx\n\nSyncer: syncers/my-syncer\n\n" +
+				"Kubernetes-User: alice (dev,ops)\nAdmission-UID: 1234\nSigned-off-by: Alice <alice@example.com>",
+		},
+		{
+			name: "sign-off already rendered by the template",
+			spec: syngit.RemoteSyncerSpec{
+				CommitMessageTemplate: "{{ .Default }}\n\nSigned-off-by: Alice <alice@example.com>",
+				CommitTrailers:        syngit.CommitTrailers{SignOff: true},
+			},
+			want: "1- deployments.apps/v1: default/nginx\n\nSigned-off-by: Alice <alice@example.com>",
+		},
+		{
+			name:    "unknown field",
+			spec:    syngit.RemoteSyncerSpec{CommitMessageTemplate: "{{ .Unknown }}"},
+			wantErr: true,
+		},
+		{
+			name:    "empty message",
+			spec:    syngit.RemoteSyncerSpec{CommitMessageTemplate: `{{ if eq .Operation "CREATE" }}created{{ end }}`},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := BuildCommitMessage(tc.spec, data, signer)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("BuildCommitMessage() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("BuildCommitMessage()=%q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuildBatchCommitMessage(t *testing.T) {
+	params := GitPipelineParams{
+		InterceptedGVR: schema.GroupVersionResource{Version: "v1", Resource: "configmaps"},
+		Operation:      admissionv1.Create,
+	}
+	data := []CommitMessageData{
+		NewCommitMessageData(params, ClaimedPaths{Add: []string{"default/a.yaml"}}, "1+ configmaps/v1: default/a"),
+		NewCommitMessageData(params, ClaimedPaths{Delete: []string{"default/b.yaml"}}, "1- configmaps/v1: default/b"),
+	}
+	spec := syngit.RemoteSyncerSpec{CommitTrailers: syngit.CommitTrailers{SignOff: true}}
+
+	got, err := BuildBatchCommitMessage(spec, data, "Alice <alice@example.com>")
+	if err != nil {
+		t.Fatalf("BuildBatchCommitMessage: %v", err)
+	}
+	want := "1+1- 2 objects\n\n- 1+ configmaps/v1: default/a\n- 1- configmaps/v1: default/b\n\nSigned-off-by: Alice <alice@example.com>"
+	if got != want {
+		t.Errorf("BuildBatchCommitMessage()=%q, want %q", got, want)
+	}
+
+	single, err := BuildBatchCommitMessage(syngit.RemoteSyncerSpec{}, data[:1], "")
+	if err != nil || single != "1+ configmaps/v1: default/a" {
+		t.Errorf("BuildBatchCommitMessage() of one change=%q, %v", single, err)
+	}
+
+	// The trailers are rendered for each object, once per distinct value.
+	data[0].Name, data[0].Username = "a", "alice"
+	data[1].Name, data[1].Username = "b", "alice"
+	spec.CommitTrailers.Custom = []syngit.CommitTrailer{
+		{Key: "Object", Value: "{{ .Name }}"},
+		{Key: "Requested-by", Value: "{{ .Username }}"},
+	}
+	got, err = BuildBatchCommitMessage(spec, data, "Alice <alice@example.com>")
+	if err != nil {
+		t.Fatalf("BuildBatchCommitMessage: %v", err)
+	}
+	want = "1+1- 2 objects\n\n- 1+ configmaps/v1: default/a\n- 1- configmaps/v1: default/b\n\n" +
+		"Object: a\nRequested-by: alice\nObject: b\nSigned-off-by: Alice <alice@example.com>"
+	if got != want {
+		t.Errorf("BuildBatchCommitMessage() with trailers=%q, want %q", got, want)
+	}
+}
+
+func TestValidateCommitMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    syngit.RemoteSyncerSpec
+		wantErr bool
+	}{
+		{"no template", syngit.RemoteSyncerSpec{}, false},
+		{"valid template", syngit.RemoteSyncerSpec{CommitMessageTemplate: "feat: {{ .Name }}"}, false},
+		{"empty for the sample data", syngit.RemoteSyncerSpec{CommitMessageTemplate: `{{ if eq .Operation "DELETE" }}x{{ end }}`}, false},
+		{"syntax error", syngit.RemoteSyncerSpec{CommitMessageTemplate: "{{ .Name "}, true},
+		{"unknown function", syngit.RemoteSyncerSpec{CommitMessageTemplate: "{{ title .Name }}"}, true},
+		{"unknown field in a trailer", syngit.RemoteSyncerSpec{CommitTrailers: syngit.CommitTrailers{
+			Custom: []syngit.CommitTrailer{{Key: "User", Value: "{{ .User }}"}},
+		}}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ValidateCommitMessage(tc.spec); (err != nil) != tc.wantErr {
+				t.Errorf("ValidateCommitMessage() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package interceptor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// PathTemplateData is what the pathTemplate of a syncer is executed with.
+type PathTemplateData struct {
+	Group    string
+	Version  string
+	Resource string
+	Kind     string
+	// Namespace is empty when the object is cluster-scoped.
+	Namespace   string
+	Name        string
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+var (
+	ErrPathTemplateEmpty     = errors.New("the path template rendered an empty path")
+	ErrPathTemplateTraversal = errors.New("the path template must not render a path out of the rootPath")
+	ErrPathTemplateInvalid   = errors.New("the path template rendered a path with invalid characters")
+	ErrPathTemplateGitDir    = errors.New("the path template must not render a path into a .git directory")
+)
+
+// invalidPathCharacters are the characters that a path of the repository
+// cannot hold.
+const invalidPathCharacters = `:*?"<>|\`
+
+var pathTemplateFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// ParsePathTemplate parses a pathTemplate. A label or an annotation that an
+// object does not have renders empty.
+func ParsePathTemplate(text string) (*template.Template, error) {
+	return template.New("pathTemplate").Funcs(pathTemplateFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// RenderPath renders the pathTemplate for an object and returns the cleaned
+// path, relative to the rootPath of the syncer. The path must stay within it.
+func RenderPath(pathTemplate string, data PathTemplateData) (string, error) {
+	tmpl, err := ParsePathTemplate(pathTemplate)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed to render the path template: %w", err)
+	}
+	return validateRenderedPath(out.String())
+}
+
+func validateRenderedPath(rendered string) (string, error) {
+	rendered = strings.TrimSpace(rendered)
+	if strings.ContainsAny(rendered, invalidPathCharacters) || strings.ContainsFunc(rendered, isControl) {
+		return "", fmt.Errorf("%w: %s", ErrPathTemplateInvalid, rendered)
+	}
+	cleaned := path.Clean(rendered)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("%w: %s", ErrPathTemplateTraversal, rendered)
+	}
+	// A label that the object does not have may leave a leading slash.
+	cleaned = strings.TrimLeft(cleaned, "/")
+	if cleaned == "" || cleaned == "." {
+		return "", ErrPathTemplateEmpty
+	}
+	// The worktree of the cached clone holds its .git directory: a file
+	// written there would rewrite its hooks, config or refs.
+	for _, segment := range strings.Split(cleaned, "/") {
+		if strings.EqualFold(segment, ".git") {
+			return "", fmt.Errorf("%w: %s", ErrPathTemplateGitDir, rendered)
+		}
+	}
+	return cleaned, nil
+}
+
+func isControl(r rune) bool {
+	return r < ' ' || r == 0x7f
+}
+
+// ValidatePathTemplate checks that a pathTemplate can be rendered. Field names
+// are only resolved when the template is executed, so it is executed against
+// sample data, which must render a path within the rootPath. The sample object
+// has no labels nor annotations: a template that renders nothing for it may
+// render a path for another object.
+func ValidatePathTemplate(pathTemplate string) error {
+	data := PathTemplateData{
+		Group:       "apps",
+		Version:     "v1",
+		Resource:    "deployments",
+		Kind:        "Deployment",
+		Namespace:   "namespace",
+		Name:        "name",
+		Labels:      map[string]string{},
+		Annotations: map[string]string{},
+	}
+	_, err := RenderPath(pathTemplate, data)
+	if errors.Is(err, ErrPathTemplateEmpty) {
+		return nil
+	}
+	return err
+}
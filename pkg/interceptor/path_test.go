@@ -0,0 +1,119 @@
+package interceptor
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRenderPath(t *testing.T) {
+	data := PathTemplateData{
+		Group:     "apps",
+		Version:   "v1",
+		Resource:  "deployments",
+		Kind:      "Deployment",
+		Namespace: "default",
+		Name:      "nginx",
+		Labels:    map[string]string{"team": "web", "repo": ".Git"},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		wantErr  error
+	}{
+		{
+			name:     "kind and name",
+			template: "apps/{{ .Namespace }}/{{ lower .Kind }}-{{ .Name }}.yaml",
+			want:     "apps/default/deployment-nginx.yaml",
+		},
+		{
+			name:     "label of the object",
+			template: "teams/{{ .Labels.team }}/{{ .Resource }}",
+			want:     "teams/web/deployments",
+		},
+		{
+			name:     "missing label leaves a leading slash",
+			template: "{{ .Labels.app }}/{{ .Name }}.yaml",
+			want:     "nginx.yaml",
+		},
+		{
+			name:     "cleaned path",
+			template: "./{{ .Namespace }}//{{ .Group }}/../{{ .Name }}.yaml",
+			want:     "default/nginx.yaml",
+		},
+		{
+			name:     "path out of the rootPath",
+			template: "../{{ .Name }}.yaml",
+			wantErr:  ErrPathTemplateTraversal,
+		},
+		{
+			name:     "path out of the rootPath through a label",
+			template: "{{ .Namespace }}/../../{{ .Name }}.yaml",
+			wantErr:  ErrPathTemplateTraversal,
+		},
+		{
+			name:     "invalid characters",
+			template: "{{ .Namespace }}:{{ .Name }}.yaml",
+			wantErr:  ErrPathTemplateInvalid,
+		},
+		{
+			name:     "path into the .git directory",
+			template: ".git/hooks/{{ .Name }}",
+			wantErr:  ErrPathTemplateGitDir,
+		},
+		{
+			name:     "path into the .git directory through a label",
+			template: "teams/{{ .Labels.repo }}/config",
+			wantErr:  ErrPathTemplateGitDir,
+		},
+		{
+			name:     "segment that only starts with .git",
+			template: ".github/{{ .Name }}.yaml",
+			want:     ".github/nginx.yaml",
+		},
+		{
+			name:     "empty path",
+			template: "{{ .Labels.app }}",
+			wantErr:  ErrPathTemplateEmpty,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := RenderPath(tc.template, data)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("RenderPath() error = %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("RenderPath()=%q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidatePathTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  bool
+	}{
+		{"default layout", "{{ .Namespace }}/{{ .Group }}/{{ .Version }}/{{ .Resource }}", false},
+		{"label only", "{{ .Labels.app }}", false},
+		{"syntax error", "{{ .Name ", true},
+		{"unknown field", "{{ .Cluster }}/{{ .Name }}.yaml", true},
+		{"unknown function", "{{ title .Name }}.yaml", true},
+		{"traversal", "../../{{ .Name }}.yaml", true},
+		{"invalid characters", "{{ .Name }}?.yaml", true},
+		{".git directory", "{{ .Namespace }}/.git/{{ .Name }}.yaml", true},
+		{".git directory in another case", ".GIT/hooks/{{ .Name }}", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := ValidatePathTemplate(tc.template); (err != nil) != tc.wantErr {
+				t.Errorf("ValidatePathTemplate() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -3,14 +3,42 @@ package interceptor
 import (
 	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 
 	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
 )
 
 type GitUserInfo struct {
 	User  string
 	Email string
 	Token string
+	// SSHPrivateKey is set instead of Token when the RemoteUser authenticates
+	// with a kubernetes.io/ssh-auth Secret. KnownHosts then holds the entries
+	// the host key of the git server is verified against.
+	SSHPrivateKey string
+	KnownHosts    string
+	// SigningKey is the armored OpenPGP or OpenSSH private key the commits
+	// are signed with, if any, and SigningKeyPassphrase unlocks it.
+	SigningKey           string
+	SigningKeyPassphrase string
+	// RemoteUserRef is the RemoteUser the credentials were read from. It is
+	// what a queued commit keeps instead of the credentials themselves.
+	RemoteUserRef types.NamespacedName
+}
+
+// GitTransport is how the git server is reached, besides the CA bundle it is
+// trusted with.
+type GitTransport struct {
+	// ProxyURL is the HTTP proxy the requests go through, if any, and
+	// ProxyUsername and ProxyPassword authenticate to it.
+	ProxyURL      string
+	ProxyUsername string
+	ProxyPassword string
+	// ClientCert and ClientKey are the PEM encoded certificate and key
+	// presented to a git server that requires a client certificate.
+	ClientCert []byte
+	ClientKey  []byte
 }
 
 type GitPipelineParams struct {
@@ -19,9 +47,20 @@ type GitPipelineParams struct {
 	InterceptedYAML string
 	InterceptedGVR  schema.GroupVersionResource
 	InterceptedName string
-	GitUserInfo     GitUserInfo
-	Operation       admissionv1.Operation
-	CABundle        []byte
+	// The kind, the labels and the annotations of the intercepted object,
+	// that the pathTemplate of the syncer can use.
+	InterceptedPathMetadata syngit.PathMetadata
+	// The ones the intercepted object had before an update, so that the file
+	// a pathTemplate placed it in can be moved. Nil for the other operations.
+	InterceptedOldPathMetadata *syngit.PathMetadata
+	GitUserInfo                GitUserInfo
+	Operation                  admissionv1.Operation
+	CABundle                   []byte
+	Transport                  GitTransport
+	// The kubernetes user that made the intercepted request.
+	Author authenticationv1.UserInfo
+	// The UID of the intercepted admission request.
+	AdmissionUID types.UID
 }
 
 type ClaimedPaths struct {
@@ -57,4 +96,16 @@ type GitPushResponse struct {
 	Paths      []string // The git paths where the resource has been pushed
 	CommitHash string   // The commit hash of the commit
 	URL        string   // The url of the repository
+	// The PendingCommit the resource has been queued in when the push failed
+	// with the BlockCacheCommit behavior. CommitHash is empty in that case.
+	PendingCommit string
+	// The pull/merge request the commit is proposed in when the RemoteTarget
+	// uses the ChangeRequest delivery mode.
+	ChangeRequestURL string
+	// Whether the resource is pushed in the background, with the
+	// ApplyThenCommit strategy. Only URL is set in that case.
+	Async bool
+	// The unified diff of the claimed paths when the syncer enables the
+	// diff preview. It is already truncated to spec.diffPreview.maxSize.
+	Diff string
 }
@@ -0,0 +1,172 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/interpreter"
+	"github.com/syngit-org/syngit/internal/cache"
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// RoutedObject is what the routing rules of a syncer match an intercepted
+// object on.
+type RoutedObject struct {
+	Group   string
+	Version string
+	Kind    string
+	// Namespace is empty when the object is cluster-scoped.
+	Namespace string
+	Labels    map[string]string
+	// Object is the intercepted object, the deleted one for a deletion, as
+	// decoded from JSON. It is the "object" variable of the expressions.
+	Object map[string]interface{}
+}
+
+var ErrRoutingExpressionNotBool = errors.New("the routing expression must evaluate to a bool")
+
+const (
+	// The cost an expression may add up to when it is evaluated for an
+	// object, the per-expression limit of the validation rules of the CRDs.
+	routingExpressionCostLimit = 1000000
+	// How many comprehension iterations run between two checks of the
+	// cancellation of the evaluation.
+	routingExpressionInterruptCheckFrequency = 100
+	// How many compiled expressions are kept, the most used ones.
+	routingProgramCacheSize = 256
+)
+
+var (
+	routingEnv = sync.OnceValues(func() (*cel.Env, error) {
+		return cel.NewEnv(cel.Variable("object", cel.DynType))
+	})
+	routingPrograms = cache.NewLFU[string, cel.Program](routingProgramCacheSize)
+)
+
+// CompileRoutingExpression compiles the CEL expression of a routing rule.
+func CompileRoutingExpression(expression string) (cel.Program, error) {
+	if program, ok := routingPrograms.Get(expression); ok {
+		return program, nil
+	}
+
+	env, err := routingEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	if ast.OutputType() != cel.BoolType && ast.OutputType() != cel.DynType {
+		return nil, fmt.Errorf("%w, not %s", ErrRoutingExpressionNotBool, ast.OutputType())
+	}
+	program, err := env.Program(ast,
+		cel.CostLimit(routingExpressionCostLimit),
+		cel.InterruptCheckFrequency(routingExpressionInterruptCheckFrequency),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	program, _ = routingPrograms.LoadOrStore(expression, program)
+	return program, nil
+}
+
+// MatchRoutingRule returns the first of the rules that matches the object, or
+// nil when none does.
+func MatchRoutingRule(ctx context.Context, rules []syngit.RoutingRule, object RoutedObject) (*syngit.RoutingRule, error) {
+	for i := range rules {
+		matched, err := matchesRoutingRule(ctx, rules[i].Match, object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to match the routing rule %s: %w", rules[i].Name, err)
+		}
+		if matched {
+			return &rules[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// FindRoutingRule returns the rule with that name, or nil when the rules do
+// not have it anymore.
+func FindRoutingRule(rules []syngit.RoutingRule, name string) *syngit.RoutingRule {
+	if name == "" {
+		return nil
+	}
+	for i := range rules {
+		if rules[i].Name == name {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+func matchesRoutingRule(ctx context.Context, match syngit.RoutingMatch, object RoutedObject) (bool, error) {
+	if len(match.Kinds) > 0 && !slices.ContainsFunc(match.Kinds, func(kind syngit.RoutingKind) bool {
+		return matchesKind(kind, object)
+	}) {
+		return false, nil
+	}
+
+	if len(match.Namespaces) > 0 && (object.Namespace == "" || !slices.Contains(match.Namespaces, object.Namespace)) {
+		return false, nil
+	}
+
+	if match.LabelSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(match.LabelSelector)
+		if err != nil {
+			return false, err
+		}
+		if !selector.Matches(labels.Set(object.Labels)) {
+			return false, nil
+		}
+	}
+
+	if match.Expression != "" {
+		return evalRoutingExpression(ctx, match.Expression, object.Object)
+	}
+
+	return true, nil
+}
+
+func matchesKind(kind syngit.RoutingKind, object RoutedObject) bool {
+	return (kind.Group == "*" || kind.Group == object.Group) &&
+		(kind.Version == "" || kind.Version == object.Version) &&
+		(kind.Kind == "*" || kind.Kind == object.Kind)
+}
+
+// evalRoutingExpression evaluates the expression for the object. An
+// expression that cannot be evaluated for it, because of a field that it does
+// not have for instance, does not match it. One that exceeds its cost limit, or
+// whose context ends, fails.
+func evalRoutingExpression(ctx context.Context, expression string, object map[string]interface{}) (bool, error) {
+	program, err := CompileRoutingExpression(expression)
+	if err != nil {
+		return false, err
+	}
+	if object == nil {
+		object = map[string]interface{}{}
+	}
+
+	out, _, err := program.ContextEval(ctx, map[string]interface{}{"object": object})
+	if err != nil {
+		if cancelled := (interpreter.EvalCancelledError{}); errors.As(err, &cancelled) {
+			return false, err
+		}
+		if ctx.Err() != nil {
+			return false, fmt.Errorf("the evaluation was interrupted: %w", ctx.Err())
+		}
+		return false, nil
+	}
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("%w, not %s", ErrRoutingExpressionNotBool, out.Type())
+	}
+	return matched, nil
+}
@@ -0,0 +1,225 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMatchRoutingRule(t *testing.T) {
+	rules := []syngit.RoutingRule{
+		{Name: "secrets", Match: syngit.RoutingMatch{Kinds: []syngit.RoutingKind{{Group: "", Kind: "Secret"}}}},
+		{Name: "team-a", Match: syngit.RoutingMatch{
+			Namespaces:    []string{"team-a"},
+			LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tier": "web"}},
+		}},
+		{Name: "large", Match: syngit.RoutingMatch{
+			Kinds:      []syngit.RoutingKind{{Group: "*", Version: "v1", Kind: "*"}},
+			Expression: "object.spec.replicas > 3",
+		}},
+		{Name: "crds", Match: syngit.RoutingMatch{Kinds: []syngit.RoutingKind{{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}}}},
+	}
+
+	deployment := func(namespace string, labels map[string]string, replicas float64) RoutedObject {
+		return RoutedObject{
+			Group: "apps", Version: "v1", Kind: "Deployment", Namespace: namespace, Labels: labels,
+			Object: map[string]interface{}{"spec": map[string]interface{}{"replicas": replicas}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		object RoutedObject
+		want   string
+	}{
+		{
+			name:   "kind of the core group",
+			object: RoutedObject{Version: "v1", Kind: "Secret", Namespace: "team-a", Labels: map[string]string{"tier": "web"}},
+			want:   "secrets",
+		},
+		{
+			name:   "namespace and labels",
+			object: deployment("team-a", map[string]string{"tier": "web"}, 5),
+			want:   "team-a",
+		},
+		{
+			name:   "labels that do not match",
+			object: deployment("team-a", map[string]string{"tier": "db"}, 2),
+			want:   "",
+		},
+		{
+			name:   "expression",
+			object: deployment("team-b", nil, 5),
+			want:   "large",
+		},
+		{
+			name:   "expression on a field that the object does not have",
+			object: RoutedObject{Version: "v1", Kind: "ConfigMap", Namespace: "team-b", Object: map[string]interface{}{}},
+			want:   "",
+		},
+		{
+			name:   "cluster-scoped object",
+			object: RoutedObject{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition", Labels: map[string]string{"tier": "web"}},
+			want:   "crds",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := MatchRoutingRule(context.Background(), rules, tc.object)
+			if err != nil {
+				t.Fatalf("MatchRoutingRule: %v", err)
+			}
+			got := ""
+			if rule != nil {
+				got = rule.Name
+			}
+			if got != tc.want {
+				t.Errorf("MatchRoutingRule()=%q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMatchRoutingRuleCostLimit(t *testing.T) {
+	items := make([]interface{}, 100)
+	for i := range items {
+		items[i] = float64(i)
+	}
+	object := RoutedObject{Object: map[string]interface{}{"items": items}}
+	rules := []syngit.RoutingRule{{Name: "expensive", Match: syngit.RoutingMatch{
+		Expression: "object.items.all(a, object.items.all(b, object.items.all(c, true)))",
+	}}}
+
+	if _, err := MatchRoutingRule(context.Background(), rules, object); err == nil {
+		t.Errorf("MatchRoutingRule() of an expression over its cost limit did not fail")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rules[0].Match.Expression = "object.items.all(a, object.items.all(b, b >= 0))"
+	if _, err := MatchRoutingRule(ctx, rules, object); err == nil {
+		t.Errorf("MatchRoutingRule() with an ended context did not fail")
+	}
+}
+
+func TestCompileRoutingExpression(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		wantErr    bool
+	}{
+		{"bool", "object.metadata.name.startsWith('team-a-')", false},
+		{"field of the object", "object.spec.enabled", false},
+		{"syntax error", "object.metadata.name ==", true},
+		{"unknown variable", "request.name == 'x'", true},
+		{"string", "object.metadata.name + '-x'", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := CompileRoutingExpression(tc.expression); (err != nil) != tc.wantErr {
+				t.Errorf("CompileRoutingExpression() error = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+
+	_, err := CompileRoutingExpression("1 + 1")
+	if !errors.Is(err, ErrRoutingExpressionNotBool) {
+		t.Errorf("CompileRoutingExpression() error = %v, want %v", err, ErrRoutingExpressionNotBool)
+	}
+}
+
+func TestSyncerContextRouting(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}
+	sc := SyncerContext{Spec: syngit.RemoteSyncerSpec{RootPath: "clusters/prod", PathTemplate: "{{ .Name }}.yaml"}}
+	routed := sc.WithRoutingRule(&syngit.RoutingRule{
+		Name:                 "team-a",
+		RootPath:             "teams/a",
+		RemoteTargetSelector: selector,
+		TargetBranch:         "team-a",
+	})
+
+	if routed.Spec.RootPath != "teams/a" || routed.Spec.PathTemplate != "{{ .Name }}.yaml" || routed.Spec.RemoteTargetSelector != selector {
+		t.Errorf("WithRoutingRule: RootPath=%q PathTemplate=%q RemoteTargetSelector=%v",
+			routed.Spec.RootPath, routed.Spec.PathTemplate, routed.Spec.RemoteTargetSelector)
+	}
+	if sc.Spec.RootPath != "clusters/prod" {
+		t.Errorf("WithRoutingRule changed the spec of the syncer: RootPath=%q", sc.Spec.RootPath)
+	}
+	if unrouted := sc.WithRoutingRule(nil); unrouted.RoutingRule != nil || unrouted.Spec.RootPath != "clusters/prod" {
+		t.Errorf("WithRoutingRule(nil) routed the syncer")
+	}
+
+	direct := syngit.RemoteTarget{Spec: syngit.RemoteTargetSpec{
+		UpstreamRepository: "https://git.example.com/org/repo.git", UpstreamBranch: "main",
+		TargetRepository: "https://git.example.com/org/repo.git", TargetBranch: "main",
+	}}
+	if got := routed.RouteRemoteTarget(direct).Spec; got.UpstreamBranch != "team-a" || got.TargetBranch != "team-a" {
+		t.Errorf("RouteRemoteTarget of a direct target: upstream=%q target=%q, want team-a", got.UpstreamBranch, got.TargetBranch)
+	}
+	if direct.Spec.TargetBranch != "main" {
+		t.Errorf("RouteRemoteTarget changed the RemoteTarget: TargetBranch=%q", direct.Spec.TargetBranch)
+	}
+
+	merged := direct
+	merged.Spec.TargetBranch = "staging"
+	merged.Spec.MergeStrategy = syngit.TryFastForwardOrDie
+	if got := routed.RouteRemoteTarget(merged).Spec; got.UpstreamBranch != "main" || got.TargetBranch != "team-a" {
+		t.Errorf("RouteRemoteTarget of a merged target: upstream=%q target=%q, want main and team-a", got.UpstreamBranch, got.TargetBranch)
+	}
+
+	if got := sc.RouteRemoteTarget(merged).Spec.TargetBranch; got != "staging" {
+		t.Errorf("RouteRemoteTarget without a routing rule: target=%q, want staging", got)
+	}
+}
+
+func TestSyncerContextRerouting(t *testing.T) {
+	sc := SyncerContext{Spec: syngit.RemoteSyncerSpec{RootPath: "clusters/prod"}}
+	teamA := &syngit.RoutingRule{Name: "team-a", RootPath: "teams/a", PathTemplate: "{{ .Name }}.yaml"}
+	teamB := &syngit.RoutingRule{Name: "team-b", RootPath: "teams/b"}
+
+	tests := []struct {
+		name          string
+		previous      *syngit.RoutingRule
+		rule          *syngit.RoutingRule
+		wantPlacement *syngit.PathPlacement
+	}{
+		{name: "same rule", previous: teamA, rule: teamA},
+		{name: "no rule", previous: nil, rule: nil},
+		{
+			name:          "other rule",
+			previous:      teamA,
+			rule:          teamB,
+			wantPlacement: &syngit.PathPlacement{RootPath: "teams/a", PathTemplate: "{{ .Name }}.yaml"},
+		},
+		{
+			name:          "no rule anymore",
+			previous:      teamB,
+			rule:          nil,
+			wantPlacement: &syngit.PathPlacement{RootPath: "teams/b"},
+		},
+		{
+			name:          "first rule",
+			previous:      nil,
+			rule:          teamB,
+			wantPlacement: &syngit.PathPlacement{RootPath: "clusters/prod"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			routed := sc.WithRoutingRules(tc.previous, tc.rule)
+			if routed.RoutingRule != tc.rule {
+				t.Errorf("RoutingRule=%v, want %v", routed.RoutingRule, tc.rule)
+			}
+			if (routed.PreviousPlacement == nil) != (tc.wantPlacement == nil) ||
+				(tc.wantPlacement != nil && *routed.PreviousPlacement != *tc.wantPlacement) {
+				t.Errorf("PreviousPlacement=%v, want %v", routed.PreviousPlacement, tc.wantPlacement)
+			}
+		})
+	}
+}
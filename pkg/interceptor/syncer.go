@@ -41,6 +41,14 @@ type SyncerContext struct {
 	// cluster-wide syncer, which makes refs.ResolveNamespace reject any
 	// reference that does not carry its own namespace.
 	RefOwnerNamespace string
+
+	// The routing rule of the spec that the intercepted object matched. It is
+	// already applied to Spec. nil when the object matched none.
+	RoutingRule *syngit.RoutingRule
+
+	// Where the object was placed before an update that changed the routing
+	// rule it matches. nil when the rule did not change.
+	PreviousPlacement *syngit.PathPlacement
 }
 
 // NewRemoteSyncerContext resolves a namespaced RemoteSyncer. Every namespace it
@@ -79,3 +87,64 @@ func (sc SyncerContext) String() string {
 	}
 	return sc.Ref.Namespace + "/" + sc.Ref.Name
 }
+
+// WithRoutingRule returns the context of the syncer for the objects of the
+// rule: its rootPath, pathTemplate and remoteTargetSelector replace the ones
+// of the spec.
+func (sc SyncerContext) WithRoutingRule(rule *syngit.RoutingRule) SyncerContext {
+	if rule == nil {
+		return sc
+	}
+
+	sc.RoutingRule = rule
+	if rule.RootPath != "" {
+		sc.Spec.RootPath = rule.RootPath
+	}
+	if rule.PathTemplate != "" {
+		sc.Spec.PathTemplate = rule.PathTemplate
+	}
+	if rule.RemoteTargetSelector != nil {
+		sc.Spec.RemoteTargetSelector = rule.RemoteTargetSelector
+	}
+	return sc
+}
+
+// WithRoutingRules returns the context of the syncer for an updated object
+// that matched the previous rule before the update and matches the rule now.
+// When the rules differ, PreviousPlacement is where the previous one placed
+// it.
+func (sc SyncerContext) WithRoutingRules(previous, rule *syngit.RoutingRule) SyncerContext {
+	if routingRuleName(previous) != routingRuleName(rule) {
+		previousSpec := sc.WithRoutingRule(previous).Spec
+		sc.PreviousPlacement = &syngit.PathPlacement{
+			RootPath:     previousSpec.RootPath,
+			PathTemplate: previousSpec.PathTemplate,
+		}
+	}
+	return sc.WithRoutingRule(rule)
+}
+
+func routingRuleName(rule *syngit.RoutingRule) string {
+	if rule == nil {
+		return ""
+	}
+	return rule.Name
+}
+
+// RouteRemoteTarget returns the RemoteTarget pushing to the targetBranch of
+// the routing rule, if it has one. A RemoteTarget that commits straight onto
+// its upstream branch commits onto the branch of the rule instead.
+func (sc SyncerContext) RouteRemoteTarget(remoteTarget syngit.RemoteTarget) syngit.RemoteTarget {
+	if sc.RoutingRule == nil || sc.RoutingRule.TargetBranch == "" {
+		return remoteTarget
+	}
+
+	routed := *remoteTarget.DeepCopy()
+	if routed.Spec.MergeStrategy == "" &&
+		routed.Spec.UpstreamRepository == routed.Spec.TargetRepository &&
+		routed.Spec.UpstreamBranch == routed.Spec.TargetBranch {
+		routed.Spec.UpstreamBranch = sc.RoutingRule.TargetBranch
+	}
+	routed.Spec.TargetBranch = sc.RoutingRule.TargetBranch
+	return routed
+}
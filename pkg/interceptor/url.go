@@ -0,0 +1,76 @@
+package interceptor
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// scpLikeURLPattern matches the scp-like syntax git accepts for SSH remotes:
+// "[user@]host:path", as in "git@github.com:org/repo.git".
+var scpLikeURLPattern = regexp.MustCompile(`^(?:([^@/]+)@)?([^@/:]+):(.+)$`)
+
+// ParseRepositoryURL parses the URL of a git repository. On top of the forms
+// url.Parse understands (http, https, git and ssh schemes), it accepts the
+// scp-like syntax of git, returned as its ssh:// equivalent.
+func ParseRepositoryURL(rawURL string) (*url.URL, error) {
+	if !strings.Contains(rawURL, "://") {
+		if m := scpLikeURLPattern.FindStringSubmatch(rawURL); m != nil {
+			u := &url.URL{
+				Scheme: "ssh",
+				Host:   m[2],
+				Path:   "/" + strings.TrimPrefix(m[3], "/"),
+			}
+			if m[1] != "" {
+				u.User = url.User(m[1])
+			}
+			return u, nil
+		}
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("the repository URL %q has no host", rawURL)
+	}
+	return u, nil
+}
+
+// IsSSHRepositoryURL reports whether the repository is reached over SSH.
+func IsSSHRepositoryURL(u *url.URL) bool {
+	return u.Scheme == "ssh" || u.Scheme == "git+ssh" || u.Scheme == "ssh+git"
+}
+
+// RepositoryHost returns the part of the repository URL that is matched
+// against the gitBaseDomainFQDN of a RemoteUser. The port of an SSH URL is
+// dropped: it is the port of the SSH daemon, not the one of the git server
+// the RemoteUser describes.
+func RepositoryHost(u *url.URL) string {
+	if IsSSHRepositoryURL(u) {
+		return u.Hostname()
+	}
+	return u.Host
+}
+
+// CommitURL returns the web page of a commit of the repository, as served by
+// GitHub, GitLab, Gitea and Forgejo. The repository is assumed to be browsable
+// over HTTPS when it is reached over SSH.
+func CommitURL(repositoryURL, commitHash string) (string, error) {
+	u, err := ParseRepositoryURL(repositoryURL)
+	if err != nil {
+		return "", err
+	}
+	scheme := u.Scheme
+	if scheme != "http" {
+		scheme = "https"
+	}
+	web := url.URL{
+		Scheme: scheme,
+		Host:   RepositoryHost(u),
+		Path:   strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), ".git") + "/commit/" + commitHash,
+	}
+	return web.String(), nil
+}
@@ -0,0 +1,76 @@
+package interceptor
+
+import "testing"
+
+func TestParseRepositoryURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawURL   string
+		wantSSH  bool
+		wantHost string
+		wantUser string
+		wantPath string
+		wantErr  bool
+	}{
+		{"https URL", "https://github.com/org/repo.git", false, "github.com", "", "/org/repo.git", false},
+		{"https URL with port", "https://git.example.com:8443/org/repo.git", false, "git.example.com:8443", "", "/org/repo.git", false},
+		{"ssh URL", "ssh://git@github.com/org/repo.git", true, "github.com", "git", "/org/repo.git", false},
+		{"ssh URL with port drops the port from the host", "ssh://git@git.example.com:2222/org/repo.git", true, "git.example.com", "git", "/org/repo.git", false},
+		{"scp-like URL", "git@github.com:org/repo.git", true, "github.com", "git", "/org/repo.git", false},
+		{"scp-like URL without user", "github.com:org/repo.git", true, "github.com", "", "/org/repo.git", false},
+		{"scp-like URL with absolute path", "git@github.com:/srv/repo.git", true, "github.com", "git", "/srv/repo.git", false},
+		{"missing host", "/org/repo.git", false, "", "", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := ParseRepositoryURL(tc.rawURL)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", u)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := IsSSHRepositoryURL(u); got != tc.wantSSH {
+				t.Errorf("IsSSHRepositoryURL=%v, want %v", got, tc.wantSSH)
+			}
+			if got := RepositoryHost(u); got != tc.wantHost {
+				t.Errorf("RepositoryHost=%q, want %q", got, tc.wantHost)
+			}
+			if got := u.User.Username(); got != tc.wantUser {
+				t.Errorf("user=%q, want %q", got, tc.wantUser)
+			}
+			if u.Path != tc.wantPath {
+				t.Errorf("path=%q, want %q", u.Path, tc.wantPath)
+			}
+		})
+	}
+}
+
+func TestCommitURL(t *testing.T) {
+	tests := []struct {
+		rawURL string
+		want   string
+	}{
+		{"https://github.com/org/repo.git", "https://github.com/org/repo/commit/abc"},
+		{"https://user@gitlab.example.com:8443/group/sub/repo", "https://gitlab.example.com:8443/group/sub/repo/commit/abc"},
+		{"http://gitea.local/org/repo.git", "http://gitea.local/org/repo/commit/abc"},
+		{"git@github.com:org/repo.git", "https://github.com/org/repo/commit/abc"},
+		{"ssh://git@git.example.com:2222/org/repo.git", "https://git.example.com/org/repo/commit/abc"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.rawURL, func(t *testing.T) {
+			got, err := CommitURL(tc.rawURL, "abc")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("CommitURL()=%q, want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -2,10 +2,10 @@ package naming
 
 import (
 	"fmt"
-	"net/url"
 	"strings"
 
 	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	"github.com/syngit-org/syngit/pkg/interceptor"
 )
 
 // RemoteTargetName derives the name of the RemoteTarget that syngit manages for
@@ -18,14 +18,14 @@ func RemoteTargetName(
 	targetBranch string,
 ) (string, error) {
 
-	upstreamU, err := url.Parse(upstreamRepo)
+	upstreamU, err := interceptor.ParseRepositoryURL(upstreamRepo)
 	if err != nil {
 		return "", err
 	}
 
 	targetRepoName := syngit.RtManagedDefaultForkNamePrefix
 	if targetRepo != "" {
-		targetU, err := url.Parse(targetRepo)
+		targetU, err := interceptor.ParseRepositoryURL(targetRepo)
 		if err != nil {
 			return "", err
 		}
@@ -51,6 +51,14 @@ func TestRemoteTargetName(t *testing.T) {
 			// "target.git" should not appear (stripped); "target" should.
 			wantContains: []string{"target"},
 		},
+		{
+			name:           "ssh and scp-like URLs produce the same name as https ones",
+			upstreamRepo:   "git@github.com:org/upstream.git",
+			upstreamBranch: "main",
+			targetRepo:     "ssh://git@github.com/org/target.git",
+			targetBranch:   "feature",
+			wantExact:      "org-upstream-main-org-target-feature",
+		},
 		{
 			name:           "invalid URL returns error",
 			upstreamRepo:   "://not-a-url",
@@ -122,6 +122,14 @@ func RemoteSyncerRefs(spec syngit.RemoteSyncerSpec, ownerNamespace string) ([]Ob
 		c.add(ref.Namespace, ref.Name, secretsGVR, c.specPath.Child("sops", "secretRef"))
 	}
 
+	if ref := spec.Proxy.SecretRef; ref.Name != "" {
+		c.add(ref.Namespace, ref.Name, secretsGVR, c.specPath.Child("proxy", "secretRef"))
+	}
+
+	if ref := spec.ClientCertSecretRef; ref.Name != "" {
+		c.add(ref.Namespace, ref.Name, secretsGVR, c.specPath.Child("clientCertSecretRef"))
+	}
+
 	return c.result()
 }
 
@@ -134,6 +142,14 @@ func RemoteUserRefs(spec syngit.RemoteUserSpec, ownerNamespace string) ([]Object
 		c.add(ref.Namespace, ref.Name, secretsGVR, c.specPath.Child("secretRef"))
 	}
 
+	if ref := spec.KnownHostsSecretRef; ref != nil && ref.Name != "" {
+		c.add(ref.Namespace, ref.Name, secretsGVR, c.specPath.Child("knownHostsSecretRef"))
+	}
+
+	if ref := spec.SigningKeySecretRef; ref != nil && ref.Name != "" {
+		c.add(ref.Namespace, ref.Name, secretsGVR, c.specPath.Child("signingKeySecretRef"))
+	}
+
 	return c.result()
 }
 
@@ -85,6 +85,11 @@ func fullRemoteSyncerSpec() syngit.RemoteSyncerSpec {
 			Enabled:   true,
 			SecretRef: corev1.SecretReference{Name: "sops-age", Namespace: "sops-ns"},
 		},
+		Proxy: syngit.ProxyConfig{
+			URL:       "http://proxy.example.com:3128",
+			SecretRef: corev1.SecretReference{Name: "proxy-auth"},
+		},
+		ClientCertSecretRef: corev1.SecretReference{Name: "client-cert", Namespace: "tls-ns"},
 	}
 }
 
@@ -101,6 +106,8 @@ func TestRemoteSyncerRefs(t *testing.T) {
 		{Namespace: "cm-ns", Name: "cm-remote", Group: "", Version: "v1", Resource: "configmaps"},
 		{Namespace: "ca-ns", Name: "ca", Group: "", Version: "v1", Resource: "secrets"},
 		{Namespace: "sops-ns", Name: "sops-age", Group: "", Version: "v1", Resource: "secrets"},
+		{Namespace: ownerNs, Name: "proxy-auth", Group: "", Version: "v1", Resource: "secrets"},
+		{Namespace: "tls-ns", Name: "client-cert", Group: "", Version: "v1", Resource: "secrets"},
 	}
 
 	if len(refs) != len(want) {
@@ -228,6 +235,46 @@ func TestRemoteUserRefs(t *testing.T) {
 		}
 	})
 
+	t.Run("the known hosts secret ref is enumerated after the secret ref", func(t *testing.T) {
+		refs, err := RemoteUserRefs(syngit.RemoteUserSpec{
+			SecretRef:           corev1.SecretReference{Name: "deploy-key"},
+			KnownHostsSecretRef: &corev1.SecretReference{Name: "known-hosts", Namespace: "ssh-ns"},
+		}, ownerNs)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(refs) != 2 {
+			t.Fatalf("got %d refs, want 2: %+v", len(refs), refs)
+		}
+		got := refs[1]
+		if got.Namespace != "ssh-ns" || got.Name != "known-hosts" || got.Resource != "secrets" {
+			t.Errorf("got %+v, want ssh-ns/known-hosts as a secret", got)
+		}
+		if p := got.FieldPath.String(); p != "spec.knownHostsSecretRef" {
+			t.Errorf("got field path %q, want spec.knownHostsSecretRef", p)
+		}
+	})
+
+	t.Run("the signing key secret ref is enumerated", func(t *testing.T) {
+		refs, err := RemoteUserRefs(syngit.RemoteUserSpec{
+			SecretRef:           corev1.SecretReference{Name: "creds"},
+			SigningKeySecretRef: &corev1.SecretReference{Name: "gpg-key"},
+		}, ownerNs)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(refs) != 2 {
+			t.Fatalf("got %d refs, want 2: %+v", len(refs), refs)
+		}
+		got := refs[1]
+		if got.Namespace != ownerNs || got.Name != "gpg-key" || got.Resource != "secrets" {
+			t.Errorf("got %+v, want %s/gpg-key as a secret", got, ownerNs)
+		}
+		if p := got.FieldPath.String(); p != "spec.signingKeySecretRef" {
+			t.Errorf("got field path %q, want spec.signingKeySecretRef", p)
+		}
+	})
+
 	t.Run("an unset secret ref yields no ref", func(t *testing.T) {
 		refs, err := RemoteUserRefs(syngit.RemoteUserSpec{}, ownerNs)
 		if err != nil {
@@ -0,0 +1,213 @@
+package render
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strings"
+
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+	"go.yaml.in/yaml/v3"
+)
+
+// fieldOrder is the order of the fields of a mapping, found by the path of the
+// mapping. "[]" stands for the items of a list. A path ending with the suffix
+// of an entry gets its order; the fields it does not list come afterwards,
+// in alphabetical order.
+type fieldOrder struct {
+	suffix []string
+	fields []string
+}
+
+var (
+	// rootFields are the fields of an object, whatever its kind.
+	rootFields = []string{"apiVersion", "kind", "metadata", "spec", "data", "stringData", "binaryData"}
+
+	// kindRootFields override rootFields for the kinds that humans write
+	// differently.
+	kindRootFields = map[string][]string{
+		"Secret":             {"apiVersion", "kind", "metadata", "type", "immutable", "data", "stringData"},
+		"ConfigMap":          {"apiVersion", "kind", "metadata", "immutable", "data", "binaryData"},
+		"Role":               {"apiVersion", "kind", "metadata", "rules"},
+		"ClusterRole":        {"apiVersion", "kind", "metadata", "aggregationRule", "rules"},
+		"RoleBinding":        {"apiVersion", "kind", "metadata", "subjects", "roleRef"},
+		"ClusterRoleBinding": {"apiVersion", "kind", "metadata", "subjects", "roleRef"},
+		"ServiceAccount": {
+			"apiVersion", "kind", "metadata", "automountServiceAccountToken", "imagePullSecrets", "secrets",
+		},
+	}
+
+	fieldOrders = []fieldOrder{
+		{suffix: []string{"metadata"}, fields: []string{
+			"name", "generateName", "namespace", "labels", "annotations",
+		}},
+		{suffix: []string{"template"}, fields: []string{"metadata", "spec"}},
+		{suffix: []string{"jobTemplate"}, fields: []string{"metadata", "spec"}},
+		{suffix: []string{"jobTemplate", "spec"}, fields: specFields},
+		{suffix: []string{"template", "spec"}, fields: []string{
+			"serviceAccountName", "nodeSelector", "affinity", "tolerations", "securityContext",
+			"initContainers", "containers", "volumes",
+		}},
+		{suffix: []string{"containers", "[]"}, fields: containerFields},
+		{suffix: []string{"initContainers", "[]"}, fields: containerFields},
+		{suffix: []string{"ephemeralContainers", "[]"}, fields: containerFields},
+		{suffix: []string{"ports", "[]"}, fields: []string{
+			"name", "port", "targetPort", "containerPort", "hostPort", "nodePort", "protocol",
+		}},
+		{suffix: []string{"env", "[]"}, fields: []string{"name", "value", "valueFrom"}},
+		{suffix: []string{"volumeMounts", "[]"}, fields: []string{"name", "mountPath", "subPath", "readOnly"}},
+		{suffix: []string{"resources"}, fields: []string{"requests", "limits"}},
+		{suffix: []string{"selector"}, fields: []string{"matchLabels", "matchExpressions"}},
+		{suffix: []string{"subjects", "[]"}, fields: []string{"kind", "name", "namespace", "apiGroup"}},
+		{suffix: []string{"roleRef"}, fields: []string{"apiGroup", "kind", "name"}},
+		{suffix: []string{"rules", "[]"}, fields: []string{
+			"apiGroups", "resources", "resourceNames", "nonResourceURLs", "verbs", "host", "http",
+		}},
+		{suffix: []string{"[]"}, fields: []string{"name"}},
+	}
+
+	// specFields are the fields of the spec of an object.
+	specFields = []string{
+		"replicas", "selector", "serviceName", "strategy", "updateStrategy", "minReadySeconds",
+		"schedule", "concurrencyPolicy", "jobTemplate", "template",
+		"type", "clusterIP", "ports", "ingressClassName", "tls", "rules",
+	}
+
+	containerFields = []string{
+		"name", "image", "imagePullPolicy", "command", "args", "workingDir", "ports", "env", "envFrom",
+		"resources", "volumeMounts", "livenessProbe", "readinessProbe", "startupProbe", "securityContext",
+	}
+)
+
+// CanonicalYAML serializes an object in the canonical Kubernetes order:
+// apiVersion, kind, metadata (name, namespace, labels, annotations), then
+// spec or data, then the rest. The well-known types have their fields in the
+// order humans write them, and the other fields are sorted alphabetically.
+func CanonicalYAML(data map[string]interface{}, format syngit.YAMLFormat) ([]byte, error) {
+	kind, _ := data["kind"].(string)
+	node, err := orderedNode(data, nil, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	indent := int(format.Indent)
+	if indent == 0 {
+		indent = int(syngit.DefaultYAMLIndent)
+	}
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(indent)
+	if format.SequenceIndent != syngit.IndentedSequenceIndent {
+		encoder.CompactSeqIndent()
+	}
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// orderedNode converts a value into a YAML node whose mappings are in the
+// canonical order of their path.
+func orderedNode(value interface{}, path []string, kind string) (*yaml.Node, error) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, key := range orderedKeys(typed, path, kind) {
+			child, err := orderedNode(typed[key], childPath(path, key), kind)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+		}
+		return node, nil
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range typed {
+			child, err := orderedNode(item, childPath(path, "[]"), kind)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, child)
+		}
+		return node, nil
+	case float64:
+		// The numbers of the JSON object are all decoded as float64.
+		node := &yaml.Node{}
+		var err error
+		if typed == math.Trunc(typed) && math.Abs(typed) < 1<<53 {
+			err = node.Encode(int64(typed))
+		} else {
+			err = node.Encode(typed)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return node, nil
+	default:
+		node := &yaml.Node{}
+		if err := node.Encode(typed); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+}
+
+func childPath(path []string, key string) []string {
+	return append(append(make([]string, 0, len(path)+1), path...), key)
+}
+
+// orderedKeys returns the keys of the mapping found at path, the ones of its
+// field order first.
+func orderedKeys(data map[string]interface{}, path []string, kind string) []string {
+	order := fieldsAt(path, kind)
+	rank := map[string]int{}
+	for i, field := range order {
+		rank[field] = i
+	}
+
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		rankI, orderedI := rank[keys[i]]
+		rankJ, orderedJ := rank[keys[j]]
+		switch {
+		case orderedI && orderedJ:
+			return rankI < rankJ
+		case orderedI != orderedJ:
+			return orderedI
+		default:
+			return keys[i] < keys[j]
+		}
+	})
+	return keys
+}
+
+func fieldsAt(path []string, kind string) []string {
+	switch {
+	case len(path) == 0:
+		if fields, ok := kindRootFields[kind]; ok {
+			return fields
+		}
+		return rootFields
+	case len(path) == 1 && path[0] == "spec":
+		return specFields
+	}
+	for _, order := range fieldOrders {
+		if hasSuffix(path, order.suffix) {
+			return order.fields
+		}
+	}
+	return nil
+}
+
+func hasSuffix(path, suffix []string) bool {
+	if len(suffix) > len(path) {
+		return false
+	}
+	return strings.Join(path[len(path)-len(suffix):], "/") == strings.Join(suffix, "/")
+}
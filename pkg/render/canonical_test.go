@@ -0,0 +1,98 @@
+package render
+
+import (
+	"testing"
+
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+)
+
+func TestCanonicalYAML(t *testing.T) {
+	tests := []struct {
+		name   string
+		object string
+		format syngit.YAMLFormat
+		want   string
+	}{
+		{
+			name: "well-known fields in the order humans write them",
+			object: `{"status":{"replicas":2},"spec":{"template":{"spec":{"containers":[{"ports":[{"protocol":"TCP",
+				"containerPort":8080}],"image":"web:1.2.0","name":"web"}]},"metadata":{"labels":{"app":"web"}}},
+				"selector":{"matchLabels":{"app":"web"}},"replicas":2},"metadata":{"annotations":{"owner":"team-a"},
+				"name":"web","labels":{"app":"web"},"namespace":"default"},"kind":"Deployment","apiVersion":"apps/v1"}`,
+			want: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+  labels:
+    app: web
+  annotations:
+    owner: team-a
+spec:
+  replicas: 2
+  selector:
+    matchLabels:
+      app: web
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: web
+        image: web:1.2.0
+        ports:
+        - containerPort: 8080
+          protocol: TCP
+status:
+  replicas: 2
+`,
+		},
+		{
+			name: "kind specific order and alphabetical order elsewhere",
+			object: `{"type":"Opaque","data":{"b":"Yg==","a":"YQ=="},"metadata":{"name":"creds"},"kind":"Secret",
+				"apiVersion":"v1"}`,
+			want: `apiVersion: v1
+kind: Secret
+metadata:
+  name: creds
+type: Opaque
+data:
+  a: YQ==
+  b: Yg==
+`,
+		},
+		{
+			name:   "configured indentation",
+			object: `{"rules":[{"verbs":["get","list"],"apiGroups":[""]}],"metadata":{"name":"reader"},"kind":"Role","apiVersion":"rbac.authorization.k8s.io/v1"}`,
+			format: syngit.YAMLFormat{Indent: 4, SequenceIndent: syngit.IndentedSequenceIndent},
+			want: `apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+    name: reader
+rules:
+    - apiGroups:
+        - ""
+      verbs:
+        - get
+        - list
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := JSONToMap([]byte(tc.object))
+			if err != nil {
+				t.Fatalf("JSONToMap: %v", err)
+			}
+			got, err := CanonicalYAML(data, tc.format)
+			if err != nil {
+				t.Fatalf("CanonicalYAML: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("CanonicalYAML:\n--- got\n%s\n--- want\n%s", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package render
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+)
+
+// managedFieldsEntry is the part of a metadata.managedFields entry needed to
+// know which fields a manager owns.
+type managedFieldsEntry struct {
+	Manager     string                 `json:"manager"`
+	Time        string                 `json:"time"`
+	Subresource string                 `json:"subresource"`
+	FieldsType  string                 `json:"fieldsType"`
+	FieldsV1    map[string]interface{} `json:"fieldsV1"`
+}
+
+// KeepOwnedFields removes from the object the fields that are not owned by
+// the selected field managers, then removes its metadata.managedFields. The
+// managers are the ones of the configuration, or the fieldManager of the
+// request when none is configured. Without one, the managers of the last
+// update of the object are used. The apiVersion, the kind, the name and the
+// namespace are always kept.
+func KeepOwnedFields(data map[string]interface{}, config syngit.OwnedFields, fieldManager string) {
+	metadata, _ := data["metadata"].(map[string]interface{})
+	entries := managedFieldsEntries(metadata)
+	delete(metadata, "managedFields")
+	if len(entries) == 0 {
+		// Nothing tells which fields are owned: keep them all.
+		return
+	}
+
+	managers := config.FieldManagers
+	if len(managers) == 0 && fieldManager != "" {
+		managers = []string{fieldManager}
+	}
+	if len(managers) == 0 {
+		managers = lastManagers(entries)
+	}
+
+	owned := map[string]interface{}{}
+	for _, entry := range entries {
+		if entry.Subresource != "" || entry.FieldsType != "FieldsV1" || !matchesManager(entry.Manager, managers) {
+			continue
+		}
+		mergeFields(owned, entry.FieldsV1)
+	}
+
+	kept := map[string]interface{}{}
+	for _, key := range []string{"apiVersion", "kind"} {
+		if value, ok := data[key]; ok {
+			kept[key] = value
+		}
+	}
+	keptMetadata := map[string]interface{}{}
+	for _, key := range []string{"name", "namespace"} {
+		if value, ok := metadata[key]; ok {
+			keptMetadata[key] = value
+		}
+	}
+
+	filterMap(data, owned)
+	for key, value := range kept {
+		data[key] = value
+	}
+	if len(keptMetadata) > 0 {
+		filteredMetadata, ok := data["metadata"].(map[string]interface{})
+		if !ok {
+			filteredMetadata = map[string]interface{}{}
+			data["metadata"] = filteredMetadata
+		}
+		for key, value := range keptMetadata {
+			filteredMetadata[key] = value
+		}
+	}
+}
+
+func managedFieldsEntries(metadata map[string]interface{}) []managedFieldsEntry {
+	raw, ok := metadata["managedFields"]
+	if !ok {
+		return nil
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil
+	}
+	var entries []managedFieldsEntry
+	if err := json.Unmarshal(encoded, &entries); err != nil {
+		return nil
+	}
+	return entries
+}
+
+// lastManagers returns the managers of the most recent entries, which are
+// the ones that made the intercepted change.
+func lastManagers(entries []managedFieldsEntry) []string {
+	var last time.Time
+	managers := []string{}
+	for _, entry := range entries {
+		if entry.Subresource != "" {
+			continue
+		}
+		updated, err := time.Parse(time.RFC3339, entry.Time)
+		if err != nil {
+			continue
+		}
+		switch {
+		case updated.After(last):
+			last = updated
+			managers = []string{entry.Manager}
+		case updated.Equal(last):
+			managers = append(managers, entry.Manager)
+		}
+	}
+	return managers
+}
+
+// matchesManager tells if the manager is one of the names, or is prefixed by
+// one of them followed by a dash.
+func matchesManager(manager string, names []string) bool {
+	for _, name := range names {
+		if manager == name || strings.HasPrefix(manager, name+"-") {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeFields adds the fields of a FieldsV1 set to another one.
+func mergeFields(into map[string]interface{}, fields map[string]interface{}) {
+	for key, value := range fields {
+		children, _ := value.(map[string]interface{})
+		existing, ok := into[key].(map[string]interface{})
+		if !ok {
+			existing = map[string]interface{}{}
+			into[key] = existing
+		}
+		mergeFields(existing, children)
+	}
+}
+
+// filterMap removes the keys of the map that are not part of the FieldsV1 set.
+func filterMap(data map[string]interface{}, fields map[string]interface{}) {
+	for key, value := range data {
+		children, ok := fields["f:"+key].(map[string]interface{})
+		if !ok {
+			delete(data, key)
+			continue
+		}
+		data[key] = filterValue(value, children)
+	}
+}
+
+// filterValue keeps the parts of the value that are part of the FieldsV1 set.
+// A set without children owns the whole value.
+func filterValue(value interface{}, fields map[string]interface{}) interface{} {
+	if !hasChildren(fields) {
+		return value
+	}
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		filterMap(typed, fields)
+		return typed
+	case []interface{}:
+		items := []interface{}{}
+		for index, item := range typed {
+			children, ok := listItemFields(item, index, fields)
+			if !ok {
+				continue
+			}
+			items = append(items, filterValue(item, children))
+		}
+		return items
+	default:
+		return value
+	}
+}
+
+func hasChildren(fields map[string]interface{}) bool {
+	for key := range fields {
+		if key != "." {
+			return true
+		}
+	}
+	return false
+}
+
+// listItemFields returns the FieldsV1 set of a list item, identified by its
+// keys ("k:"), its value ("v:") or its index ("i:").
+func listItemFields(item interface{}, index int, fields map[string]interface{}) (map[string]interface{}, bool) {
+	for key, value := range fields {
+		children, _ := value.(map[string]interface{})
+		switch {
+		case strings.HasPrefix(key, "k:"):
+			var keys map[string]interface{}
+			if json.Unmarshal([]byte(strings.TrimPrefix(key, "k:")), &keys) != nil {
+				continue
+			}
+			object, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			matches := true
+			for name, want := range keys {
+				if !sameValue(object[name], want) {
+					matches = false
+					break
+				}
+			}
+			if matches {
+				return children, true
+			}
+		case strings.HasPrefix(key, "v:"):
+			var want interface{}
+			if json.Unmarshal([]byte(strings.TrimPrefix(key, "v:")), &want) != nil {
+				continue
+			}
+			if sameValue(item, want) {
+				return children, true
+			}
+		case strings.HasPrefix(key, "i:"):
+			if strconv.Itoa(index) == strings.TrimPrefix(key, "i:") {
+				return children, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// sameValue compares two decoded JSON values, whatever the type their
+// numbers were decoded to.
+func sameValue(a, b interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(encodedA) == string(encodedB)
+}
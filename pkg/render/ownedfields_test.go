@@ -0,0 +1,166 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
+)
+
+// deployment is a Deployment as admitted by the API server: defaulted, with
+// the fields set by kubectl, by a helm upgrade and by the controller manager.
+const deployment = `{
+  "apiVersion": "apps/v1",
+  "kind": "Deployment",
+  "metadata": {
+    "name": "web",
+    "namespace": "team-a",
+    "uid": "0b7c5f4e",
+    "generation": 2,
+    "labels": {"app": "web", "chart": "web-1.0.0"},
+    "managedFields": [
+      {
+        "manager": "kubectl-client-side-apply",
+        "operation": "Update",
+        "apiVersion": "apps/v1",
+        "time": "2026-10-16T10:00:00Z",
+        "fieldsType": "FieldsV1",
+        "fieldsV1": {
+          "f:metadata": {"f:labels": {".": {}, "f:app": {}}},
+          "f:spec": {
+            "f:replicas": {},
+            "f:template": {"f:spec": {"f:containers": {
+              "k:{\"name\":\"web\"}": {".": {}, "f:image": {}, "f:name": {}, "f:ports": {
+                "k:{\"containerPort\":8080,\"protocol\":\"TCP\"}": {".": {}, "f:containerPort": {}}
+              }}
+            }}}
+          }
+        }
+      },
+      {
+        "manager": "helm",
+        "operation": "Update",
+        "apiVersion": "apps/v1",
+        "time": "2026-10-16T11:00:00Z",
+        "fieldsType": "FieldsV1",
+        "fieldsV1": {
+          "f:metadata": {"f:labels": {"f:chart": {}}},
+          "f:spec": {"f:template": {"f:spec": {"f:containers": {
+            "k:{\"name\":\"sidecar\"}": {".": {}, "f:image": {}, "f:name": {}}
+          }}}}
+        }
+      },
+      {
+        "manager": "kube-controller-manager",
+        "operation": "Update",
+        "apiVersion": "apps/v1",
+        "time": "2026-10-16T11:00:05Z",
+        "fieldsType": "FieldsV1",
+        "subresource": "status",
+        "fieldsV1": {"f:status": {"f:replicas": {}}}
+      }
+    ]
+  },
+  "spec": {
+    "replicas": 2,
+    "revisionHistoryLimit": 10,
+    "strategy": {"type": "RollingUpdate"},
+    "template": {"spec": {
+      "containers": [
+        {
+          "name": "web",
+          "image": "web:1.2.0",
+          "imagePullPolicy": "IfNotPresent",
+          "terminationMessagePath": "/dev/termination-log",
+          "ports": [{"containerPort": 8080, "protocol": "TCP"}]
+        },
+        {"name": "sidecar", "image": "proxy:2.0.0", "imagePullPolicy": "IfNotPresent"}
+      ],
+      "restartPolicy": "Always"
+    }}
+  },
+  "status": {"replicas": 2}
+}`
+
+func TestKeepOwnedFields(t *testing.T) {
+	webContainer := map[string]interface{}{
+		"name":  "web",
+		"image": "web:1.2.0",
+		"ports": []interface{}{map[string]interface{}{"containerPort": float64(8080)}},
+	}
+	sidecarContainer := map[string]interface{}{"name": "sidecar", "image": "proxy:2.0.0"}
+	owned := func(labels map[string]interface{}, spec map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":      "web",
+				"namespace": "team-a",
+				"labels":    labels,
+			},
+			"spec": spec,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		config       syngit.OwnedFields
+		fieldManager string
+		want         map[string]interface{}
+	}{
+		{
+			name:         "fields of the manager of the request",
+			fieldManager: "kubectl-client-side-apply",
+			want: owned(map[string]interface{}{"app": "web"}, map[string]interface{}{
+				"replicas": float64(2),
+				"template": map[string]interface{}{"spec": map[string]interface{}{
+					"containers": []interface{}{webContainer},
+				}},
+			}),
+		},
+		{
+			name:   "fields of the configured managers, matched by prefix",
+			config: syngit.OwnedFields{FieldManagers: []string{"kubectl", "helm"}},
+			want: owned(map[string]interface{}{"app": "web", "chart": "web-1.0.0"}, map[string]interface{}{
+				"replicas": float64(2),
+				"template": map[string]interface{}{"spec": map[string]interface{}{
+					"containers": []interface{}{webContainer, sidecarContainer},
+				}},
+			}),
+		},
+		{
+			name: "fields of the last manager without a manager",
+			want: owned(map[string]interface{}{"chart": "web-1.0.0"}, map[string]interface{}{
+				"template": map[string]interface{}{"spec": map[string]interface{}{
+					"containers": []interface{}{sidecarContainer},
+				}},
+			}),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := JSONToMap([]byte(deployment))
+			if err != nil {
+				t.Fatalf("JSONToMap: %v", err)
+			}
+			KeepOwnedFields(data, tc.config, tc.fieldManager)
+			if !reflect.DeepEqual(data, tc.want) {
+				t.Errorf("KeepOwnedFields:\n got  %v\n want %v", data, tc.want)
+			}
+		})
+	}
+}
+
+func TestKeepOwnedFields_withoutManagedFields(t *testing.T) {
+	data, err := JSONToMap([]byte(`{"kind":"ConfigMap","metadata":{"name":"demo"},"data":{"key":"value"}}`))
+	if err != nil {
+		t.Fatalf("JSONToMap: %v", err)
+	}
+	want, _ := JSONToMap([]byte(`{"kind":"ConfigMap","metadata":{"name":"demo"},"data":{"key":"value"}}`))
+
+	KeepOwnedFields(data, syngit.OwnedFields{Enabled: true}, "kubectl")
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("an object without managedFields must be kept as is, got %v", data)
+	}
+}
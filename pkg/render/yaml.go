@@ -11,18 +11,19 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/yaml"
 )
 
 // Convert the json string object to a yaml string.
 // We have no other choice than extracting the json into a map
 // and then convert the map into a yaml string.
 // Because the 'map' object is, by definition, not ordered
-// we cannot reorder fields.
+// the fields are written in the canonical order (see CanonicalYAML).
 // refOwnerNamespace is the syncer's namespace (empty for CWRSY)
+// fieldManager is the one of the request, used by the ownedFields rendering.
 func ObjectToYAML(
 	ctx context.Context,
 	rawObject []byte,
+	fieldManager string,
 	syngitNamespace string,
 	spec syngit.RemoteSyncerSpec,
 	refOwnerNamespace string,
@@ -32,6 +33,11 @@ func ObjectToYAML(
 		return "", err
 	}
 
+	// Keep the fields owned by the field managers
+	if spec.OwnedFields.Enabled {
+		KeepOwnedFields(data, spec.OwnedFields, fieldManager)
+	}
+
 	// Excluded fields paths to remove
 	paths := []string{}
 
@@ -96,7 +102,7 @@ func ObjectToYAML(
 	}
 
 	// Marshal back to YAML
-	updatedYAML, err := yaml.Marshal(data)
+	updatedYAML, err := CanonicalYAML(data, spec.YAMLFormat)
 	if err != nil {
 		return "", err
 	}
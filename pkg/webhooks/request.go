@@ -1,7 +1,11 @@
 package webhooks
 
 import (
+	"encoding/json"
+
+	syngit "github.com/syngit-org/syngit/pkg/api/v1beta5"
 	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
@@ -31,6 +35,12 @@ type ObjectMetadata struct {
 	Name string
 	// Namespace of the intercepted object. Empty when it is cluster-scoped.
 	Namespace string
+	// The kind, the labels and the annotations of the intercepted object, the
+	// ones of the deleted object on a delete.
+	PathMetadata syngit.PathMetadata
+	// The kind, the labels and the annotations the object had before an
+	// update. Nil for the other operations.
+	OldPathMetadata *syngit.PathMetadata
 }
 
 // ExtractObjectMetadata reads the identity of the intercepted object off an
@@ -38,9 +48,47 @@ type ObjectMetadata struct {
 func ExtractObjectMetadata(admissionRequest *admissionv1.AdmissionRequest) ObjectMetadata {
 	interceptedGVR := (*schema.GroupVersionResource)(admissionRequest.RequestResource.DeepCopy())
 
-	return ObjectMetadata{
+	objectMetadata := ObjectMetadata{
 		Name:      admissionRequest.Name,
 		Namespace: admissionRequest.Namespace,
 		GVR:       *interceptedGVR,
 	}
+	if admissionRequest.Operation == admissionv1.Delete {
+		objectMetadata.PathMetadata = extractPathMetadata(admissionRequest, admissionRequest.OldObject.Raw)
+	} else {
+		objectMetadata.PathMetadata = extractPathMetadata(admissionRequest, admissionRequest.Object.Raw)
+	}
+	if admissionRequest.Operation == admissionv1.Update && len(admissionRequest.OldObject.Raw) > 0 {
+		oldPathMetadata := extractPathMetadata(admissionRequest, admissionRequest.OldObject.Raw)
+		objectMetadata.OldPathMetadata = &oldPathMetadata
+	}
+	return objectMetadata
+}
+
+func extractPathMetadata(admissionRequest *admissionv1.AdmissionRequest, raw []byte) syngit.PathMetadata {
+	object := metav1.PartialObjectMetadata{}
+	if len(raw) > 0 {
+		// The object has already been decoded by the API server.
+		_ = json.Unmarshal(raw, &object)
+	}
+	return syngit.PathMetadata{
+		Kind:        admissionRequest.Kind.Kind,
+		Labels:      object.Labels,
+		Annotations: object.Annotations,
+	}
+}
+
+// FieldManager returns the fieldManager of the create, update or patch options
+// of an admission request. It is empty when the client did not set one.
+func FieldManager(admissionRequest *admissionv1.AdmissionRequest) string {
+	options := struct {
+		FieldManager string `json:"fieldManager"`
+	}{}
+	if len(admissionRequest.Options.Raw) == 0 {
+		return ""
+	}
+	if err := json.Unmarshal(admissionRequest.Options.Raw, &options); err != nil {
+		return ""
+	}
+	return options.FieldManager
 }
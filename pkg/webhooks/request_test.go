@@ -5,6 +5,7 @@ import (
 
 	admissionv1 "k8s.io/api/admission/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 func TestExtractObjectMetadata(t *testing.T) {
@@ -32,3 +33,78 @@ func TestExtractObjectMetadata(t *testing.T) {
 		t.Errorf("GVR.Group should be a deep copy; mutation leaked: %q", md.GVR.Group)
 	}
 }
+
+func TestExtractObjectMetadataPathMetadata(t *testing.T) {
+	object := []byte(`{"kind":"Deployment","metadata":{"name":"web","labels":{"app":"web"},"annotations":{"team":"a"}}}`)
+	tests := []struct {
+		name      string
+		operation admissionv1.Operation
+		object    []byte
+		oldObject []byte
+		wantApp   string
+		// The app label of the object before an update, "-" when there is
+		// no previous metadata.
+		wantOldApp string
+	}{
+		{name: "labels of the object", operation: admissionv1.Create, object: object, wantApp: "web", wantOldApp: "-"},
+		{name: "labels of the deleted object", operation: admissionv1.Delete, oldObject: object, wantApp: "web", wantOldApp: "-"},
+		{name: "no object", operation: admissionv1.Delete, wantApp: "", wantOldApp: "-"},
+		{
+			name:       "labels before an update",
+			operation:  admissionv1.Update,
+			object:     []byte(`{"kind":"Deployment","metadata":{"name":"web","labels":{"app":"api"}}}`),
+			oldObject:  object,
+			wantApp:    "api",
+			wantOldApp: "web",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			admReq := &admissionv1.AdmissionRequest{
+				Operation:       tc.operation,
+				RequestResource: &metav1.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+				Kind:            metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"},
+				Object:          runtime.RawExtension{Raw: tc.object},
+				OldObject:       runtime.RawExtension{Raw: tc.oldObject},
+			}
+			objectMetadata := ExtractObjectMetadata(admReq)
+			md := objectMetadata.PathMetadata
+			if md.Kind != "Deployment" {
+				t.Errorf("Kind=%q, want Deployment", md.Kind)
+			}
+			if md.Labels["app"] != tc.wantApp {
+				t.Errorf("Labels[app]=%q, want %q", md.Labels["app"], tc.wantApp)
+			}
+			oldApp := "-"
+			if objectMetadata.OldPathMetadata != nil {
+				oldApp = objectMetadata.OldPathMetadata.Labels["app"]
+			}
+			if oldApp != tc.wantOldApp {
+				t.Errorf("OldPathMetadata.Labels[app]=%q, want %q", oldApp, tc.wantOldApp)
+			}
+		})
+	}
+}
+
+func TestFieldManager(t *testing.T) {
+	tests := []struct {
+		name    string
+		options string
+		want    string
+	}{
+		{name: "no options", options: "", want: ""},
+		{name: "options without a field manager", options: `{"kind":"UpdateOptions"}`, want: ""},
+		{name: "field manager of the options", options: `{"kind":"PatchOptions","fieldManager":"kubectl-edit"}`, want: "kubectl-edit"},
+		{name: "invalid options", options: `{not json`, want: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			admReq := &admissionv1.AdmissionRequest{Options: runtime.RawExtension{Raw: []byte(tc.options)}}
+			if got := FieldManager(admReq); got != tc.want {
+				t.Errorf("FieldManager=%q, want %q", got, tc.want)
+			}
+		})
+	}
+}